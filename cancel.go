@@ -0,0 +1,115 @@
+package statemachine
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/google/uuid"
+	"github.com/samber/lo"
+
+	"github.com/kkiling/statemachine/storage"
+)
+
+// runningStates контексты стейтов, выполняющихся в этом экземпляре стейт машины
+type runningStates struct {
+	mu      sync.Mutex
+	cancels map[uuid.UUID]context.CancelCauseFunc
+}
+
+func newRunningStates() *runningStates {
+	return &runningStates{
+		cancels: make(map[uuid.UUID]context.CancelCauseFunc),
+	}
+}
+
+// add регистрирует выполнение стейта, done должна быть вызвана по завершении выполнения
+func (r *runningStates) add(ctx context.Context, stateID uuid.UUID) (runCtx context.Context, done func()) {
+	runCtx, cancel := context.WithCancelCause(ctx)
+
+	r.mu.Lock()
+	r.cancels[stateID] = cancel
+	r.mu.Unlock()
+
+	return runCtx, func() {
+		r.mu.Lock()
+		delete(r.cancels, stateID)
+		r.mu.Unlock()
+		cancel(nil)
+	}
+}
+
+// cancel отменяет контекст выполнения стейта с причиной cause, если он выполняется в этом экземпляре
+func (r *runningStates) cancel(stateID uuid.UUID, cause error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if cancel, ok := r.cancels[stateID]; ok {
+		cancel(cause)
+	}
+}
+
+// Cancel переводит стейт в статус CancelledStatus с причиной reason и сохраняет отмену в истории выполнения.
+// Если шаг стейта выполняется в этом экземпляре стейт машины, его контекст отменяется,
+// а результат выполнения не сохраняется. Для стейта в терминальном статусе возвращает ErrInTerminalStatus
+func (i *StateMachine[DataT, FailDataT, MetaDataT, StepT, TypeT, CreateOptionsT]) Cancel(
+	ctx context.Context,
+	stateID uuid.UUID,
+	reason string,
+) (*State[DataT, FailDataT, MetaDataT, StepT, TypeT], error) {
+	var res *State[DataT, FailDataT, MetaDataT, StepT, TypeT]
+	// Стейт мог измениться во время отмены (например завершился шаг), тогда он перечитывается
+	err := retryOnConflict(ctx, func() (err error) {
+		res, err = i.cancel(ctx, stateID, reason)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	i.running.cancel(stateID, ErrStateCancelled)
+	return res, nil
+}
+
+func (i *StateMachine[DataT, FailDataT, MetaDataT, StepT, TypeT, CreateOptionsT]) cancel(
+	ctx context.Context,
+	stateID uuid.UUID,
+	reason string,
+) (*State[DataT, FailDataT, MetaDataT, StepT, TypeT], error) {
+	findState, err := i.GetStateByID(ctx, stateID)
+	if err != nil {
+		return nil, fmt.Errorf("GetStateByID: %w", err)
+	}
+	if findState == nil {
+		return nil, fmt.Errorf("state not found: %w", ErrNotFound)
+	}
+	if isTerminalStatus(findState.Status) {
+		return nil, ErrInTerminalStatus
+	}
+
+	now := i.clock.Now()
+	newState := *findState
+	newState.Status = CancelledStatus
+	newState.Step = ""
+	newState.UpdatedAt = now
+	newState.Error = lo.ToPtr(reason)
+	newState.NextRunAt = nil
+	newState.FailedAttempts = 0
+	newState.StepAttempts = 0
+	newState.PanicAttempts = 0
+
+	// Отмена сохраняется в истории выполнения как переход с текущего шага без следующего шага
+	err = i.saveState(ctx, &newState, storage.StepExecuteInfo{
+		StateID:            stateID,
+		StartExecutedAt:    now,
+		CompleteExecutedAt: now,
+		Error:              newState.Error,
+		PreviewStep:        string(findState.Step),
+		Reason:             lo.ToPtr(reason),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &newState, nil
+}
@@ -0,0 +1,228 @@
+package statemachine
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+
+	"github.com/kkiling/statemachine/memstore"
+	"github.com/kkiling/statemachine/storage"
+)
+
+// changeStateStorage хранилище, в котором стейт изменяется другим обработчиком
+// при следующем чтении после вызова changeOnNextRead
+type changeStateStorage struct {
+	*memstore.Storage
+	change atomic.Pointer[func()]
+}
+
+// changeOnNextRead change выполнится один раз перед следующим чтением стейта
+func (s *changeStateStorage) changeOnNextRead(change func()) {
+	s.change.Store(&change)
+}
+
+func (s *changeStateStorage) GetStateByID(ctx context.Context, stateID uuid.UUID) (*storage.State, error) {
+	if change := s.change.Swap(nil); change != nil {
+		(*change)()
+	}
+	return s.Storage.GetStateByID(ctx, stateID)
+}
+
+func TestStateMachine_Cancel(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	t.Run("cancel new state", func(t *testing.T) {
+		t.Parallel()
+
+		s := memstore.NewStorage()
+		sm := NewService[int, any, any, string, string, testOptions](Config{}, s, newStepTestRunner("cancel_test", testStepperStep{
+			OnStep: func(_ context.Context, stepContext StepContext[int, any, any, string, string]) *StepResult[int, any, any, string] {
+				return stepContext.Complete()
+			},
+		}))
+
+		state, err := sm.Create(ctx, testOptions{})
+		require.NoError(t, err)
+
+		cancelState, err := sm.Cancel(ctx, state.ID, "cancelled by operator")
+		require.NoError(t, err)
+		require.Equal(t, CancelledStatus, cancelState.Status)
+		require.Equal(t, "", cancelState.Step)
+		require.Equal(t, "cancelled by operator", *cancelState.Error)
+
+		findState, err := sm.GetStateByID(ctx, state.ID)
+		require.NoError(t, err)
+		require.Equal(t, cancelState, findState)
+
+		// Отмена сохраняется в истории выполнения
+		infos, err := s.GetStepExecuteInfos(ctx, state.ID)
+		require.NoError(t, err)
+		require.Len(t, infos, 1)
+		require.Equal(t, "first", infos[0].PreviewStep)
+		require.Nil(t, infos[0].NextStep)
+		require.Equal(t, "cancelled by operator", *infos[0].Error)
+		require.Equal(t, "cancelled by operator", *infos[0].Reason)
+
+		// Отмененный стейт больше не выполняется
+		_, _, err = sm.Complete(ctx, state.ID)
+		require.ErrorIs(t, err, ErrInTerminalStatus)
+	})
+
+	t.Run("state in terminal status", func(t *testing.T) {
+		t.Parallel()
+
+		sm := NewService[int, any, any, string, string, testOptions](Config{}, memstore.NewStorage(), newStepTestRunner("cancel_test", testStepperStep{
+			OnStep: func(_ context.Context, stepContext StepContext[int, any, any, string, string]) *StepResult[int, any, any, string] {
+				return stepContext.Complete()
+			},
+		}))
+
+		state, err := sm.Create(ctx, testOptions{})
+		require.NoError(t, err)
+		_, _, err = sm.Complete(ctx, state.ID)
+		require.NoError(t, err)
+
+		_, err = sm.Cancel(ctx, state.ID, "too late")
+		require.ErrorIs(t, err, ErrInTerminalStatus)
+	})
+
+	t.Run("state not found", func(t *testing.T) {
+		t.Parallel()
+
+		sm := NewService[int, any, any, string, string, testOptions](Config{}, memstore.NewStorage(), newStepTestRunner("cancel_test", testStepperStep{}))
+
+		_, err := sm.Cancel(ctx, uuid.New(), "unknown")
+		require.ErrorIs(t, err, ErrNotFound)
+	})
+
+	t.Run("cancel executing step", func(t *testing.T) {
+		t.Parallel()
+
+		started := make(chan struct{})
+		s := memstore.NewStorage()
+		sm := NewService[int, any, any, string, string, testOptions](Config{}, s, newStepTestRunner("cancel_test", testStepperStep{
+			OnStep: func(ctx context.Context, stepContext StepContext[int, any, any, string, string]) *StepResult[int, any, any, string] {
+				close(started)
+				// Шаг выполняется до отмены контекста
+				<-ctx.Done()
+				return stepContext.Error(ctx.Err())
+			},
+		}))
+
+		state, err := sm.Create(ctx, testOptions{})
+		require.NoError(t, err)
+
+		completeErr := make(chan error)
+		go func() {
+			_, _, err := sm.Complete(ctx, state.ID)
+			completeErr <- err
+		}()
+
+		select {
+		case <-started:
+		case <-time.After(5 * time.Second):
+			t.Fatal("step was not started")
+		}
+
+		_, err = sm.Cancel(ctx, state.ID, "stop")
+		require.NoError(t, err)
+		require.ErrorIs(t, <-completeErr, ErrStateCancelled)
+
+		// Результат прерванного шага не сохраняется
+		findState, err := sm.GetStateByID(ctx, state.ID)
+		require.NoError(t, err)
+		require.Equal(t, CancelledStatus, findState.Status)
+		require.Equal(t, "stop", *findState.Error)
+
+		infos, err := s.GetStepExecuteInfos(ctx, state.ID)
+		require.NoError(t, err)
+		require.Len(t, infos, 1)
+	})
+
+	t.Run("cancel saved before step result", func(t *testing.T) {
+		t.Parallel()
+
+		var sm *StateMachine[int, any, any, string, string, testOptions]
+		sm = NewService[int, any, any, string, string, testOptions](Config{}, memstore.NewStorage(), newStepTestRunner("cancel_test", testStepperStep{
+			OnStep: func(ctx context.Context, stepContext StepContext[int, any, any, string, string]) *StepResult[int, any, any, string] {
+				// Отмена сохраняется после завершения шага, но до отмены контекста выполнения
+				_, err := sm.cancel(ctx, stepContext.State.ID, "stop")
+				require.NoError(t, err)
+				return stepContext.Complete()
+			},
+		}))
+		state, err := sm.Create(ctx, testOptions{})
+		require.NoError(t, err)
+
+		_, _, err = sm.Complete(ctx, state.ID)
+		require.ErrorIs(t, err, ErrStateCancelled)
+
+		findState, err := sm.GetStateByID(ctx, state.ID)
+		require.NoError(t, err)
+		require.Equal(t, CancelledStatus, findState.Status)
+	})
+
+	t.Run("cancelled context", func(t *testing.T) {
+		t.Parallel()
+
+		sm := NewService[int, any, any, string, string, testOptions](Config{}, memstore.NewStorage(), newStepTestRunner("cancel_test", testStepperStep{}))
+		state, err := sm.Create(ctx, testOptions{})
+		require.NoError(t, err)
+
+		cancelCtx, cancel := context.WithCancel(ctx)
+		cancel()
+		_, err = sm.Cancel(cancelCtx, state.ID, "stop")
+		require.ErrorIs(t, err, context.Canceled)
+
+		findState, err := sm.GetStateByID(ctx, state.ID)
+		require.NoError(t, err)
+		require.Equal(t, NewStatus, findState.Status)
+	})
+
+	t.Run("state changed between chained steps", func(t *testing.T) {
+		t.Parallel()
+
+		var (
+			s        = &changeStateStorage{Storage: memstore.NewStorage()}
+			sm       *StateMachine[int, any, any, string, string, testOptions]
+			executed []string
+		)
+		step := func(name, next string) testStepperStep {
+			return testStepperStep{
+				OnStep: func(_ context.Context, stepContext testStepperContext) *testStepperResult {
+					executed = append(executed, name)
+					if name == "a" {
+						// Другой обработчик отменяет стейт после сохранения результата шага a,
+						// контекст выполнения в этом экземпляре не отменяется
+						s.changeOnNextRead(func() {
+							_, err := sm.cancel(ctx, stepContext.State.ID, "stop")
+							require.NoError(t, err)
+						})
+					}
+					return stepContext.Next(next)
+				},
+			}
+		}
+		sm = NewService[int, any, any, string, string, testOptions](Config{}, s, newTestRunner("cancel_test", "a", map[string]testStepperStep{
+			"a": step("a", "b"),
+			"b": step("b", "c"),
+		}))
+		state, err := sm.Create(ctx, testOptions{})
+		require.NoError(t, err)
+
+		_, _, err = sm.Complete(ctx, state.ID)
+		require.ErrorIs(t, err, ErrStateCancelled)
+		// Следующий шаг не выполняется после изменения стейта
+		require.Equal(t, []string{"a"}, executed)
+
+		infos, err := s.GetStepExecuteInfos(ctx, state.ID)
+		require.NoError(t, err)
+		require.Len(t, infos, 2)
+	})
+}
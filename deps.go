@@ -8,10 +8,10 @@ import (
 
 	"github.com/google/uuid"
 
-	"github.com/kkiling/statemachine/internal/storage"
+	"github.com/kkiling/statemachine/storage"
 )
 
-// Storage интерфейс хранения данных стейтмашины
+// Storage интерфейс хранения данных стейтмашины, контракт реализаций описан в пакете storage
 type Storage interface {
 	RunTransaction(ctx context.Context, txFunc func(ctxTx context.Context) error) error
 	// CreateState создание нового стейта в базе
@@ -22,8 +22,32 @@ type Storage interface {
 	GetStateByID(ctx context.Context, stateID uuid.UUID) (*storage.State, error)
 	// SaveStepExecuteInfo Сохранение информации о запуске выполнения шага
 	SaveStepExecuteInfo(ctx context.Context, execute storage.StepExecuteInfo) error
+	// GetStepExecuteInfos получение истории выполнения шагов стейта в порядке начала выполнения
+	GetStepExecuteInfos(ctx context.Context, stateID uuid.UUID) ([]storage.StepExecuteInfo, error)
 	// UpdateState обновление стейта
 	UpdateState(ctx context.Context, stateID uuid.UUID, state storage.UpdateState) error
+	// ClaimState захват стейта в аренду, если стейт захвачен другим обработчиком возвращает storage.ErrLocked
+	ClaimState(ctx context.Context, stateID uuid.UUID, lease storage.Lease) (*storage.State, error)
+	// ClaimReadyStates захват в аренду стейтов, готовых к выполнению и не захваченных другими обработчиками
+	ClaimReadyStates(ctx context.Context, filter storage.ReadyStatesFilter, lease storage.Lease) ([]storage.State, error)
+	// RenewLease продление аренды стейта владельцем, если стейт захвачен другим обработчиком возвращает storage.ErrLocked
+	RenewLease(ctx context.Context, stateID uuid.UUID, lease storage.Lease) error
+	// ReleaseState освобождение аренды стейта владельцем
+	ReleaseState(ctx context.Context, stateID uuid.UUID, owner string) error
+	// ListStates выборка стейтов по фильтру в порядке (CreatedAt, ID)
+	ListStates(ctx context.Context, filter storage.ListStatesFilter) ([]storage.State, error)
+	// SetStatePaused приостановка или возобновление выполнения стейта с проверкой версии, без ее изменения
+	SetStatePaused(ctx context.Context, stateID uuid.UUID, paused bool, version int64) error
+	// PauseType приостановка выполнения всех стейтов типа
+	PauseType(ctx context.Context, stateType string, pausedAt time.Time) error
+	// ResumeType возобновление выполнения стейтов типа
+	ResumeType(ctx context.Context, stateType string) error
+	// IsTypePaused приостановлено ли выполнение стейтов типа
+	IsTypePaused(ctx context.Context, stateType string) (bool, error)
+	// SavePauseEvent сохранение записи о приостановке или возобновлении
+	SavePauseEvent(ctx context.Context, event storage.PauseEvent) error
+	// GetPauseEvents записи о приостановках и возобновлениях стейтов типа в порядке их создания
+	GetPauseEvents(ctx context.Context, stateType string) ([]storage.PauseEvent, error)
 }
 
 // UUIDGenerator интерфейс для генерации UUID (реальный или мок)
@@ -11,4 +11,36 @@ var (
 	ErrInTerminalStatus = errors.New("state already in terminal status")
 	// ErrOptionsIsUndefined ошибка добивания шага без опций
 	ErrOptionsIsUndefined = errors.New("options is undefined")
+	// ErrStateLocked стейт выполняется другим обработчиком
+	ErrStateLocked = errors.New("state is locked by another executor")
+	// ErrLeaseLost аренда стейта потеряна во время выполнения, стейт захвачен другим обработчиком
+	ErrLeaseLost = errors.New("state lease lost")
+	// ErrConcurrentModification стейт был изменен другим обработчиком с момента чтения
+	ErrConcurrentModification = errors.New("state concurrently modified")
+	// ErrNotReady время следующего выполнения стейта еще не наступило
+	ErrNotReady = errors.New("state is not ready to run yet")
+	// ErrRetryAttemptsExhausted исчерпаны попытки повторного выполнения шага
+	ErrRetryAttemptsExhausted = errors.New("retry attempts exhausted")
+	// ErrNotRetryable ошибка выполнения шага не допускает повторного выполнения
+	ErrNotRetryable = errors.New("error is not retryable")
+	// ErrStepTimeout превышено время выполнения шага
+	ErrStepTimeout = errors.New("step timeout")
+	// ErrPanicAttemptsExhausted исчерпаны попытки выполнения шага, завершившегося паникой
+	ErrPanicAttemptsExhausted = errors.New("panic attempts exhausted")
+	// ErrStateCancelled стейт отменен во время выполнения шага
+	ErrStateCancelled = errors.New("state cancelled")
+	// ErrStateMoved стейт переведен на другой шаг через MoveToStep во время выполнения шага
+	ErrStateMoved = errors.New("state moved to another step")
+	// ErrPaused выполнение стейта или всех стейтов его типа приостановлено
+	ErrPaused = errors.New("state is paused")
+	// ErrNotFailed операция допустима только для стейта в статусе фейла
+	ErrNotFailed = errors.New("state is not failed")
+	// ErrUnknownStep шаг не зарегистрирован в StepRegistration.Steps
+	ErrUnknownStep = errors.New("unknown step")
+	// ErrTransitionLimitExceeded превышено количество переходов между шагами за один вызов Complete
+	ErrTransitionLimitExceeded = errors.New("transition limit exceeded")
+	// ErrStepCycle стейт вернулся на пройденный шаг без изменения данных
+	ErrStepCycle = errors.New("step cycle detected")
+	// ErrNoDataSnapshots в истории выполнения нет снимков данных для воспроизведения шагов
+	ErrNoDataSnapshots = errors.New("no data snapshots in history")
 )
@@ -0,0 +1,71 @@
+package statemachine
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/samber/lo"
+
+	"github.com/kkiling/statemachine/storage"
+)
+
+// StepExecution запись истории выполнения шага стейта
+type StepExecution[StepT ~string] struct {
+	// StartedAt время начала выполнения шага
+	StartedAt time.Time
+	// CompletedAt время завершения выполнения шага
+	CompletedAt time.Time
+	// Duration длительность выполнения шага
+	Duration time.Duration
+	// Error ошибка выполнения шага (nil - шаг выполнен без ошибки)
+	Error *string
+	// PreviousStep шаг, который выполнялся (пустой для ручного перезапуска зафейленного стейта)
+	PreviousStep StepT
+	// NextStep шаг, на который перешел стейт (nil - стейт остался на шаге или завершился)
+	NextStep *StepT
+	// Reason причина ручного вмешательства оператора (nil - шаг выполнен стейт машиной)
+	Reason *string
+	// DataBefore данные стейта (JSON) до выполнения шага, сохраняются при включенном StepRegistration.DataSnapshots
+	DataBefore json.RawMessage
+	// DataAfter данные стейта (JSON) после выполнения шага, сохраняются при включенном StepRegistration.DataSnapshots
+	DataAfter json.RawMessage
+}
+
+// History история выполнения шагов стейта в порядке начала выполнения,
+// включая ручные действия оператора (отмена, повтор, переход на шаг).
+// Если стейт не найден возвращает ErrNotFound
+func (i *StateMachine[DataT, FailDataT, MetaDataT, StepT, TypeT, CreateOptionsT]) History(
+	ctx context.Context,
+	stateID uuid.UUID,
+) ([]StepExecution[StepT], error) {
+	findState, err := i.GetStateByID(ctx, stateID)
+	if err != nil {
+		return nil, fmt.Errorf("GetStateByID: %w", err)
+	}
+	if findState == nil {
+		return nil, fmt.Errorf("state not found: %w", ErrNotFound)
+	}
+
+	infos, err := i.storage.GetStepExecuteInfos(ctx, stateID)
+	if err != nil {
+		return nil, fmt.Errorf("storage.GetStepExecuteInfos: %w", err)
+	}
+
+	return lo.Map(infos, func(item storage.StepExecuteInfo, _ int) StepExecution[StepT] {
+		return StepExecution[StepT]{
+			StartedAt:    item.StartExecutedAt,
+			CompletedAt:  item.CompleteExecutedAt,
+			Duration:     item.CompleteExecutedAt.Sub(item.StartExecutedAt),
+			Error:        item.Error,
+			PreviousStep: StepT(item.PreviewStep),
+			NextStep: lo.Ternary(item.NextStep != nil,
+				lo.ToPtr(StepT(lo.FromPtr(item.NextStep))), nil),
+			Reason:     item.Reason,
+			DataBefore: item.DataBefore,
+			DataAfter:  item.DataAfter,
+		}
+	}), nil
+}
@@ -0,0 +1,136 @@
+package statemachine
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/samber/lo"
+	"github.com/stretchr/testify/require"
+
+	"github.com/kkiling/statemachine/memstore"
+)
+
+// historyTestClock каждое обращение сдвигает время на секунду
+type historyTestClock struct {
+	now time.Time
+}
+
+func (c *historyTestClock) Now() time.Time {
+	c.now = c.now.Add(time.Second)
+	return c.now
+}
+
+func TestStateMachine_History(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	t.Run("step executions and operator actions", func(t *testing.T) {
+		t.Parallel()
+
+		sm := NewService[int, any, any, string, string, testOptions](Config{}, memstore.NewStorage(), newRetryFailedTestRunner())
+		sm.SetClock(&historyTestClock{now: time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)})
+
+		state, err := sm.Create(ctx, testOptions{})
+		require.NoError(t, err)
+		_, _, err = sm.Complete(ctx, state.ID)
+		require.NoError(t, err)
+		_, err = sm.Retry(ctx, state.ID, "", RetryOptions[int]{Data: lo.ToPtr(1), Reason: "data fixed"})
+		require.NoError(t, err)
+		_, _, err = sm.Complete(ctx, state.ID)
+		require.NoError(t, err)
+
+		history, err := sm.History(ctx, state.ID)
+		require.NoError(t, err)
+		require.Len(t, history, 4)
+
+		// first -> second
+		require.Equal(t, "first", history[0].PreviousStep)
+		require.Equal(t, lo.ToPtr("second"), history[0].NextStep)
+		require.Equal(t, time.Second, history[0].Duration)
+		require.Equal(t, history[0].CompletedAt.Sub(history[0].StartedAt), history[0].Duration)
+		require.Nil(t, history[0].Reason)
+		// second фейлится
+		require.Equal(t, "second", history[1].PreviousStep)
+		require.Nil(t, history[1].NextStep)
+		// Ручной перезапуск оператором
+		require.Equal(t, "", history[2].PreviousStep)
+		require.Equal(t, lo.ToPtr("second"), history[2].NextStep)
+		require.Equal(t, lo.ToPtr("data fixed"), history[2].Reason)
+		// second завершает стейт
+		require.Equal(t, "second", history[3].PreviousStep)
+		require.Nil(t, history[3].NextStep)
+
+		for i := 1; i < len(history); i++ {
+			require.False(t, history[i].StartedAt.Before(history[i-1].StartedAt))
+		}
+		// Снимки данных по умолчанию не сохраняются
+		for _, item := range history {
+			require.Nil(t, item.DataBefore)
+			require.Nil(t, item.DataAfter)
+		}
+	})
+
+	t.Run("data snapshots", func(t *testing.T) {
+		t.Parallel()
+
+		sm := NewService[int, any, any, string, string, testOptions](Config{}, memstore.NewStorage(), newRetryFailedTestRunner().withDataSnapshots())
+
+		state, err := sm.Create(ctx, testOptions{})
+		require.NoError(t, err)
+		_, _, err = sm.Complete(ctx, state.ID)
+		require.NoError(t, err)
+		_, err = sm.Retry(ctx, state.ID, "", RetryOptions[int]{Data: lo.ToPtr(1), Reason: "data fixed"})
+		require.NoError(t, err)
+		_, _, err = sm.Complete(ctx, state.ID)
+		require.NoError(t, err)
+
+		history, err := sm.History(ctx, state.ID)
+		require.NoError(t, err)
+		require.Len(t, history, 4)
+
+		snapshots := lo.Map(history, func(item StepExecution[string], _ int) [2]string {
+			return [2]string{string(item.DataBefore), string(item.DataAfter)}
+		})
+		require.Equal(t, [][2]string{
+			{"0", "0"},
+			{"0", "0"},
+			// Данные исправлены оператором при повторном запуске
+			{"0", "1"},
+			{"1", "1"},
+		}, snapshots)
+	})
+
+	t.Run("step error", func(t *testing.T) {
+		t.Parallel()
+
+		stepErr := errors.New("external service unavailable")
+		sm := NewService[int, any, any, string, string, testOptions](Config{}, memstore.NewStorage(), newStepTestRunner("history_test", testStepperStep{
+			OnStep: func(_ context.Context, stepContext StepContext[int, any, any, string, string]) *StepResult[int, any, any, string] {
+				return stepContext.Error(stepErr)
+			},
+		}))
+
+		state, err := sm.Create(ctx, testOptions{})
+		require.NoError(t, err)
+		_, executeErr, err := sm.Complete(ctx, state.ID)
+		require.NoError(t, err)
+		require.ErrorIs(t, executeErr, stepErr)
+
+		history, err := sm.History(ctx, state.ID)
+		require.NoError(t, err)
+		require.Len(t, history, 1)
+		require.Equal(t, lo.ToPtr(stepErr.Error()), history[0].Error)
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		t.Parallel()
+
+		sm := NewService[int, any, any, string, string, testOptions](Config{}, memstore.NewStorage(), newRetryFailedTestRunner())
+		_, err := sm.History(ctx, uuid.New())
+		require.ErrorIs(t, err, ErrNotFound)
+	})
+}
@@ -13,7 +13,8 @@ import (
 	"github.com/stretchr/testify/require"
 
 	"github.com/kkiling/statemachine"
-	"github.com/kkiling/statemachine/internal/storage"
+	"github.com/kkiling/statemachine/memstore"
+	"github.com/kkiling/statemachine/storage"
 )
 
 func marshalData(data Data) []byte {
@@ -33,6 +34,24 @@ func toData(data []byte) Data {
 	return res
 }
 
+// expectClaimState ожидание захвата стейта в аренду при вызове Complete
+func expectClaimState(deps *testDeps, stateID uuid.UUID, state *storage.State, err error) {
+	deps.clock.EXPECT().Now().Return(time.Now())
+	deps.storageMock.EXPECT().ClaimState(gomock.Any(), stateID, gomock.Any()).Return(state, err)
+	if err == nil {
+		deps.storageMock.EXPECT().ReleaseState(gomock.Any(), stateID, gomock.Any())
+		// Приостановка типа проверяется только для нетерминальных стейтов
+		deps.storageMock.EXPECT().IsTypePaused(gomock.Any(), string(TestType)).Return(false, nil).MaxTimes(1)
+	}
+}
+
+// expectStateUnchanged ожидание проверки стейта перед выполнением следующего шага:
+// стейт не изменен другим обработчиком (версия равна version) и не приостановлен
+func expectStateUnchanged(deps *testDeps, stateID uuid.UUID, version int64) {
+	deps.storageMock.EXPECT().GetStateByID(gomock.Any(), stateID).Return(&storage.State{ID: stateID, Version: version}, nil)
+	deps.storageMock.EXPECT().IsTypePaused(gomock.Any(), string(TestType)).Return(false, nil)
+}
+
 func TestTaskRunner_MockDb(t *testing.T) {
 	t.Parallel()
 	var (
@@ -113,7 +132,7 @@ func TestTaskRunner_MockDb(t *testing.T) {
 	// Попытка выполнить Complete для не существующего стейта
 	t.Run("complete not found state", func(t *testing.T) {
 		// Стейт не найден
-		deps.storageMock.EXPECT().GetStateByID(gomock.Any(), stateID).Return(nil, storagebase.ErrNotFound)
+		expectClaimState(deps, stateID, nil, storagebase.ErrNotFound)
 		completeState, executeErr, err := deps.service.Complete(deps.ctx, stateID)
 		require.Error(t, err)
 		require.ErrorIs(t, err, statemachine.ErrNotFound)
@@ -125,7 +144,7 @@ func TestTaskRunner_MockDb(t *testing.T) {
 	t.Run("complete not found state", func(t *testing.T) {
 		init := initStateDb()
 		init.Status = statemachine.CompletedStatus
-		deps.storageMock.EXPECT().GetStateByID(gomock.Any(), stateID).Return(init, nil)
+		expectClaimState(deps, stateID, init, nil)
 
 		completeState, executeErr, err := deps.service.Complete(deps.ctx, stateID)
 		require.Error(t, err)
@@ -147,7 +166,7 @@ func TestTaskRunner_MockDb(t *testing.T) {
 			completeExecutedAt2 = now.Add(3 * time.Second)
 		)
 		// Достаем из базы свежесозданный стейт
-		deps.storageMock.EXPECT().GetStateByID(gomock.Any(), stateID).Return(initStateDb(), nil)
+		expectClaimState(deps, stateID, initStateDb(), nil)
 
 		// Первое выполнение - шаг FirstStep
 		deps.clock.EXPECT().Now().Return(startExecutedAt1)
@@ -180,6 +199,7 @@ func TestTaskRunner_MockDb(t *testing.T) {
 		})
 
 		// Второе выполнение - шаг TestErrorStep
+		expectStateUnchanged(deps, stateID, 1)
 		deps.clock.EXPECT().Now().Return(startExecutedAt2)
 		deps.clock.EXPECT().Now().Return(completeExecutedAt2)
 		//
@@ -206,6 +226,10 @@ func TestTaskRunner_MockDb(t *testing.T) {
 			FailData: []byte{},
 			MetaData: []byte{},
 			Error:    lo.ToPtr("counter eq 2"),
+			Version:  1, // Версия увеличилась после сохранения шага FirstStep
+			// Первое выполнение шага TestErrorStep завершилось ошибкой
+			FailedAttempts: 1,
+			StepAttempts:   1,
 		})
 
 		completeState, executeErr, err := deps.service.Complete(deps.ctx, stateID)
@@ -248,7 +272,7 @@ func TestTaskRunner_MockDb(t *testing.T) {
 			Title:   "start title",
 			Amount:  42,
 		})
-		deps.storageMock.EXPECT().GetStateByID(gomock.Any(), stateID).Return(init, nil)
+		expectClaimState(deps, stateID, init, nil)
 
 		// Первое выполнение - шаг TestErrorStep
 		deps.clock.EXPECT().Now().Return(startExecutedAt)
@@ -270,12 +294,13 @@ func TestTaskRunner_MockDb(t *testing.T) {
 			Amount:  42,
 		}
 		deps.storageMock.EXPECT().UpdateState(gomock.Any(), stateID, storage.UpdateState{
-			UpdatedAt: init.UpdatedAt, // Шаг не изменился, по этому время остается старым
-			Status:    statemachine.InProgressStatus,
-			Step:      string(TestErrorStep),
-			Data:      marshalData(resultData),
-			FailData:  []byte{},
-			MetaData:  []byte{},
+			UpdatedAt:    init.UpdatedAt, // Шаг не изменился, по этому время остается старым
+			Status:       statemachine.InProgressStatus,
+			Step:         string(TestErrorStep),
+			Data:         marshalData(resultData),
+			FailData:     []byte{},
+			MetaData:     []byte{},
+			StepAttempts: 1,
 		})
 
 		completeState, executeErr, err := deps.service.Complete(deps.ctx, stateID)
@@ -310,7 +335,7 @@ func TestTaskRunner_MockDb(t *testing.T) {
 			Title:   "start title",
 			Amount:  42,
 		})
-		deps.storageMock.EXPECT().GetStateByID(gomock.Any(), stateID).Return(init, nil)
+		expectClaimState(deps, stateID, init, nil)
 
 		// шаг TestErrorStep
 		deps.clock.EXPECT().Now().Return(startExecutedAt)
@@ -324,6 +349,7 @@ func TestTaskRunner_MockDb(t *testing.T) {
 		deps.storageMock.EXPECT().SaveStepExecuteInfo(gomock.Any(), gomock.Any())
 		deps.storageMock.EXPECT().UpdateState(gomock.Any(), gomock.Any(), gomock.Any())
 
+		expectStateUnchanged(deps, stateID, 1)
 		// Шаг TestNoSaveChangeStep
 		// Даже не хотим пере тестировать время, оставляем старые значения
 		deps.clock.EXPECT().Now().Return(startExecutedAt)
@@ -347,8 +373,10 @@ func TestTaskRunner_MockDb(t *testing.T) {
 			Data:      marshalData(resultData),
 			FailData:  []byte{},
 			MetaData:  []byte{},
+			Version:   1,
 		}) //
 
+		expectStateUnchanged(deps, stateID, 2)
 		// шаг WaitingInputStep
 		deps.clock.EXPECT().Now().Return(startExecutedAt)
 		deps.clock.EXPECT().Now().Return(completeExecutedAt)
@@ -387,7 +415,7 @@ func TestTaskRunner_MockDb(t *testing.T) {
 			Title:   "start title",
 			Amount:  42,
 		})
-		deps.storageMock.EXPECT().GetStateByID(gomock.Any(), stateID).Return(init, nil)
+		expectClaimState(deps, stateID, init, nil)
 
 		// шаг WaitingInputStep
 		deps.clock.EXPECT().Now().Return(startExecutedAt)
@@ -426,7 +454,7 @@ func TestTaskRunner_MockDb(t *testing.T) {
 			Title:   "start title",
 			Amount:  42,
 		})
-		deps.storageMock.EXPECT().GetStateByID(gomock.Any(), stateID).Return(init, nil)
+		expectClaimState(deps, stateID, init, nil)
 
 		// шаг WaitingInputStep
 		deps.clock.EXPECT().Now().Return(startExecutedAt)
@@ -454,6 +482,53 @@ func TestTaskRunner_MockDb(t *testing.T) {
 			Amount:  100,
 		})
 	})
+
+	// Попытка выполнить Complete для отложенного стейта, время выполнения которого еще не наступило
+	t.Run("complete not ready state", func(t *testing.T) {
+		init := initStateDb()
+		init.Status = statemachine.InProgressStatus
+		init.NextRunAt = lo.ToPtr(time.Now().Add(time.Hour))
+		expectClaimState(deps, stateID, init, nil)
+
+		completeState, executeErr, err := deps.service.Complete(deps.ctx, stateID)
+		require.Error(t, err)
+		require.ErrorIs(t, err, statemachine.ErrNotReady)
+		require.NoError(t, executeErr)
+		require.Nil(t, completeState)
+	})
+
+	// Стейт изменен другим обработчиком во время выполнения шага
+	t.Run("complete stops on concurrent modification", func(t *testing.T) {
+		var (
+			now = time.Now()
+			// Фиксация времени начала выполнения шага
+			startExecutedAt = now
+			// Фиксация времени выполнения шага
+			completeExecutedAt = now.Add(time.Second)
+		)
+		expectClaimState(deps, stateID, initStateDb(), nil)
+
+		// шаг FirstStep
+		deps.clock.EXPECT().Now().Return(startExecutedAt)
+		deps.clock.EXPECT().Now().Return(completeExecutedAt)
+
+		deps.storageMock.EXPECT().RunTransaction(gomock.Any(), gomock.Any()).
+			DoAndReturn(func(ctx context.Context, txFunc func(context.Context) error) error {
+				return txFunc(ctx)
+			})
+		deps.storageMock.EXPECT().SaveStepExecuteInfo(gomock.Any(), gomock.Any())
+		// Версия в базе уже не совпадает, следующий шаг выполняться не должен
+		deps.storageMock.EXPECT().UpdateState(gomock.Any(), stateID, gomock.Any()).
+			Return(storage.ErrConcurrentModification)
+		// Стейт изменен, но не отменен
+		deps.storageMock.EXPECT().GetStateByID(gomock.Any(), stateID).Return(initStateDb(), nil)
+
+		completeState, executeErr, err := deps.service.Complete(deps.ctx, stateID)
+		require.Error(t, err)
+		require.ErrorIs(t, err, statemachine.ErrConcurrentModification)
+		require.NoError(t, executeErr)
+		require.Nil(t, completeState)
+	})
 }
 
 func TestTaskRunner_RealDB(t *testing.T) {
@@ -493,6 +568,9 @@ func TestTaskRunner_RealDB(t *testing.T) {
 		// Выполняем первый прогон стейт машины
 		// выполняем шаг FirstStep и выходим с ошибкой на шаге TestErrorStep
 
+		// Захват стейта в аренду
+		deps.clock.EXPECT().Now().Return(now)
+
 		// Первое выполнение - шаг FirstStep
 		deps.clock.EXPECT().Now().Return(startExecutedAt1)
 		deps.clock.EXPECT().Now().Return(completeExecutedAt1)
@@ -549,3 +627,56 @@ func TestTaskRunner_RealDB(t *testing.T) {
 		require.Nil(t, infos[1].NextStep)
 	})
 }
+
+func TestTaskRunner_MemStore(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	memStorage := memstore.NewStorage()
+	service := NewState(memStorage)
+
+	newState, err := service.Create(ctx, &CreateOptions{
+		IdempotencyKey: uuid.NewString(),
+		Title:          "Custom title",
+		Amount:         42,
+	})
+	require.NoError(t, err)
+
+	// FirstStep, затем ошибка на шаге TestErrorStep
+	_, executeErr, err := service.Complete(ctx, newState.ID)
+	require.NoError(t, err)
+	require.ErrorContains(t, executeErr, "counter eq 2")
+
+	// Повтор шага TestErrorStep без продвижения
+	completeState, executeErr, err := service.Complete(ctx, newState.ID)
+	require.NoError(t, err)
+	require.NoError(t, executeErr)
+	require.Equal(t, TestErrorStep, completeState.Step)
+
+	// Проходим до шага ожидания опций
+	completeState, executeErr, err = service.Complete(ctx, newState.ID)
+	require.NoError(t, err)
+	require.NoError(t, executeErr)
+	require.Equal(t, WaitingInputStep, completeState.Step)
+
+	completeState, executeErr, err = service.Complete(ctx, newState.ID, WaitingInputOptions{
+		IsComplete: true,
+		NewAmount:  100,
+	})
+	require.NoError(t, err)
+	require.NoError(t, executeErr)
+	require.Equal(t, statemachine.CompletedStatus, completeState.Status)
+	require.Equal(t, Data{
+		Counter: 4,
+		Title:   "start title",
+		Amount:  100,
+	}, completeState.Data)
+
+	findState, err := service.GetStateByID(ctx, newState.ID)
+	require.NoError(t, err)
+	require.Equal(t, completeState, findState)
+
+	infos, err := memStorage.GetStepExecuteInfos(ctx, newState.ID)
+	require.NoError(t, err)
+	require.Len(t, infos, 7)
+}
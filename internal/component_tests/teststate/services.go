@@ -9,7 +9,7 @@ type State = statemachine.State[Data, interface{}, interface{}, StepType, Type]
 type Step = statemachine.Step[Data, interface{}, interface{}, StepType, Type]
 type StepRegistration = statemachine.StepRegistration[Data, interface{}, interface{}, StepType, Type]
 type StepContext = statemachine.StepContext[Data, interface{}, interface{}, StepType, Type]
-type StepResult = statemachine.StepResult[Data, StepType]
+type StepResult = statemachine.StepResult[Data, interface{}, interface{}, StepType]
 type StateMachineService = statemachine.StateMachine[Data, interface{}, interface{}, StepType, Type, *CreateOptions]
 
 func NewState(stateMachineStorage statemachine.Storage) *StateMachineService {
@@ -0,0 +1,92 @@
+package migrate
+
+import (
+	"cmp"
+	"fmt"
+	"io/fs"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+const (
+	upAnnotation   = "-- +goose Up"
+	downAnnotation = "-- +goose Down"
+)
+
+// Migration миграция схемы
+type Migration struct {
+	// Version версия миграции, префикс имени файла
+	Version int64
+	// Name имя файла миграции
+	Name string
+	// Up sql применения миграции
+	Up string
+}
+
+// Load загружает миграции формата goose из директории dir, отсортированные по версии
+func Load(fsys fs.FS, dir string) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return nil, fmt.Errorf("fs.ReadDir: %w", err)
+	}
+
+	res := make([]Migration, 0, len(entries))
+	for _, entry := range entries {
+		if entry.IsDir() || path.Ext(entry.Name()) != ".sql" {
+			continue
+		}
+
+		prefix, _, ok := strings.Cut(entry.Name(), "_")
+		if !ok {
+			return nil, fmt.Errorf("invalid migration name %s", entry.Name())
+		}
+		version, err := strconv.ParseInt(prefix, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid migration version %s: %w", entry.Name(), err)
+		}
+
+		content, err := fs.ReadFile(fsys, path.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, fmt.Errorf("fs.ReadFile: %w", err)
+		}
+
+		up, err := parseUp(string(content))
+		if err != nil {
+			return nil, fmt.Errorf("migration %s: %w", entry.Name(), err)
+		}
+
+		res = append(res, Migration{
+			Version: version,
+			Name:    entry.Name(),
+			Up:      up,
+		})
+	}
+
+	slices.SortFunc(res, func(a, b Migration) int {
+		return cmp.Compare(a.Version, b.Version)
+	})
+	for i := 1; i < len(res); i++ {
+		if res[i].Version == res[i-1].Version {
+			return nil, fmt.Errorf("duplicate migration version %d", res[i].Version)
+		}
+	}
+
+	return res, nil
+}
+
+// parseUp возвращает секцию Up миграции
+func parseUp(content string) (string, error) {
+	_, up, ok := strings.Cut(content, upAnnotation)
+	if !ok {
+		return "", fmt.Errorf("annotation %q not found", upAnnotation)
+	}
+	up, _, _ = strings.Cut(up, downAnnotation)
+
+	up = strings.TrimSpace(up)
+	if up == "" {
+		return "", fmt.Errorf("empty up section")
+	}
+	return up, nil
+}
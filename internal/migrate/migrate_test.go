@@ -0,0 +1,102 @@
+package migrate
+
+import (
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/kkiling/statemachine/migrations"
+)
+
+func TestLoad(t *testing.T) {
+	t.Parallel()
+
+	t.Run("sorted by version", func(t *testing.T) {
+		t.Parallel()
+
+		fsys := fstest.MapFS{
+			"db/20_second.sql": {Data: []byte("-- +goose Up\nCREATE TABLE b (id INT);\n-- +goose Down\nDROP TABLE b;\n")},
+			"db/10_first.sql":  {Data: []byte("-- +goose Up\n-- +goose StatementBegin\nCREATE TABLE a (id INT);\n-- +goose StatementEnd\n")},
+			"db/readme.md":     {Data: []byte("не миграция")},
+		}
+
+		res, err := Load(fsys, "db")
+		require.NoError(t, err)
+		require.Equal(t, []Migration{
+			{
+				Version: 10,
+				Name:    "10_first.sql",
+				Up:      "-- +goose StatementBegin\nCREATE TABLE a (id INT);\n-- +goose StatementEnd",
+			},
+			{
+				Version: 20,
+				Name:    "20_second.sql",
+				Up:      "CREATE TABLE b (id INT);",
+			},
+		}, res)
+	})
+
+	t.Run("sorted by large versions", func(t *testing.T) {
+		t.Parallel()
+
+		// Разница версий не помещается в int на 32-битных платформах
+		fsys := fstest.MapFS{
+			"db/4294967297_second.sql": {Data: []byte("-- +goose Up\nCREATE TABLE b (id INT);\n")},
+			"db/5_first.sql":           {Data: []byte("-- +goose Up\nCREATE TABLE a (id INT);\n")},
+		}
+
+		res, err := Load(fsys, "db")
+		require.NoError(t, err)
+		require.Len(t, res, 2)
+		require.Equal(t, int64(5), res[0].Version)
+		require.Equal(t, int64(4294967297), res[1].Version)
+	})
+
+	t.Run("invalid version", func(t *testing.T) {
+		t.Parallel()
+
+		fsys := fstest.MapFS{
+			"db/first.sql": {Data: []byte("-- +goose Up\nSELECT 1;\n")},
+		}
+
+		_, err := Load(fsys, "db")
+		require.Error(t, err)
+	})
+
+	t.Run("duplicate version", func(t *testing.T) {
+		t.Parallel()
+
+		fsys := fstest.MapFS{
+			"db/10_first.sql":  {Data: []byte("-- +goose Up\nSELECT 1;\n")},
+			"db/10_second.sql": {Data: []byte("-- +goose Up\nSELECT 2;\n")},
+		}
+
+		_, err := Load(fsys, "db")
+		require.Error(t, err)
+	})
+
+	t.Run("without up section", func(t *testing.T) {
+		t.Parallel()
+
+		fsys := fstest.MapFS{
+			"db/10_first.sql": {Data: []byte("-- +goose Down\nDROP TABLE a;\n")},
+		}
+
+		_, err := Load(fsys, "db")
+		require.Error(t, err)
+	})
+
+	t.Run("embedded migrations", func(t *testing.T) {
+		t.Parallel()
+
+		// Встроенные миграции библиотеки должны корректно загружаться
+		pg, err := Load(migrations.PostgreSQL, "postgresql")
+		require.NoError(t, err)
+		require.NotEmpty(t, pg)
+
+		sqlite, err := Load(migrations.SQLite, "sqlite")
+		require.NoError(t, err)
+		require.NotEmpty(t, sqlite)
+	})
+}
@@ -0,0 +1,86 @@
+package postgresql
+
+import (
+	"context"
+	"regexp"
+	"sync"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+
+	"github.com/kkiling/statemachine/internal/storage/statemachine"
+)
+
+// Config расположение таблиц хранилища в базе
+type Config struct {
+	// Schema схема, в которой расположены таблицы. Если не задана, используется search_path соединения
+	Schema string
+	// TablePrefix префикс имен таблиц и индексов хранилища
+	TablePrefix string
+}
+
+var (
+	// tableRe ссылки на таблицы хранилища в запросах и миграциях
+	tableRe = regexp.MustCompile(`(?i)\b(FROM|INTO|UPDATE|JOIN|REFERENCES|ON|TABLE(?:\s+IF(?:\s+NOT)?\s+EXISTS)?)(\s+)(state|step_execute_info|state_type_pause|pause_event|statemachine_schema_migrations)\b`)
+	// indexRe имена индексов, которые должны быть уникальны в пределах схемы
+	indexRe = regexp.MustCompile(`\bidx_\w+`)
+)
+
+// isDefault таблицы расположены по умолчанию и запросы не требуют изменений
+func (c Config) isDefault() bool {
+	return c.Schema == "" && c.TablePrefix == ""
+}
+
+// table полное имя таблицы с учетом схемы и префикса
+func (c Config) table(name string) string {
+	if c.Schema == "" {
+		return pgx.Identifier{c.TablePrefix + name}.Sanitize()
+	}
+	return pgx.Identifier{c.Schema, c.TablePrefix + name}.Sanitize()
+}
+
+// rewrite заменяет имена таблиц и индексов в sql на имена из настроек.
+// Заменяются только ссылки на таблицы после ключевых слов (FROM, UPDATE, JOIN и т.д.), поэтому в запросах
+// нельзя квалифицировать колонки именем таблицы (state.id) вместо псевдонима и упоминать имена таблиц
+// в строковых литералах. Соблюдение этого для всех запросов проверяет TestConfig_RewriteGeneratedQueries
+func (c Config) rewrite(sql string) string {
+	sql = tableRe.ReplaceAllStringFunc(sql, func(match string) string {
+		parts := tableRe.FindStringSubmatch(match)
+		return parts[1] + parts[2] + c.table(parts[3])
+	})
+	if c.TablePrefix != "" {
+		sql = indexRe.ReplaceAllStringFunc(sql, func(match string) string {
+			return pgx.Identifier{c.TablePrefix + match}.Sanitize()
+		})
+	}
+	return sql
+}
+
+// rewriteDBTX выполняет сгенерированные запросы над таблицами из настроек
+type rewriteDBTX struct {
+	db      statemachine.DBTX
+	cfg     Config
+	queries *sync.Map
+}
+
+// sql запрос с замененными именами таблиц, результат замены кэшируется
+func (d rewriteDBTX) sql(query string) string {
+	if res, ok := d.queries.Load(query); ok {
+		return res.(string)
+	}
+	res := d.cfg.rewrite(query)
+	d.queries.Store(query, res)
+	return res
+}
+
+func (d rewriteDBTX) Exec(ctx context.Context, query string, args ...interface{}) (pgconn.CommandTag, error) {
+	return d.db.Exec(ctx, d.sql(query), args...)
+}
+
+func (d rewriteDBTX) Query(ctx context.Context, query string, args ...interface{}) (pgx.Rows, error) {
+	return d.db.Query(ctx, d.sql(query), args...)
+}
+
+func (d rewriteDBTX) QueryRow(ctx context.Context, query string, args ...interface{}) pgx.Row {
+	return d.db.QueryRow(ctx, d.sql(query), args...)
+}
@@ -0,0 +1,154 @@
+package postgresql
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/kkiling/statemachine/internal/migrate"
+	"github.com/kkiling/statemachine/migrations"
+)
+
+func TestConfig_Rewrite(t *testing.T) {
+	t.Parallel()
+
+	t.Run("default", func(t *testing.T) {
+		t.Parallel()
+
+		require.True(t, Config{}.isDefault())
+		require.Equal(t, `SELECT id FROM "state" WHERE id = $1`, Config{}.rewrite("SELECT id FROM state WHERE id = $1"))
+	})
+
+	t.Run("schema and prefix", func(t *testing.T) {
+		t.Parallel()
+
+		cfg := Config{Schema: "billing", TablePrefix: "sm_"}
+
+		testCases := []struct {
+			sql      string
+			expected string
+		}{
+			{
+				sql:      "SELECT s.id FROM state s WHERE s.state_id = $1",
+				expected: `SELECT s.id FROM "billing"."sm_state" s WHERE s.state_id = $1`,
+			},
+			{
+				sql:      "INSERT INTO step_execute_info (state_id) VALUES ($1)",
+				expected: `INSERT INTO "billing"."sm_step_execute_info" (state_id) VALUES ($1)`,
+			},
+			{
+				sql:      "UPDATE state SET step = $1",
+				expected: `UPDATE "billing"."sm_state" SET step = $1`,
+			},
+			{
+				sql:      "CREATE TABLE IF NOT EXISTS state (id UUID PRIMARY KEY)",
+				expected: `CREATE TABLE IF NOT EXISTS "billing"."sm_state" (id UUID PRIMARY KEY)`,
+			},
+			{
+				sql:      "ALTER TABLE state ADD COLUMN IF NOT EXISTS version BIGINT",
+				expected: `ALTER TABLE "billing"."sm_state" ADD COLUMN IF NOT EXISTS version BIGINT`,
+			},
+			{
+				sql:      "CREATE INDEX IF NOT EXISTS idx_step_execute_state_id ON step_execute_info(state_id)",
+				expected: `CREATE INDEX IF NOT EXISTS "sm_idx_step_execute_state_id" ON "billing"."sm_step_execute_info"(state_id)`,
+			},
+			{
+				sql:      "FOREIGN KEY (state_id) REFERENCES state(id) ON DELETE CASCADE",
+				expected: `FOREIGN KEY (state_id) REFERENCES "billing"."sm_state"(id) ON DELETE CASCADE`,
+			},
+		}
+
+		for _, tc := range testCases {
+			require.Equal(t, tc.expected, cfg.rewrite(tc.sql))
+		}
+	})
+
+	t.Run("all tables replaced", func(t *testing.T) {
+		t.Parallel()
+
+		cfg := Config{Schema: "billing"}
+
+		list, err := migrate.Load(migrations.PostgreSQL, "postgresql")
+		require.NoError(t, err)
+
+		// Исходные запросы, из которых сгенерирован пакет statemachine
+		queries, err := os.ReadFile("../../../query.sql")
+		require.NoError(t, err)
+
+		sqls := []string{string(queries)}
+		for _, m := range list {
+			sqls = append(sqls, m.Up)
+		}
+
+		// В запросах и миграциях не должно остаться ссылок на таблицы вне схемы
+		for _, sql := range sqls {
+			res := cfg.rewrite(sql)
+			require.NotRegexp(t, `(?i)(FROM|INTO|UPDATE|REFERENCES|ON|TABLE|EXISTS)\s+(state|step_execute_info|state_type_pause|pause_event)\b`, res)
+		}
+	})
+}
+
+// generatedQueries запросы, сгенерированные sqlc в пакете statemachine, по имени запроса
+func generatedQueries(t *testing.T) map[string]string {
+	t.Helper()
+
+	file, err := parser.ParseFile(token.NewFileSet(), "../statemachine/query.sql.go", nil, 0)
+	require.NoError(t, err)
+
+	queries := make(map[string]string)
+	ast.Inspect(file, func(node ast.Node) bool {
+		spec, ok := node.(*ast.ValueSpec)
+		if !ok || len(spec.Values) != 1 {
+			return true
+		}
+		lit, ok := spec.Values[0].(*ast.BasicLit)
+		if !ok || lit.Kind != token.STRING {
+			return true
+		}
+		query, err := strconv.Unquote(lit.Value)
+		require.NoError(t, err)
+		if strings.HasPrefix(query, "-- name: ") {
+			queries[spec.Names[0].Name] = query
+		}
+		return true
+	})
+	return queries
+}
+
+func TestConfig_RewriteGeneratedQueries(t *testing.T) {
+	t.Parallel()
+
+	var (
+		cfg = Config{Schema: "billing", TablePrefix: "sm_"}
+		// Комментарии, строковые литералы и идентификаторы в кавычках, в которых не ищутся ссылки на таблицы
+		ignoredRe = regexp.MustCompile(`--[^\n]*|'(?:[^']|'')*'|"(?:[^"]|"")*"`)
+		// Любое упоминание имени таблицы: ссылка на таблицу или квалификатор колонки (state.id)
+		tableNameRe = regexp.MustCompile(`(?i)\b(state|step_execute_info|state_type_pause|pause_event)\b`)
+	)
+
+	// Квалификатор колонки не заменяется, такие запросы должны обнаруживаться проверкой
+	require.Regexp(t, tableNameRe, ignoredRe.ReplaceAllString(cfg.rewrite("SELECT state.id FROM state"), ""))
+
+	queries := generatedQueries(t)
+	// Каждый запрос из query.sql должен быть проверен
+	source, err := os.ReadFile("../../../query.sql")
+	require.NoError(t, err)
+	require.Len(t, queries, strings.Count(string(source), "-- name: "))
+
+	for name, query := range queries {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			res := cfg.rewrite(query)
+			require.Contains(t, res, `"billing"."sm_`)
+			require.NotRegexp(t, tableNameRe, ignoredRe.ReplaceAllString(res, ""))
+		})
+	}
+}
@@ -0,0 +1,63 @@
+package postgresql
+
+import (
+	"context"
+
+	"github.com/samber/lo"
+
+	"github.com/kkiling/statemachine/internal/storage/statemachine"
+	"github.com/kkiling/statemachine/storage"
+)
+
+func (s *Storage) ListStates(ctx context.Context, filter storage.ListStatesFilter) ([]storage.State, error) {
+	queries := s.getQueries(ctx)
+
+	params := statemachine.ListStatesParams{
+		Type: filter.Type,
+		Statuses: lo.Map(filter.Statuses, func(item uint8, _ int) int {
+			return int(item)
+		}),
+		Steps:       filter.Steps,
+		CreatedFrom: toTimestamptz(filter.CreatedFrom),
+		CreatedTo:   toTimestamptz(filter.CreatedTo),
+		UpdatedFrom: toTimestamptz(filter.UpdatedFrom),
+		UpdatedTo:   toTimestamptz(filter.UpdatedTo),
+		HasError:    filter.HasError,
+		LimitCount:  int32(filter.Limit),
+	}
+	if params.Steps == nil {
+		params.Steps = []string{}
+	}
+	if filter.After != nil {
+		params.AfterCreatedAt = toTimestamptz(&filter.After.CreatedAt)
+		params.AfterID = filter.After.ID
+	}
+
+	res, err := queries.ListStates(ctx, params)
+	if err != nil {
+		return nil, s.base.HandleError(err)
+	}
+
+	return lo.Map(res, func(item statemachine.ListStatesRow, _ int) storage.State {
+		return storage.State{
+			ID:             item.ID,
+			IdempotencyKey: item.IdempotencyKey,
+			CreatedAt:      item.CreatedAt,
+			UpdatedAt:      item.UpdatedAt,
+			Status:         uint8(item.Status),
+			Step:           item.Step,
+			Type:           item.Type,
+			Data:           item.Data,
+			FailData:       item.FailData,
+			MetaData:       item.MetaData,
+			Error:          item.Error,
+			Version:        item.Version,
+			NextRunAt:      toTimePtr(item.NextRunAt),
+			FailedAttempts: item.FailedAttempts,
+			StepAttempts:   item.StepAttempts,
+			PanicAttempts:  item.PanicAttempts,
+			Paused:         item.Paused,
+			FailedStep:     item.FailedStep,
+		}
+	}), nil
+}
@@ -0,0 +1,77 @@
+package postgresql
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/kkiling/statemachine/internal/migrate"
+	"github.com/kkiling/statemachine/migrations"
+)
+
+// migrateLockID ключ advisory блокировки, исключающей одновременное применение миграций несколькими экземплярами сервиса
+const migrateLockID = 7_340_112_905_201_017
+
+// Migrate применяет встроенные миграции, которые еще не применены к базе.
+// Примененные версии хранятся в таблице statemachine_schema_migrations, все миграции применяются в одной транзакции
+func Migrate(ctx context.Context, pool *pgxpool.Pool) error {
+	return MigrateWithConfig(ctx, pool, Config{})
+}
+
+// MigrateWithConfig применяет встроенные миграции к таблицам, расположенным согласно cfg.
+// Схема cfg.Schema создается, если не существует
+func MigrateWithConfig(ctx context.Context, pool *pgxpool.Pool, cfg Config) error {
+	list, err := migrate.Load(migrations.PostgreSQL, "postgresql")
+	if err != nil {
+		return fmt.Errorf("migrate.Load: %w", err)
+	}
+
+	return pgx.BeginFunc(ctx, pool, func(tx pgx.Tx) error {
+		if _, err := tx.Exec(ctx, "SELECT pg_advisory_xact_lock($1)", int64(migrateLockID)); err != nil {
+			return fmt.Errorf("pg_advisory_xact_lock: %w", err)
+		}
+
+		if cfg.Schema != "" {
+			if _, err := tx.Exec(ctx, "CREATE SCHEMA IF NOT EXISTS "+pgx.Identifier{cfg.Schema}.Sanitize()); err != nil {
+				return fmt.Errorf("create schema: %w", err)
+			}
+		}
+
+		_, err := tx.Exec(ctx, cfg.rewrite(`CREATE TABLE IF NOT EXISTS statemachine_schema_migrations (
+    version BIGINT PRIMARY KEY,
+    applied_at TIMESTAMPTZ NOT NULL DEFAULT now()
+)`))
+		if err != nil {
+			return fmt.Errorf("create migrations table: %w", err)
+		}
+
+		rows, err := tx.Query(ctx, cfg.rewrite("SELECT version FROM statemachine_schema_migrations"))
+		if err != nil {
+			return fmt.Errorf("select versions: %w", err)
+		}
+		versions, err := pgx.CollectRows(rows, pgx.RowTo[int64])
+		if err != nil {
+			return fmt.Errorf("pgx.CollectRows: %w", err)
+		}
+		applied := make(map[int64]struct{}, len(versions))
+		for _, version := range versions {
+			applied[version] = struct{}{}
+		}
+
+		for _, m := range list {
+			if _, ok := applied[m.Version]; ok {
+				continue
+			}
+			if _, err := tx.Exec(ctx, cfg.rewrite(m.Up)); err != nil {
+				return fmt.Errorf("apply migration %s: %w", m.Name, err)
+			}
+			if _, err := tx.Exec(ctx, cfg.rewrite("INSERT INTO statemachine_schema_migrations (version) VALUES ($1)"), m.Version); err != nil {
+				return fmt.Errorf("save migration version %s: %w", m.Name, err)
+			}
+		}
+
+		return nil
+	})
+}
@@ -2,12 +2,13 @@ package postgresql
 
 import (
 	"context"
+	"errors"
 
 	"github.com/google/uuid"
 	"github.com/samber/lo"
 
-	"github.com/kkiling/statemachine/internal/storage"
 	"github.com/kkiling/statemachine/internal/storage/statemachine"
+	"github.com/kkiling/statemachine/storage"
 )
 
 func (s *Storage) CreateState(ctx context.Context, state *storage.State) error {
@@ -60,6 +61,13 @@ func (s *Storage) GetStateByIdempotencyKey(ctx context.Context, idempotencyKey s
 		FailData:       res.FailData,
 		MetaData:       res.MetaData,
 		Error:          res.Error,
+		Version:        res.Version,
+		NextRunAt:      toTimePtr(res.NextRunAt),
+		FailedAttempts: res.FailedAttempts,
+		StepAttempts:   res.StepAttempts,
+		PanicAttempts:  res.PanicAttempts,
+		Paused:         res.Paused,
+		FailedStep:     res.FailedStep,
 	}, nil
 }
 
@@ -83,6 +91,13 @@ func (s *Storage) GetStateByID(ctx context.Context, stateID uuid.UUID) (*storage
 		FailData:       res.FailData,
 		MetaData:       res.MetaData,
 		Error:          res.Error,
+		Version:        res.Version,
+		NextRunAt:      toTimePtr(res.NextRunAt),
+		FailedAttempts: res.FailedAttempts,
+		StepAttempts:   res.StepAttempts,
+		PanicAttempts:  res.PanicAttempts,
+		Paused:         res.Paused,
+		FailedStep:     res.FailedStep,
 	}, nil
 }
 
@@ -96,6 +111,9 @@ func (s *Storage) SaveStepExecuteInfo(ctx context.Context, execute storage.StepE
 		Error:              execute.Error,
 		PreviewStep:        execute.PreviewStep,
 		NextStep:           execute.NextStep,
+		Reason:             execute.Reason,
+		DataBefore:         emptyToNil(execute.DataBefore),
+		DataAfter:          emptyToNil(execute.DataAfter),
 	})
 
 	return s.base.HandleError(err)
@@ -116,6 +134,9 @@ func (s *Storage) GetStepExecuteInfos(ctx context.Context, stateID uuid.UUID) ([
 			Error:              item.Error,
 			PreviewStep:        item.PreviewStep,
 			NextStep:           item.NextStep,
+			Reason:             item.Reason,
+			DataBefore:         item.DataBefore,
+			DataAfter:          item.DataAfter,
 		}
 	}), nil
 }
@@ -140,8 +161,143 @@ func (s *Storage) UpdateState(ctx context.Context, stateID uuid.UUID, state stor
 			}
 			return state.MetaData
 		}(),
-		Error: state.Error,
-		ID:    stateID,
+		Error:          state.Error,
+		ID:             stateID,
+		Version:        state.Version,
+		NextRunAt:      toTimestamptz(state.NextRunAt),
+		FailedAttempts: state.FailedAttempts,
+		StepAttempts:   state.StepAttempts,
+		PanicAttempts:  state.PanicAttempts,
+		FailedStep:     state.FailedStep,
+	})
+	if err != nil {
+		err = s.base.HandleError(err)
+		if !errors.Is(err, storage.ErrNotFound) {
+			return err
+		}
+		// Стейт либо не существует, либо его версия изменилась
+		if _, err = queries.GetStateByID(ctx, stateID); err != nil {
+			return s.base.HandleError(err)
+		}
+		return storage.ErrConcurrentModification
+	}
+
+	return nil
+}
+
+func (s *Storage) ClaimState(ctx context.Context, stateID uuid.UUID, lease storage.Lease) (*storage.State, error) {
+	queries := s.getQueries(ctx)
+
+	res, err := queries.ClaimState(ctx, statemachine.ClaimStateParams{
+		LeaseOwner:     lease.Owner,
+		LeaseExpiresAt: toTimestamptz(&lease.ExpiresAt),
+		ID:             stateID,
+		AcquiredAt:     toTimestamptz(&lease.AcquiredAt),
+	})
+	if err != nil {
+		err = s.base.HandleError(err)
+		if !errors.Is(err, storage.ErrNotFound) {
+			return nil, err
+		}
+		// Стейт либо не существует, либо захвачен другим обработчиком
+		if _, err = queries.GetStateByID(ctx, stateID); err != nil {
+			return nil, s.base.HandleError(err)
+		}
+		return nil, storage.ErrLocked
+	}
+
+	return &storage.State{
+		ID:             res.ID,
+		IdempotencyKey: res.IdempotencyKey,
+		CreatedAt:      res.CreatedAt,
+		UpdatedAt:      res.UpdatedAt,
+		Status:         uint8(res.Status),
+		Step:           res.Step,
+		Type:           res.Type,
+		Data:           res.Data,
+		FailData:       res.FailData,
+		MetaData:       res.MetaData,
+		Error:          res.Error,
+		Version:        res.Version,
+		NextRunAt:      toTimePtr(res.NextRunAt),
+		FailedAttempts: res.FailedAttempts,
+		StepAttempts:   res.StepAttempts,
+		PanicAttempts:  res.PanicAttempts,
+		Paused:         res.Paused,
+		FailedStep:     res.FailedStep,
+	}, nil
+}
+
+func (s *Storage) ClaimReadyStates(ctx context.Context, filter storage.ReadyStatesFilter, lease storage.Lease) ([]storage.State, error) {
+	queries := s.getQueries(ctx)
+
+	res, err := queries.ClaimReadyStates(ctx, statemachine.ClaimReadyStatesParams{
+		LeaseOwner:     lease.Owner,
+		LeaseExpiresAt: toTimestamptz(&lease.ExpiresAt),
+		Type:           filter.Type,
+		Statuses: lo.Map(filter.Statuses, func(item uint8, _ int) int {
+			return int(item)
+		}),
+		AcquiredAt: toTimestamptz(&lease.AcquiredAt),
+		LimitCount: int32(filter.Limit),
+	})
+	if err != nil {
+		return nil, s.base.HandleError(err)
+	}
+
+	return lo.Map(res, func(item statemachine.ClaimReadyStatesRow, _ int) storage.State {
+		return storage.State{
+			ID:             item.ID,
+			IdempotencyKey: item.IdempotencyKey,
+			CreatedAt:      item.CreatedAt,
+			UpdatedAt:      item.UpdatedAt,
+			Status:         uint8(item.Status),
+			Step:           item.Step,
+			Type:           item.Type,
+			Data:           item.Data,
+			FailData:       item.FailData,
+			MetaData:       item.MetaData,
+			Error:          item.Error,
+			Version:        item.Version,
+			NextRunAt:      toTimePtr(item.NextRunAt),
+			FailedAttempts: item.FailedAttempts,
+			StepAttempts:   item.StepAttempts,
+			PanicAttempts:  item.PanicAttempts,
+			Paused:         item.Paused,
+			FailedStep:     item.FailedStep,
+		}
+	}), nil
+}
+
+func (s *Storage) RenewLease(ctx context.Context, stateID uuid.UUID, lease storage.Lease) error {
+	queries := s.getQueries(ctx)
+
+	_, err := queries.RenewLease(ctx, statemachine.RenewLeaseParams{
+		LeaseExpiresAt: toTimestamptz(&lease.ExpiresAt),
+		ID:             stateID,
+		LeaseOwner:     lease.Owner,
+	})
+	if err != nil {
+		err = s.base.HandleError(err)
+		if !errors.Is(err, storage.ErrNotFound) {
+			return err
+		}
+		// Стейт либо не существует, либо захвачен другим обработчиком
+		if _, err = queries.GetStateByID(ctx, stateID); err != nil {
+			return s.base.HandleError(err)
+		}
+		return storage.ErrLocked
+	}
+
+	return nil
+}
+
+func (s *Storage) ReleaseState(ctx context.Context, stateID uuid.UUID, owner string) error {
+	queries := s.getQueries(ctx)
+
+	err := queries.ReleaseState(ctx, statemachine.ReleaseStateParams{
+		ID:         stateID,
+		LeaseOwner: owner,
 	})
 
 	return s.base.HandleError(err)
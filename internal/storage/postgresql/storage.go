@@ -2,6 +2,7 @@ package postgresql
 
 import (
 	"context"
+	"sync"
 
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/kkiling/goplatform/storagebase/postgrebase"
@@ -10,17 +11,34 @@ import (
 )
 
 type Storage struct {
-	base *postgrebase.Storage
+	base    *postgrebase.Storage
+	cfg     Config
+	queries *sync.Map
 }
 
 func NewStorage(pool *pgxpool.Pool) *Storage {
+	return NewStorageWithConfig(pool, Config{})
+}
+
+// NewStorageWithConfig хранилище, таблицы которого расположены согласно cfg.
+// Схема должна быть создана через MigrateWithConfig с теми же настройками
+func NewStorageWithConfig(pool *pgxpool.Pool, cfg Config) *Storage {
 	return &Storage{
-		base: postgrebase.NewStorage(pool),
+		base:    postgrebase.NewStorage(pool),
+		cfg:     cfg,
+		queries: &sync.Map{},
 	}
 }
 
 func (s *Storage) getQueries(ctx context.Context) *statemachine.Queries {
-	return statemachine.New(s.base.Next(ctx))
+	if s.cfg.isDefault() {
+		return statemachine.New(s.base.Next(ctx))
+	}
+	return statemachine.New(rewriteDBTX{
+		db:      s.base.Next(ctx),
+		cfg:     s.cfg,
+		queries: s.queries,
+	})
 }
 
 func (s *Storage) RunTransaction(ctx context.Context, txFunc func(ctxTx context.Context) error) error {
@@ -28,7 +46,13 @@ func (s *Storage) RunTransaction(ctx context.Context, txFunc func(ctxTx context.
 }
 
 func NewTestStorage(base *postgrebase.Storage) *Storage {
+	return NewTestStorageWithConfig(base, Config{})
+}
+
+func NewTestStorageWithConfig(base *postgrebase.Storage, cfg Config) *Storage {
 	return &Storage{
-		base: base,
+		base:    base,
+		cfg:     cfg,
+		queries: &sync.Map{},
 	}
 }
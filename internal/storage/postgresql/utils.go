@@ -22,3 +22,11 @@ func toTimestamptz(t *time.Time) pgtype.Timestamptz {
 	}
 	return pgtype.Timestamptz{}
 }
+
+// emptyToNil пустые JSON данные сохраняются как NULL
+func emptyToNil(b []byte) []byte {
+	if len(b) == 0 {
+		return nil
+	}
+	return b
+}
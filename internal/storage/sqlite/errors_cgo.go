@@ -0,0 +1,25 @@
+//go:build cgo
+
+package sqlite
+
+import (
+	"errors"
+
+	"github.com/mattn/go-sqlite3"
+
+	"github.com/kkiling/statemachine/storage"
+)
+
+// mapConstraintError преобразует ошибки нарушения ограничений SQLite в ошибки хранилища
+func mapConstraintError(err error) error {
+	var sqliteErr sqlite3.Error
+	if errors.As(err, &sqliteErr) {
+		switch sqliteErr.ExtendedCode {
+		case sqlite3.ErrConstraintForeignKey:
+			return storage.ErrForeignKeyViolation
+		case sqlite3.ErrConstraintUnique, sqlite3.ErrConstraintPrimaryKey:
+			return storage.ErrAlreadyExists
+		}
+	}
+	return err
+}
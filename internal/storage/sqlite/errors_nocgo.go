@@ -0,0 +1,13 @@
+//go:build !cgo
+
+package sqlite
+
+import (
+	// Без cgo драйвер регистрируется заглушкой, открытие базы возвращает ошибку о необходимости cgo
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// mapConstraintError без cgo запросы к SQLite не выполняются, ошибки возвращаются без изменений
+func mapConstraintError(err error) error {
+	return err
+}
@@ -0,0 +1,118 @@
+package sqlite
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/kkiling/statemachine/internal/storage/sqlite/statemachine"
+	"github.com/kkiling/statemachine/storage"
+)
+
+// listStates запрос собирается вручную: набор условий зависит от фильтра, а sqlc не поддерживает
+// необязательные списки значений для SQLite
+const listStates = `SELECT id, idempotency_key, created_at, updated_at,
+       status, step, type, data, fail_data, meta_data, error, version, next_run_at, failed_attempts, step_attempts, paused, failed_step, panic_attempts
+FROM state`
+
+func (s *Storage) ListStates(ctx context.Context, filter storage.ListStatesFilter) ([]storage.State, error) {
+	var (
+		where []string
+		args  []any
+	)
+	addCondition := func(condition string, values ...any) {
+		where = append(where, condition)
+		args = append(args, values...)
+	}
+
+	if filter.Type != "" {
+		addCondition("type = ?", filter.Type)
+	}
+	if len(filter.Statuses) > 0 {
+		values := make([]any, 0, len(filter.Statuses))
+		for _, status := range filter.Statuses {
+			values = append(values, int64(status))
+		}
+		addCondition("status IN ("+placeholders(len(values))+")", values...)
+	}
+	if len(filter.Steps) > 0 {
+		values := make([]any, 0, len(filter.Steps))
+		for _, step := range filter.Steps {
+			values = append(values, step)
+		}
+		addCondition("step IN ("+placeholders(len(values))+")", values...)
+	}
+	if filter.CreatedFrom != nil {
+		addCondition("created_at >= ?", toUnixNano(*filter.CreatedFrom))
+	}
+	if filter.CreatedTo != nil {
+		addCondition("created_at < ?", toUnixNano(*filter.CreatedTo))
+	}
+	if filter.UpdatedFrom != nil {
+		addCondition("updated_at >= ?", toUnixNano(*filter.UpdatedFrom))
+	}
+	if filter.UpdatedTo != nil {
+		addCondition("updated_at < ?", toUnixNano(*filter.UpdatedTo))
+	}
+	if filter.HasError != nil {
+		if *filter.HasError {
+			addCondition("error IS NOT NULL")
+		} else {
+			addCondition("error IS NULL")
+		}
+	}
+	if filter.After != nil {
+		addCondition("(created_at, id) > (?, ?)", toUnixNano(filter.After.CreatedAt), filter.After.ID)
+	}
+
+	query := listStates
+	if len(where) > 0 {
+		query += "\nWHERE " + strings.Join(where, "\n  AND ")
+	}
+	query += "\nORDER BY created_at, id\nLIMIT ?"
+	args = append(args, filter.Limit)
+
+	rows, err := s.next(ctx).QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, handleError(err)
+	}
+	defer rows.Close()
+
+	var res []storage.State
+	for rows.Next() {
+		var i statemachine.State
+		if err = rows.Scan(
+			&i.ID,
+			&i.IdempotencyKey,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Status,
+			&i.Step,
+			&i.Type,
+			&i.Data,
+			&i.FailData,
+			&i.MetaData,
+			&i.Error,
+			&i.Version,
+			&i.NextRunAt,
+			&i.FailedAttempts,
+			&i.StepAttempts,
+			&i.Paused,
+			&i.FailedStep,
+			&i.PanicAttempts,
+		); err != nil {
+			return nil, fmt.Errorf("rows.Scan: %w", err)
+		}
+		res = append(res, toStorageState(i))
+	}
+	if err = rows.Err(); err != nil {
+		return nil, handleError(err)
+	}
+
+	return res, nil
+}
+
+// placeholders список из n параметров запроса
+func placeholders(n int) string {
+	return strings.TrimSuffix(strings.Repeat("?, ", n), ", ")
+}
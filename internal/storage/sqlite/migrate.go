@@ -0,0 +1,75 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/kkiling/statemachine/internal/migrate"
+	"github.com/kkiling/statemachine/migrations"
+)
+
+// Migrate применяет встроенные миграции, которые еще не применены к базе.
+// Примененные версии хранятся в таблице statemachine_schema_migrations, все миграции применяются в одной транзакции
+func Migrate(ctx context.Context, db *sql.DB) error {
+	list, err := migrate.Load(migrations.SQLite, "sqlite")
+	if err != nil {
+		return fmt.Errorf("migrate.Load: %w", err)
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("db.BeginTx: %w", err)
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	_, err = tx.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS statemachine_schema_migrations (
+    version INTEGER PRIMARY KEY,
+    applied_at INTEGER NOT NULL
+)`)
+	if err != nil {
+		return fmt.Errorf("create migrations table: %w", err)
+	}
+
+	applied := make(map[int64]struct{})
+	rows, err := tx.QueryContext(ctx, "SELECT version FROM statemachine_schema_migrations")
+	if err != nil {
+		return fmt.Errorf("select versions: %w", err)
+	}
+	for rows.Next() {
+		var version int64
+		if err = rows.Scan(&version); err != nil {
+			_ = rows.Close()
+			return fmt.Errorf("rows.Scan: %w", err)
+		}
+		applied[version] = struct{}{}
+	}
+	if err = rows.Close(); err != nil {
+		return fmt.Errorf("rows.Close: %w", err)
+	}
+	if err = rows.Err(); err != nil {
+		return fmt.Errorf("rows.Err: %w", err)
+	}
+
+	for _, m := range list {
+		if _, ok := applied[m.Version]; ok {
+			continue
+		}
+		if _, err = tx.ExecContext(ctx, m.Up); err != nil {
+			return fmt.Errorf("apply migration %s: %w", m.Name, err)
+		}
+		_, err = tx.ExecContext(ctx, "INSERT INTO statemachine_schema_migrations (version, applied_at) VALUES (?, ?)",
+			m.Version, toUnixNano(time.Now()))
+		if err != nil {
+			return fmt.Errorf("save migration version %s: %w", m.Name, err)
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("tx.Commit: %w", err)
+	}
+	return nil
+}
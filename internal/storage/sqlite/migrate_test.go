@@ -0,0 +1,42 @@
+package sqlite_test
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/kkiling/statemachine/internal/migrate"
+	"github.com/kkiling/statemachine/internal/storage/sqlite"
+	"github.com/kkiling/statemachine/migrations"
+)
+
+func TestMigrate(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	db, err := sqlite.NewConn(ctx, filepath.Join(t.TempDir(), "statemachine.db"))
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		require.NoError(t, db.Close())
+	})
+
+	list, err := migrate.Load(migrations.SQLite, "sqlite")
+	require.NoError(t, err)
+
+	// Повторный запуск не применяет миграции заново
+	require.NoError(t, sqlite.Migrate(ctx, db))
+	require.NoError(t, sqlite.Migrate(ctx, db))
+
+	var count int
+	err = db.QueryRowContext(ctx, "SELECT count(*) FROM statemachine_schema_migrations").Scan(&count)
+	require.NoError(t, err)
+	require.Equal(t, len(list), count)
+
+	var version int64
+	err = db.QueryRowContext(ctx, "SELECT max(version) FROM statemachine_schema_migrations").Scan(&version)
+	require.NoError(t, err)
+	require.Equal(t, list[len(list)-1].Version, version)
+}
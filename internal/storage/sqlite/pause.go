@@ -0,0 +1,101 @@
+package sqlite
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/samber/lo"
+
+	"github.com/kkiling/statemachine/internal/storage/sqlite/statemachine"
+	"github.com/kkiling/statemachine/storage"
+)
+
+func (s *Storage) SetStatePaused(ctx context.Context, stateID uuid.UUID, paused bool, version int64) error {
+	queries := s.getQueries(ctx)
+
+	_, err := queries.SetStatePaused(ctx, statemachine.SetStatePausedParams{
+		Paused:  paused,
+		ID:      stateID,
+		Version: version,
+	})
+	if err != nil {
+		err = handleError(err)
+		if !errors.Is(err, storage.ErrNotFound) {
+			return err
+		}
+		// Стейт либо не существует, либо его версия изменилась
+		if _, err = queries.GetStateByID(ctx, stateID); err != nil {
+			return handleError(err)
+		}
+		return storage.ErrConcurrentModification
+	}
+
+	return nil
+}
+
+func (s *Storage) PauseType(ctx context.Context, stateType string, pausedAt time.Time) error {
+	queries := s.getQueries(ctx)
+
+	err := queries.PauseType(ctx, statemachine.PauseTypeParams{
+		Type:     stateType,
+		PausedAt: toUnixNano(pausedAt),
+	})
+
+	return handleError(err)
+}
+
+func (s *Storage) ResumeType(ctx context.Context, stateType string) error {
+	queries := s.getQueries(ctx)
+
+	err := queries.ResumeType(ctx, stateType)
+
+	return handleError(err)
+}
+
+func (s *Storage) IsTypePaused(ctx context.Context, stateType string) (bool, error) {
+	queries := s.getQueries(ctx)
+
+	res, err := queries.IsTypePaused(ctx, stateType)
+	if err != nil {
+		return false, handleError(err)
+	}
+
+	return res != 0, nil
+}
+
+func (s *Storage) SavePauseEvent(ctx context.Context, event storage.PauseEvent) error {
+	queries := s.getQueries(ctx)
+
+	err := queries.SavePauseEvent(ctx, statemachine.SavePauseEventParams{
+		Type:      event.Type,
+		StateID:   event.StateID,
+		Paused:    event.Paused,
+		CreatedAt: toUnixNano(event.CreatedAt),
+		CreatedBy: event.CreatedBy,
+		Reason:    event.Reason,
+	})
+
+	return handleError(err)
+}
+
+func (s *Storage) GetPauseEvents(ctx context.Context, stateType string) ([]storage.PauseEvent, error) {
+	queries := s.getQueries(ctx)
+
+	res, err := queries.GetPauseEvents(ctx, stateType)
+	if err != nil {
+		return nil, handleError(err)
+	}
+
+	return lo.Map(res, func(item statemachine.GetPauseEventsRow, _ int) storage.PauseEvent {
+		return storage.PauseEvent{
+			Type:      item.Type,
+			StateID:   item.StateID,
+			Paused:    item.Paused,
+			CreatedAt: fromUnixNano(item.CreatedAt),
+			CreatedBy: item.CreatedBy,
+			Reason:    item.Reason,
+		}
+	}), nil
+}
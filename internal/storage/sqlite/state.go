@@ -0,0 +1,308 @@
+package sqlite
+
+import (
+	"context"
+	"errors"
+
+	"github.com/google/uuid"
+	"github.com/samber/lo"
+
+	"github.com/kkiling/statemachine/internal/storage/sqlite/statemachine"
+	"github.com/kkiling/statemachine/storage"
+)
+
+func (s *Storage) CreateState(ctx context.Context, state *storage.State) error {
+	queries := s.getQueries(ctx)
+
+	err := queries.CreateState(ctx, statemachine.CreateStateParams{
+		ID:             state.ID,
+		IdempotencyKey: state.IdempotencyKey,
+		CreatedAt:      toUnixNano(state.CreatedAt),
+		UpdatedAt:      toUnixNano(state.UpdatedAt),
+		Status:         int64(state.Status),
+		Step:           state.Step,
+		Type:           state.Type,
+		Data:           emptyToNil(state.Data),
+		FailData:       emptyToNil(state.FailData),
+		MetaData:       emptyToNil(state.MetaData),
+	})
+
+	return handleError(err)
+}
+
+func (s *Storage) GetStateByIdempotencyKey(ctx context.Context, idempotencyKey string) (*storage.State, error) {
+	queries := s.getQueries(ctx)
+
+	res, err := queries.GetStateByIdempotencyKey(ctx, idempotencyKey)
+	if err != nil {
+		return nil, handleError(err)
+	}
+
+	return lo.ToPtr(toStorageState(statemachine.State{
+		ID:             res.ID,
+		IdempotencyKey: res.IdempotencyKey,
+		CreatedAt:      res.CreatedAt,
+		UpdatedAt:      res.UpdatedAt,
+		Status:         res.Status,
+		Step:           res.Step,
+		Type:           res.Type,
+		Error:          res.Error,
+		Data:           res.Data,
+		FailData:       res.FailData,
+		MetaData:       res.MetaData,
+		Version:        res.Version,
+		NextRunAt:      res.NextRunAt,
+		FailedAttempts: res.FailedAttempts,
+		StepAttempts:   res.StepAttempts,
+		PanicAttempts:  res.PanicAttempts,
+		Paused:         res.Paused,
+		FailedStep:     res.FailedStep,
+	})), nil
+}
+
+func (s *Storage) GetStateByID(ctx context.Context, stateID uuid.UUID) (*storage.State, error) {
+	queries := s.getQueries(ctx)
+
+	res, err := queries.GetStateByID(ctx, stateID)
+	if err != nil {
+		return nil, handleError(err)
+	}
+
+	return lo.ToPtr(toStorageState(statemachine.State{
+		ID:             res.ID,
+		IdempotencyKey: res.IdempotencyKey,
+		CreatedAt:      res.CreatedAt,
+		UpdatedAt:      res.UpdatedAt,
+		Status:         res.Status,
+		Step:           res.Step,
+		Type:           res.Type,
+		Error:          res.Error,
+		Data:           res.Data,
+		FailData:       res.FailData,
+		MetaData:       res.MetaData,
+		Version:        res.Version,
+		NextRunAt:      res.NextRunAt,
+		FailedAttempts: res.FailedAttempts,
+		StepAttempts:   res.StepAttempts,
+		PanicAttempts:  res.PanicAttempts,
+		Paused:         res.Paused,
+		FailedStep:     res.FailedStep,
+	})), nil
+}
+
+func (s *Storage) SaveStepExecuteInfo(ctx context.Context, execute storage.StepExecuteInfo) error {
+	queries := s.getQueries(ctx)
+
+	err := queries.SaveStepExecuteInfo(ctx, statemachine.SaveStepExecuteInfoParams{
+		StateID:            execute.StateID,
+		StartExecutedAt:    toUnixNano(execute.StartExecutedAt),
+		CompleteExecutedAt: toUnixNano(execute.CompleteExecutedAt),
+		Error:              execute.Error,
+		PreviewStep:        execute.PreviewStep,
+		NextStep:           execute.NextStep,
+		Reason:             execute.Reason,
+		DataBefore:         emptyToNil(execute.DataBefore),
+		DataAfter:          emptyToNil(execute.DataAfter),
+	})
+
+	return handleError(err)
+}
+
+func (s *Storage) GetStepExecuteInfos(ctx context.Context, stateID uuid.UUID) ([]storage.StepExecuteInfo, error) {
+	queries := s.getQueries(ctx)
+	res, err := queries.GetStepExecuteInfos(ctx, stateID)
+	if err != nil {
+		return nil, handleError(err)
+	}
+
+	return lo.Map(res, func(item statemachine.GetStepExecuteInfosRow, _ int) storage.StepExecuteInfo {
+		return storage.StepExecuteInfo{
+			StateID:            item.StateID,
+			StartExecutedAt:    fromUnixNano(item.StartExecutedAt),
+			CompleteExecutedAt: fromUnixNano(item.CompleteExecutedAt),
+			Error:              item.Error,
+			PreviewStep:        item.PreviewStep,
+			NextStep:           item.NextStep,
+			Reason:             item.Reason,
+			DataBefore:         item.DataBefore,
+			DataAfter:          item.DataAfter,
+		}
+	}), nil
+}
+
+func (s *Storage) UpdateState(ctx context.Context, stateID uuid.UUID, state storage.UpdateState) error {
+	queries := s.getQueries(ctx)
+
+	_, err := queries.UpdateState(ctx, statemachine.UpdateStateParams{
+		UpdatedAt:      toUnixNano(state.UpdatedAt),
+		Status:         int64(state.Status),
+		Step:           state.Step,
+		Data:           emptyToNil(state.Data),
+		FailData:       emptyToNil(state.FailData),
+		MetaData:       emptyToNil(state.MetaData),
+		Error:          state.Error,
+		NextRunAt:      toUnixNanoPtr(state.NextRunAt),
+		FailedAttempts: int64(state.FailedAttempts),
+		StepAttempts:   int64(state.StepAttempts),
+		PanicAttempts:  int64(state.PanicAttempts),
+		FailedStep:     state.FailedStep,
+		ID:             stateID,
+		Version:        state.Version,
+	})
+	if err != nil {
+		err = handleError(err)
+		if !errors.Is(err, storage.ErrNotFound) {
+			return err
+		}
+		// Стейт либо не существует, либо его версия изменилась
+		if _, err = queries.GetStateByID(ctx, stateID); err != nil {
+			return handleError(err)
+		}
+		return storage.ErrConcurrentModification
+	}
+
+	return nil
+}
+
+func (s *Storage) ClaimState(ctx context.Context, stateID uuid.UUID, lease storage.Lease) (*storage.State, error) {
+	queries := s.getQueries(ctx)
+
+	res, err := queries.ClaimState(ctx, statemachine.ClaimStateParams{
+		LeaseOwner:     &lease.Owner,
+		LeaseExpiresAt: toUnixNanoPtr(&lease.ExpiresAt),
+		ID:             stateID,
+		AcquiredAt:     toUnixNanoPtr(&lease.AcquiredAt),
+	})
+	if err != nil {
+		err = handleError(err)
+		if !errors.Is(err, storage.ErrNotFound) {
+			return nil, err
+		}
+		// Стейт либо не существует, либо захвачен другим обработчиком
+		if _, err = queries.GetStateByID(ctx, stateID); err != nil {
+			return nil, handleError(err)
+		}
+		return nil, storage.ErrLocked
+	}
+
+	return lo.ToPtr(toStorageState(statemachine.State{
+		ID:             res.ID,
+		IdempotencyKey: res.IdempotencyKey,
+		CreatedAt:      res.CreatedAt,
+		UpdatedAt:      res.UpdatedAt,
+		Status:         res.Status,
+		Step:           res.Step,
+		Type:           res.Type,
+		Error:          res.Error,
+		Data:           res.Data,
+		FailData:       res.FailData,
+		MetaData:       res.MetaData,
+		Version:        res.Version,
+		NextRunAt:      res.NextRunAt,
+		FailedAttempts: res.FailedAttempts,
+		StepAttempts:   res.StepAttempts,
+		PanicAttempts:  res.PanicAttempts,
+		Paused:         res.Paused,
+		FailedStep:     res.FailedStep,
+	})), nil
+}
+
+func (s *Storage) ClaimReadyStates(ctx context.Context, filter storage.ReadyStatesFilter, lease storage.Lease) ([]storage.State, error) {
+	queries := s.getQueries(ctx)
+
+	res, err := queries.ClaimReadyStates(ctx, statemachine.ClaimReadyStatesParams{
+		LeaseOwner:     &lease.Owner,
+		LeaseExpiresAt: toUnixNanoPtr(&lease.ExpiresAt),
+		Type:           filter.Type,
+		Statuses: lo.Map(filter.Statuses, func(item uint8, _ int) int64 {
+			return int64(item)
+		}),
+		// Параметры запроса без имен (sqlc.slice), время захвата передается для проверки аренды и NextRunAt
+		LeaseExpiresAt_2: toUnixNanoPtr(&lease.AcquiredAt),
+		NextRunAt:        toUnixNanoPtr(&lease.AcquiredAt),
+		Limit:            int64(filter.Limit),
+	})
+	if err != nil {
+		return nil, handleError(err)
+	}
+
+	return lo.Map(res, func(item statemachine.ClaimReadyStatesRow, _ int) storage.State {
+		return toStorageState(statemachine.State{
+			ID:             item.ID,
+			IdempotencyKey: item.IdempotencyKey,
+			CreatedAt:      item.CreatedAt,
+			UpdatedAt:      item.UpdatedAt,
+			Status:         item.Status,
+			Step:           item.Step,
+			Type:           item.Type,
+			Error:          item.Error,
+			Data:           item.Data,
+			FailData:       item.FailData,
+			MetaData:       item.MetaData,
+			Version:        item.Version,
+			NextRunAt:      item.NextRunAt,
+			FailedAttempts: item.FailedAttempts,
+			StepAttempts:   item.StepAttempts,
+			PanicAttempts:  item.PanicAttempts,
+			Paused:         item.Paused,
+			FailedStep:     item.FailedStep,
+		})
+	}), nil
+}
+
+func (s *Storage) RenewLease(ctx context.Context, stateID uuid.UUID, lease storage.Lease) error {
+	queries := s.getQueries(ctx)
+
+	_, err := queries.RenewLease(ctx, statemachine.RenewLeaseParams{
+		LeaseExpiresAt: toUnixNanoPtr(&lease.ExpiresAt),
+		ID:             stateID,
+		LeaseOwner:     &lease.Owner,
+	})
+	if err != nil {
+		err = handleError(err)
+		if !errors.Is(err, storage.ErrNotFound) {
+			return err
+		}
+		// Стейт либо не существует, либо захвачен другим обработчиком
+		if _, err = queries.GetStateByID(ctx, stateID); err != nil {
+			return handleError(err)
+		}
+		return storage.ErrLocked
+	}
+
+	return nil
+}
+
+func (s *Storage) ReleaseState(ctx context.Context, stateID uuid.UUID, owner string) error {
+	queries := s.getQueries(ctx)
+
+	err := queries.ReleaseState(ctx, statemachine.ReleaseStateParams{
+		ID:         stateID,
+		LeaseOwner: &owner,
+	})
+
+	return handleError(err)
+}
+
+func toStorageState(state statemachine.State) storage.State {
+	return storage.State{
+		ID:             state.ID,
+		IdempotencyKey: state.IdempotencyKey,
+		CreatedAt:      fromUnixNano(state.CreatedAt),
+		UpdatedAt:      fromUnixNano(state.UpdatedAt),
+		Status:         uint8(state.Status),
+		Step:           state.Step,
+		Type:           state.Type,
+		Data:           state.Data,
+		FailData:       state.FailData,
+		MetaData:       state.MetaData,
+		Error:          state.Error,
+		Version:        state.Version,
+		NextRunAt:      fromUnixNanoPtr(state.NextRunAt),
+		FailedAttempts: int(state.FailedAttempts),
+		StepAttempts:   int(state.StepAttempts),
+		PanicAttempts:  int(state.PanicAttempts),
+		FailedStep:     state.FailedStep,
+		Paused:         state.Paused,
+	}
+}
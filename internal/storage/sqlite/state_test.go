@@ -0,0 +1,15 @@
+package sqlite_test
+
+import (
+	"testing"
+
+	"github.com/kkiling/statemachine/storagetest"
+)
+
+func TestStorage(t *testing.T) {
+	t.Parallel()
+
+	storagetest.Run(t, func(t *testing.T) storagetest.Storage {
+		return newTestStorage(t)
+	})
+}
@@ -0,0 +1,60 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.30.0
+
+package statemachine
+
+import (
+	"github.com/google/uuid"
+)
+
+type PauseEvent struct {
+	ID        int64
+	Type      string
+	StateID   *uuid.UUID
+	Paused    bool
+	CreatedAt int64
+	CreatedBy string
+	Reason    string
+}
+
+type State struct {
+	ID             uuid.UUID
+	IdempotencyKey string
+	CreatedAt      int64
+	UpdatedAt      int64
+	Status         int64
+	Step           string
+	Type           string
+	Error          *string
+	Data           []byte
+	FailData       []byte
+	MetaData       []byte
+	LeaseOwner     *string
+	LeaseExpiresAt *int64
+	Version        int64
+	NextRunAt      *int64
+	FailedAttempts int64
+	StepAttempts   int64
+	Paused         bool
+	FailedStep     string
+	PanicAttempts  int64
+}
+
+type StateTypePause struct {
+	Type     string
+	PausedAt int64
+}
+
+type StepExecuteInfo struct {
+	ID                 int64
+	StateID            uuid.UUID
+	StartExecutedAt    int64
+	CompleteExecutedAt int64
+	Error              *string
+	PreviewStep        string
+	NextStep           *string
+	Reason             *string
+	DataBefore         []byte
+	DataAfter          []byte
+}
@@ -0,0 +1,652 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.30.0
+// source: query.sqlite.sql
+
+package statemachine
+
+import (
+	"context"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+const claimReadyStates = `-- name: ClaimReadyStates :many
+UPDATE state
+SET lease_owner = ?,
+    lease_expires_at = ?
+WHERE id IN (
+    SELECT s.id
+    FROM state s
+    WHERE s.type = ?
+      AND s.status IN (/*SLICE:statuses*/?)
+      AND (s.lease_expires_at IS NULL OR s.lease_expires_at <= ?)
+      AND (s.next_run_at IS NULL OR s.next_run_at <= ?)
+      AND NOT s.paused
+      AND NOT EXISTS (SELECT 1 FROM state_type_pause p WHERE p.type = s.type)
+    ORDER BY s.updated_at, s.id
+    LIMIT ?
+)
+RETURNING id, idempotency_key, created_at, updated_at,
+          status, step, type, data, fail_data, meta_data, error, version, next_run_at, failed_attempts, step_attempts, paused, failed_step, panic_attempts
+`
+
+type ClaimReadyStatesParams struct {
+	LeaseOwner       *string
+	LeaseExpiresAt   *int64
+	Type             string
+	Statuses         []int64
+	LeaseExpiresAt_2 *int64
+	NextRunAt        *int64
+	Limit            int64
+}
+
+type ClaimReadyStatesRow struct {
+	ID             uuid.UUID
+	IdempotencyKey string
+	CreatedAt      int64
+	UpdatedAt      int64
+	Status         int64
+	Step           string
+	Type           string
+	Data           []byte
+	FailData       []byte
+	MetaData       []byte
+	Error          *string
+	Version        int64
+	NextRunAt      *int64
+	FailedAttempts int64
+	StepAttempts   int64
+	Paused         bool
+	FailedStep     string
+	PanicAttempts  int64
+}
+
+func (q *Queries) ClaimReadyStates(ctx context.Context, arg ClaimReadyStatesParams) ([]ClaimReadyStatesRow, error) {
+	query := claimReadyStates
+	var queryParams []interface{}
+	queryParams = append(queryParams, arg.LeaseOwner)
+	queryParams = append(queryParams, arg.LeaseExpiresAt)
+	queryParams = append(queryParams, arg.Type)
+	if len(arg.Statuses) > 0 {
+		for _, v := range arg.Statuses {
+			queryParams = append(queryParams, v)
+		}
+		query = strings.Replace(query, "/*SLICE:statuses*/?", strings.Repeat(",?", len(arg.Statuses))[1:], 1)
+	} else {
+		query = strings.Replace(query, "/*SLICE:statuses*/?", "NULL", 1)
+	}
+	queryParams = append(queryParams, arg.LeaseExpiresAt_2)
+	queryParams = append(queryParams, arg.NextRunAt)
+	queryParams = append(queryParams, arg.Limit)
+	rows, err := q.db.QueryContext(ctx, query, queryParams...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ClaimReadyStatesRow
+	for rows.Next() {
+		var i ClaimReadyStatesRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.IdempotencyKey,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Status,
+			&i.Step,
+			&i.Type,
+			&i.Data,
+			&i.FailData,
+			&i.MetaData,
+			&i.Error,
+			&i.Version,
+			&i.NextRunAt,
+			&i.FailedAttempts,
+			&i.StepAttempts,
+			&i.Paused,
+			&i.FailedStep,
+			&i.PanicAttempts,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const claimState = `-- name: ClaimState :one
+UPDATE state
+SET lease_owner = ?1,
+    lease_expires_at = ?2
+WHERE id = ?3
+  AND (lease_expires_at IS NULL OR lease_expires_at <= ?4)
+RETURNING id, idempotency_key, created_at, updated_at,
+          status, step, type, data, fail_data, meta_data, error, version, next_run_at, failed_attempts, step_attempts, paused, failed_step, panic_attempts
+`
+
+type ClaimStateParams struct {
+	LeaseOwner     *string
+	LeaseExpiresAt *int64
+	ID             uuid.UUID
+	AcquiredAt     *int64
+}
+
+type ClaimStateRow struct {
+	ID             uuid.UUID
+	IdempotencyKey string
+	CreatedAt      int64
+	UpdatedAt      int64
+	Status         int64
+	Step           string
+	Type           string
+	Data           []byte
+	FailData       []byte
+	MetaData       []byte
+	Error          *string
+	Version        int64
+	NextRunAt      *int64
+	FailedAttempts int64
+	StepAttempts   int64
+	Paused         bool
+	FailedStep     string
+	PanicAttempts  int64
+}
+
+func (q *Queries) ClaimState(ctx context.Context, arg ClaimStateParams) (ClaimStateRow, error) {
+	row := q.db.QueryRowContext(ctx, claimState,
+		arg.LeaseOwner,
+		arg.LeaseExpiresAt,
+		arg.ID,
+		arg.AcquiredAt,
+	)
+	var i ClaimStateRow
+	err := row.Scan(
+		&i.ID,
+		&i.IdempotencyKey,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Status,
+		&i.Step,
+		&i.Type,
+		&i.Data,
+		&i.FailData,
+		&i.MetaData,
+		&i.Error,
+		&i.Version,
+		&i.NextRunAt,
+		&i.FailedAttempts,
+		&i.StepAttempts,
+		&i.Paused,
+		&i.FailedStep,
+		&i.PanicAttempts,
+	)
+	return i, err
+}
+
+const createState = `-- name: CreateState :exec
+INSERT INTO state (id, idempotency_key, created_at, updated_at,
+                   status, step, type, data, fail_data, meta_data)
+VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+`
+
+type CreateStateParams struct {
+	ID             uuid.UUID
+	IdempotencyKey string
+	CreatedAt      int64
+	UpdatedAt      int64
+	Status         int64
+	Step           string
+	Type           string
+	Data           []byte
+	FailData       []byte
+	MetaData       []byte
+}
+
+func (q *Queries) CreateState(ctx context.Context, arg CreateStateParams) error {
+	_, err := q.db.ExecContext(ctx, createState,
+		arg.ID,
+		arg.IdempotencyKey,
+		arg.CreatedAt,
+		arg.UpdatedAt,
+		arg.Status,
+		arg.Step,
+		arg.Type,
+		arg.Data,
+		arg.FailData,
+		arg.MetaData,
+	)
+	return err
+}
+
+const getPauseEvents = `-- name: GetPauseEvents :many
+SELECT type, state_id, paused, created_at, created_by, reason
+FROM pause_event
+WHERE type = ?
+ORDER BY created_at, id
+`
+
+type GetPauseEventsRow struct {
+	Type      string
+	StateID   *uuid.UUID
+	Paused    bool
+	CreatedAt int64
+	CreatedBy string
+	Reason    string
+}
+
+func (q *Queries) GetPauseEvents(ctx context.Context, type_ string) ([]GetPauseEventsRow, error) {
+	rows, err := q.db.QueryContext(ctx, getPauseEvents, type_)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetPauseEventsRow
+	for rows.Next() {
+		var i GetPauseEventsRow
+		if err := rows.Scan(
+			&i.Type,
+			&i.StateID,
+			&i.Paused,
+			&i.CreatedAt,
+			&i.CreatedBy,
+			&i.Reason,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getStateByID = `-- name: GetStateByID :one
+SELECT id, idempotency_key, created_at, updated_at,
+       status, step, type, data, fail_data, meta_data, error, version, next_run_at, failed_attempts, step_attempts, paused, failed_step, panic_attempts
+FROM state
+WHERE id = ?
+LIMIT 1
+`
+
+type GetStateByIDRow struct {
+	ID             uuid.UUID
+	IdempotencyKey string
+	CreatedAt      int64
+	UpdatedAt      int64
+	Status         int64
+	Step           string
+	Type           string
+	Data           []byte
+	FailData       []byte
+	MetaData       []byte
+	Error          *string
+	Version        int64
+	NextRunAt      *int64
+	FailedAttempts int64
+	StepAttempts   int64
+	Paused         bool
+	FailedStep     string
+	PanicAttempts  int64
+}
+
+func (q *Queries) GetStateByID(ctx context.Context, id uuid.UUID) (GetStateByIDRow, error) {
+	row := q.db.QueryRowContext(ctx, getStateByID, id)
+	var i GetStateByIDRow
+	err := row.Scan(
+		&i.ID,
+		&i.IdempotencyKey,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Status,
+		&i.Step,
+		&i.Type,
+		&i.Data,
+		&i.FailData,
+		&i.MetaData,
+		&i.Error,
+		&i.Version,
+		&i.NextRunAt,
+		&i.FailedAttempts,
+		&i.StepAttempts,
+		&i.Paused,
+		&i.FailedStep,
+		&i.PanicAttempts,
+	)
+	return i, err
+}
+
+const getStateByIdempotencyKey = `-- name: GetStateByIdempotencyKey :one
+SELECT id, idempotency_key, created_at, updated_at,
+       status, step, type, data, fail_data, meta_data, error, version, next_run_at, failed_attempts, step_attempts, paused, failed_step, panic_attempts
+FROM state
+WHERE idempotency_key = ?
+LIMIT 1
+`
+
+type GetStateByIdempotencyKeyRow struct {
+	ID             uuid.UUID
+	IdempotencyKey string
+	CreatedAt      int64
+	UpdatedAt      int64
+	Status         int64
+	Step           string
+	Type           string
+	Data           []byte
+	FailData       []byte
+	MetaData       []byte
+	Error          *string
+	Version        int64
+	NextRunAt      *int64
+	FailedAttempts int64
+	StepAttempts   int64
+	Paused         bool
+	FailedStep     string
+	PanicAttempts  int64
+}
+
+func (q *Queries) GetStateByIdempotencyKey(ctx context.Context, idempotencyKey string) (GetStateByIdempotencyKeyRow, error) {
+	row := q.db.QueryRowContext(ctx, getStateByIdempotencyKey, idempotencyKey)
+	var i GetStateByIdempotencyKeyRow
+	err := row.Scan(
+		&i.ID,
+		&i.IdempotencyKey,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Status,
+		&i.Step,
+		&i.Type,
+		&i.Data,
+		&i.FailData,
+		&i.MetaData,
+		&i.Error,
+		&i.Version,
+		&i.NextRunAt,
+		&i.FailedAttempts,
+		&i.StepAttempts,
+		&i.Paused,
+		&i.FailedStep,
+		&i.PanicAttempts,
+	)
+	return i, err
+}
+
+const getStepExecuteInfos = `-- name: GetStepExecuteInfos :many
+SELECT
+    state_id, start_executed_at, complete_executed_at,
+    error, preview_step, next_step, reason, data_before, data_after
+FROM step_execute_info
+WHERE state_id = ?
+ORDER BY start_executed_at, id
+`
+
+type GetStepExecuteInfosRow struct {
+	StateID            uuid.UUID
+	StartExecutedAt    int64
+	CompleteExecutedAt int64
+	Error              *string
+	PreviewStep        string
+	NextStep           *string
+	Reason             *string
+	DataBefore         []byte
+	DataAfter          []byte
+}
+
+func (q *Queries) GetStepExecuteInfos(ctx context.Context, stateID uuid.UUID) ([]GetStepExecuteInfosRow, error) {
+	rows, err := q.db.QueryContext(ctx, getStepExecuteInfos, stateID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetStepExecuteInfosRow
+	for rows.Next() {
+		var i GetStepExecuteInfosRow
+		if err := rows.Scan(
+			&i.StateID,
+			&i.StartExecutedAt,
+			&i.CompleteExecutedAt,
+			&i.Error,
+			&i.PreviewStep,
+			&i.NextStep,
+			&i.Reason,
+			&i.DataBefore,
+			&i.DataAfter,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const isTypePaused = `-- name: IsTypePaused :one
+SELECT EXISTS (SELECT 1 FROM state_type_pause WHERE type = ?)
+`
+
+func (q *Queries) IsTypePaused(ctx context.Context, type_ string) (int64, error) {
+	row := q.db.QueryRowContext(ctx, isTypePaused, type_)
+	var column_1 int64
+	err := row.Scan(&column_1)
+	return column_1, err
+}
+
+const pauseType = `-- name: PauseType :exec
+INSERT INTO state_type_pause (type, paused_at)
+VALUES (?, ?)
+ON CONFLICT (type) DO NOTHING
+`
+
+type PauseTypeParams struct {
+	Type     string
+	PausedAt int64
+}
+
+func (q *Queries) PauseType(ctx context.Context, arg PauseTypeParams) error {
+	_, err := q.db.ExecContext(ctx, pauseType, arg.Type, arg.PausedAt)
+	return err
+}
+
+const releaseState = `-- name: ReleaseState :exec
+UPDATE state
+SET lease_owner = NULL,
+    lease_expires_at = NULL
+WHERE id = ?1
+  AND lease_owner = ?2
+`
+
+type ReleaseStateParams struct {
+	ID         uuid.UUID
+	LeaseOwner *string
+}
+
+func (q *Queries) ReleaseState(ctx context.Context, arg ReleaseStateParams) error {
+	_, err := q.db.ExecContext(ctx, releaseState, arg.ID, arg.LeaseOwner)
+	return err
+}
+
+const renewLease = `-- name: RenewLease :one
+UPDATE state
+SET lease_expires_at = ?1
+WHERE id = ?2
+  AND lease_owner = ?3
+RETURNING id
+`
+
+type RenewLeaseParams struct {
+	LeaseExpiresAt *int64
+	ID             uuid.UUID
+	LeaseOwner     *string
+}
+
+func (q *Queries) RenewLease(ctx context.Context, arg RenewLeaseParams) (uuid.UUID, error) {
+	row := q.db.QueryRowContext(ctx, renewLease, arg.LeaseExpiresAt, arg.ID, arg.LeaseOwner)
+	var id uuid.UUID
+	err := row.Scan(&id)
+	return id, err
+}
+
+const resumeType = `-- name: ResumeType :exec
+DELETE FROM state_type_pause
+WHERE type = ?
+`
+
+func (q *Queries) ResumeType(ctx context.Context, type_ string) error {
+	_, err := q.db.ExecContext(ctx, resumeType, type_)
+	return err
+}
+
+const savePauseEvent = `-- name: SavePauseEvent :exec
+INSERT INTO pause_event (type, state_id, paused, created_at, created_by, reason)
+VALUES (?, ?, ?, ?, ?, ?)
+`
+
+type SavePauseEventParams struct {
+	Type      string
+	StateID   *uuid.UUID
+	Paused    bool
+	CreatedAt int64
+	CreatedBy string
+	Reason    string
+}
+
+func (q *Queries) SavePauseEvent(ctx context.Context, arg SavePauseEventParams) error {
+	_, err := q.db.ExecContext(ctx, savePauseEvent,
+		arg.Type,
+		arg.StateID,
+		arg.Paused,
+		arg.CreatedAt,
+		arg.CreatedBy,
+		arg.Reason,
+	)
+	return err
+}
+
+const saveStepExecuteInfo = `-- name: SaveStepExecuteInfo :exec
+INSERT INTO step_execute_info (
+    state_id, start_executed_at, complete_executed_at,
+    error, preview_step, next_step, reason, data_before, data_after
+) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+`
+
+type SaveStepExecuteInfoParams struct {
+	StateID            uuid.UUID
+	StartExecutedAt    int64
+	CompleteExecutedAt int64
+	Error              *string
+	PreviewStep        string
+	NextStep           *string
+	Reason             *string
+	DataBefore         []byte
+	DataAfter          []byte
+}
+
+func (q *Queries) SaveStepExecuteInfo(ctx context.Context, arg SaveStepExecuteInfoParams) error {
+	_, err := q.db.ExecContext(ctx, saveStepExecuteInfo,
+		arg.StateID,
+		arg.StartExecutedAt,
+		arg.CompleteExecutedAt,
+		arg.Error,
+		arg.PreviewStep,
+		arg.NextStep,
+		arg.Reason,
+		arg.DataBefore,
+		arg.DataAfter,
+	)
+	return err
+}
+
+const setStatePaused = `-- name: SetStatePaused :one
+UPDATE state
+SET paused = ?1
+WHERE id = ?2 AND version = ?3
+RETURNING id
+`
+
+type SetStatePausedParams struct {
+	Paused  bool
+	ID      uuid.UUID
+	Version int64
+}
+
+func (q *Queries) SetStatePaused(ctx context.Context, arg SetStatePausedParams) (uuid.UUID, error) {
+	row := q.db.QueryRowContext(ctx, setStatePaused, arg.Paused, arg.ID, arg.Version)
+	var id uuid.UUID
+	err := row.Scan(&id)
+	return id, err
+}
+
+const updateState = `-- name: UpdateState :one
+UPDATE state
+SET
+    updated_at = ?1,
+    status = ?2,
+    step = ?3,
+    data = ?4,
+    fail_data = ?5,
+    meta_data = ?6,
+    error = ?7,
+    next_run_at = ?8,
+    failed_attempts = ?9,
+    step_attempts = ?10,
+    failed_step = ?11,
+    panic_attempts = ?12,
+    version = version + 1
+WHERE id = ?13
+  AND version = ?14
+RETURNING id
+`
+
+type UpdateStateParams struct {
+	UpdatedAt      int64
+	Status         int64
+	Step           string
+	Data           []byte
+	FailData       []byte
+	MetaData       []byte
+	Error          *string
+	NextRunAt      *int64
+	FailedAttempts int64
+	StepAttempts   int64
+	FailedStep     string
+	PanicAttempts  int64
+	ID             uuid.UUID
+	Version        int64
+}
+
+func (q *Queries) UpdateState(ctx context.Context, arg UpdateStateParams) (uuid.UUID, error) {
+	row := q.db.QueryRowContext(ctx, updateState,
+		arg.UpdatedAt,
+		arg.Status,
+		arg.Step,
+		arg.Data,
+		arg.FailData,
+		arg.MetaData,
+		arg.Error,
+		arg.NextRunAt,
+		arg.FailedAttempts,
+		arg.StepAttempts,
+		arg.FailedStep,
+		arg.PanicAttempts,
+		arg.ID,
+		arg.Version,
+	)
+	var id uuid.UUID
+	err := row.Scan(&id)
+	return id, err
+}
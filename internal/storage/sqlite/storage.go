@@ -0,0 +1,87 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/kkiling/statemachine/internal/storage/sqlite/statemachine"
+	"github.com/kkiling/statemachine/storage"
+)
+
+type txKey struct{}
+
+type Storage struct {
+	db *sql.DB
+}
+
+func NewStorage(db *sql.DB) *Storage {
+	return &Storage{
+		db: db,
+	}
+}
+
+// NewConn открывает базу SQLite по пути dsn с настройками, необходимыми хранилищу
+func NewConn(ctx context.Context, dsn string) (*sql.DB, error) {
+	// Транзакции сразу захватывают блокировку на запись, что бы избежать взаимоблокировок при повышении уровня блокировки
+	db, err := sql.Open("sqlite3", fmt.Sprintf("file:%s?_busy_timeout=5000&_foreign_keys=on&_journal_mode=WAL&_txlock=immediate", dsn))
+	if err != nil {
+		return nil, fmt.Errorf("sql.Open: %w", err)
+	}
+	// SQLite допускает только одну пишущую транзакцию
+	db.SetMaxOpenConns(1)
+
+	if err = db.PingContext(ctx); err != nil {
+		_ = db.Close()
+		return nil, fmt.Errorf("db.PingContext: %w", err)
+	}
+
+	return db, nil
+}
+
+func (s *Storage) next(ctx context.Context) statemachine.DBTX {
+	if tx, ok := ctx.Value(txKey{}).(*sql.Tx); ok {
+		return tx
+	}
+	return s.db
+}
+
+func (s *Storage) getQueries(ctx context.Context) *statemachine.Queries {
+	return statemachine.New(s.next(ctx))
+}
+
+func (s *Storage) RunTransaction(ctx context.Context, txFunc func(ctxTx context.Context) error) error {
+	if _, ok := ctx.Value(txKey{}).(*sql.Tx); ok {
+		return txFunc(ctx)
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("db.BeginTx: %w", err)
+	}
+
+	if err = txFunc(context.WithValue(ctx, txKey{}, tx)); err != nil {
+		if rollBackErr := tx.Rollback(); rollBackErr != nil {
+			return fmt.Errorf("tx.Rollback: %w", rollBackErr)
+		}
+		return handleError(err)
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("tx.Commit: %w", handleError(err))
+	}
+
+	return nil
+}
+
+func handleError(err error) error {
+	if err == nil {
+		return nil
+	}
+	if errors.Is(err, sql.ErrNoRows) {
+		return storage.ErrNotFound
+	}
+
+	return mapConstraintError(err)
+}
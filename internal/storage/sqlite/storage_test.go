@@ -0,0 +1,26 @@
+package sqlite_test
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/kkiling/statemachine/internal/storage/sqlite"
+)
+
+// newTestStorage хранилище на временной базе с примененными миграциями
+func newTestStorage(t *testing.T) *sqlite.Storage {
+	ctx := context.Background()
+
+	db, err := sqlite.NewConn(ctx, filepath.Join(t.TempDir(), "statemachine.db"))
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		require.NoError(t, db.Close())
+	})
+
+	require.NoError(t, sqlite.Migrate(ctx, db))
+
+	return sqlite.NewStorage(db)
+}
@@ -0,0 +1,39 @@
+package sqlite
+
+import (
+	"time"
+
+	"github.com/samber/lo"
+)
+
+// Время хранится в наносекундах unix time
+
+func toUnixNano(t time.Time) int64 {
+	return t.UnixNano()
+}
+
+func toUnixNanoPtr(t *time.Time) *int64 {
+	if t != nil {
+		return lo.ToPtr(t.UnixNano())
+	}
+	return nil
+}
+
+func fromUnixNano(t int64) time.Time {
+	return time.Unix(0, t)
+}
+
+func fromUnixNanoPtr(t *int64) *time.Time {
+	if t != nil {
+		return lo.ToPtr(time.Unix(0, *t))
+	}
+	return nil
+}
+
+// emptyToNil пустые данные сохраняются как NULL
+func emptyToNil(b []byte) []byte {
+	if len(b) == 0 {
+		return nil
+	}
+	return b
+}
@@ -18,6 +18,16 @@ type GooseDbVersion struct {
 	Tstamp    pgtype.Timestamp
 }
 
+type PauseEvent struct {
+	ID        int
+	Type      string
+	StateID   *uuid.UUID
+	Paused    bool
+	CreatedAt time.Time
+	CreatedBy string
+	Reason    string
+}
+
 type State struct {
 	ID             uuid.UUID
 	IdempotencyKey string
@@ -30,6 +40,20 @@ type State struct {
 	Data           []byte
 	FailData       []byte
 	MetaData       []byte
+	LeaseOwner     *string
+	LeaseExpiresAt pgtype.Timestamptz
+	Version        int64
+	NextRunAt      pgtype.Timestamptz
+	FailedAttempts int
+	StepAttempts   int
+	Paused         bool
+	FailedStep     string
+	PanicAttempts  int
+}
+
+type StateTypePause struct {
+	Type     string
+	PausedAt time.Time
 }
 
 type StepExecuteInfo struct {
@@ -40,4 +64,7 @@ type StepExecuteInfo struct {
 	Error              *string
 	PreviewStep        string
 	NextStep           *string
+	Reason             *string
+	DataBefore         []byte
+	DataAfter          []byte
 }
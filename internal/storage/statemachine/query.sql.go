@@ -10,8 +10,175 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
 )
 
+const claimReadyStates = `-- name: ClaimReadyStates :many
+UPDATE state
+SET lease_owner = $1::text,
+    lease_expires_at = $2::timestamptz
+WHERE id IN (
+    SELECT s.id
+    FROM state s
+    WHERE s.type = $3
+      AND s.status = ANY($4::int[])
+      AND (s.lease_expires_at IS NULL OR s.lease_expires_at <= $5::timestamptz)
+      AND (s.next_run_at IS NULL OR s.next_run_at <= $5::timestamptz)
+      AND NOT s.paused
+      AND NOT EXISTS (SELECT 1 FROM state_type_pause p WHERE p.type = s.type)
+    ORDER BY s.updated_at, s.id
+    LIMIT $6
+    FOR UPDATE SKIP LOCKED
+)
+RETURNING id, idempotency_key, created_at, updated_at,
+          status, step, type, data, fail_data, meta_data, error, version, next_run_at, failed_attempts, step_attempts, paused, failed_step, panic_attempts
+`
+
+type ClaimReadyStatesParams struct {
+	LeaseOwner     string
+	LeaseExpiresAt pgtype.Timestamptz
+	Type           string
+	Statuses       []int
+	AcquiredAt     pgtype.Timestamptz
+	LimitCount     int32
+}
+
+type ClaimReadyStatesRow struct {
+	ID             uuid.UUID
+	IdempotencyKey string
+	CreatedAt      time.Time
+	UpdatedAt      time.Time
+	Status         int
+	Step           string
+	Type           string
+	Data           []byte
+	FailData       []byte
+	MetaData       []byte
+	Error          *string
+	Version        int64
+	NextRunAt      pgtype.Timestamptz
+	FailedAttempts int
+	StepAttempts   int
+	Paused         bool
+	FailedStep     string
+	PanicAttempts  int
+}
+
+func (q *Queries) ClaimReadyStates(ctx context.Context, arg ClaimReadyStatesParams) ([]ClaimReadyStatesRow, error) {
+	rows, err := q.db.Query(ctx, claimReadyStates,
+		arg.LeaseOwner,
+		arg.LeaseExpiresAt,
+		arg.Type,
+		arg.Statuses,
+		arg.AcquiredAt,
+		arg.LimitCount,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ClaimReadyStatesRow
+	for rows.Next() {
+		var i ClaimReadyStatesRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.IdempotencyKey,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Status,
+			&i.Step,
+			&i.Type,
+			&i.Data,
+			&i.FailData,
+			&i.MetaData,
+			&i.Error,
+			&i.Version,
+			&i.NextRunAt,
+			&i.FailedAttempts,
+			&i.StepAttempts,
+			&i.Paused,
+			&i.FailedStep,
+			&i.PanicAttempts,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const claimState = `-- name: ClaimState :one
+UPDATE state
+SET lease_owner = $1::text,
+    lease_expires_at = $2::timestamptz
+WHERE id = $3
+  AND (lease_expires_at IS NULL OR lease_expires_at <= $4::timestamptz)
+RETURNING id, idempotency_key, created_at, updated_at,
+          status, step, type, data, fail_data, meta_data, error, version, next_run_at, failed_attempts, step_attempts, paused, failed_step, panic_attempts
+`
+
+type ClaimStateParams struct {
+	LeaseOwner     string
+	LeaseExpiresAt pgtype.Timestamptz
+	ID             uuid.UUID
+	AcquiredAt     pgtype.Timestamptz
+}
+
+type ClaimStateRow struct {
+	ID             uuid.UUID
+	IdempotencyKey string
+	CreatedAt      time.Time
+	UpdatedAt      time.Time
+	Status         int
+	Step           string
+	Type           string
+	Data           []byte
+	FailData       []byte
+	MetaData       []byte
+	Error          *string
+	Version        int64
+	NextRunAt      pgtype.Timestamptz
+	FailedAttempts int
+	StepAttempts   int
+	Paused         bool
+	FailedStep     string
+	PanicAttempts  int
+}
+
+func (q *Queries) ClaimState(ctx context.Context, arg ClaimStateParams) (ClaimStateRow, error) {
+	row := q.db.QueryRow(ctx, claimState,
+		arg.LeaseOwner,
+		arg.LeaseExpiresAt,
+		arg.ID,
+		arg.AcquiredAt,
+	)
+	var i ClaimStateRow
+	err := row.Scan(
+		&i.ID,
+		&i.IdempotencyKey,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Status,
+		&i.Step,
+		&i.Type,
+		&i.Data,
+		&i.FailData,
+		&i.MetaData,
+		&i.Error,
+		&i.Version,
+		&i.NextRunAt,
+		&i.FailedAttempts,
+		&i.StepAttempts,
+		&i.Paused,
+		&i.FailedStep,
+		&i.PanicAttempts,
+	)
+	return i, err
+}
+
 const createState = `-- name: CreateState :exec
 INSERT INTO state (id, idempotency_key, created_at, updated_at,
                    status, step, type, data, fail_data, meta_data)
@@ -48,9 +215,52 @@ func (q *Queries) CreateState(ctx context.Context, arg CreateStateParams) error
 	return err
 }
 
+const getPauseEvents = `-- name: GetPauseEvents :many
+SELECT type, state_id, paused, created_at, created_by, reason
+FROM pause_event
+WHERE type = $1
+ORDER BY created_at, id
+`
+
+type GetPauseEventsRow struct {
+	Type      string
+	StateID   *uuid.UUID
+	Paused    bool
+	CreatedAt time.Time
+	CreatedBy string
+	Reason    string
+}
+
+func (q *Queries) GetPauseEvents(ctx context.Context, type_ string) ([]GetPauseEventsRow, error) {
+	rows, err := q.db.Query(ctx, getPauseEvents, type_)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetPauseEventsRow
+	for rows.Next() {
+		var i GetPauseEventsRow
+		if err := rows.Scan(
+			&i.Type,
+			&i.StateID,
+			&i.Paused,
+			&i.CreatedAt,
+			&i.CreatedBy,
+			&i.Reason,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const getStateByID = `-- name: GetStateByID :one
 SELECT id, idempotency_key, created_at, updated_at,
-       status, step, type, data, fail_data, meta_data, error
+       status, step, type, data, fail_data, meta_data, error, version, next_run_at, failed_attempts, step_attempts, paused, failed_step, panic_attempts
 FROM state
 WHERE id = $1
 LIMIT 1
@@ -68,6 +278,13 @@ type GetStateByIDRow struct {
 	FailData       []byte
 	MetaData       []byte
 	Error          *string
+	Version        int64
+	NextRunAt      pgtype.Timestamptz
+	FailedAttempts int
+	StepAttempts   int
+	Paused         bool
+	FailedStep     string
+	PanicAttempts  int
 }
 
 func (q *Queries) GetStateByID(ctx context.Context, id uuid.UUID) (GetStateByIDRow, error) {
@@ -85,13 +302,20 @@ func (q *Queries) GetStateByID(ctx context.Context, id uuid.UUID) (GetStateByIDR
 		&i.FailData,
 		&i.MetaData,
 		&i.Error,
+		&i.Version,
+		&i.NextRunAt,
+		&i.FailedAttempts,
+		&i.StepAttempts,
+		&i.Paused,
+		&i.FailedStep,
+		&i.PanicAttempts,
 	)
 	return i, err
 }
 
 const getStateByIdempotencyKey = `-- name: GetStateByIdempotencyKey :one
 SELECT id, idempotency_key, created_at, updated_at,
-       status, step, type, data, fail_data, meta_data, error
+       status, step, type, data, fail_data, meta_data, error, version, next_run_at, failed_attempts, step_attempts, paused, failed_step, panic_attempts
 FROM state
 WHERE idempotency_key = $1
 LIMIT 1
@@ -109,6 +333,13 @@ type GetStateByIdempotencyKeyRow struct {
 	FailData       []byte
 	MetaData       []byte
 	Error          *string
+	Version        int64
+	NextRunAt      pgtype.Timestamptz
+	FailedAttempts int
+	StepAttempts   int
+	Paused         bool
+	FailedStep     string
+	PanicAttempts  int
 }
 
 func (q *Queries) GetStateByIdempotencyKey(ctx context.Context, idempotencyKey string) (GetStateByIdempotencyKeyRow, error) {
@@ -126,6 +357,13 @@ func (q *Queries) GetStateByIdempotencyKey(ctx context.Context, idempotencyKey s
 		&i.FailData,
 		&i.MetaData,
 		&i.Error,
+		&i.Version,
+		&i.NextRunAt,
+		&i.FailedAttempts,
+		&i.StepAttempts,
+		&i.Paused,
+		&i.FailedStep,
+		&i.PanicAttempts,
 	)
 	return i, err
 }
@@ -133,10 +371,10 @@ func (q *Queries) GetStateByIdempotencyKey(ctx context.Context, idempotencyKey s
 const getStepExecuteInfos = `-- name: GetStepExecuteInfos :many
 SELECT
     state_id, start_executed_at, complete_executed_at,
-    error, preview_step, next_step
+    error, preview_step, next_step, reason, data_before, data_after
 FROM step_execute_info
 WHERE state_id = $1
-ORDER BY start_executed_at
+ORDER BY start_executed_at, id
 `
 
 type GetStepExecuteInfosRow struct {
@@ -146,6 +384,9 @@ type GetStepExecuteInfosRow struct {
 	Error              *string
 	PreviewStep        string
 	NextStep           *string
+	Reason             *string
+	DataBefore         []byte
+	DataAfter          []byte
 }
 
 func (q *Queries) GetStepExecuteInfos(ctx context.Context, stateID uuid.UUID) ([]GetStepExecuteInfosRow, error) {
@@ -164,6 +405,124 @@ func (q *Queries) GetStepExecuteInfos(ctx context.Context, stateID uuid.UUID) ([
 			&i.Error,
 			&i.PreviewStep,
 			&i.NextStep,
+			&i.Reason,
+			&i.DataBefore,
+			&i.DataAfter,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const isTypePaused = `-- name: IsTypePaused :one
+SELECT EXISTS (SELECT 1 FROM state_type_pause WHERE type = $1)
+`
+
+func (q *Queries) IsTypePaused(ctx context.Context, type_ string) (bool, error) {
+	row := q.db.QueryRow(ctx, isTypePaused, type_)
+	var exists bool
+	err := row.Scan(&exists)
+	return exists, err
+}
+
+const listStates = `-- name: ListStates :many
+SELECT id, idempotency_key, created_at, updated_at,
+       status, step, type, data, fail_data, meta_data, error, version, next_run_at, failed_attempts, step_attempts, paused, failed_step, panic_attempts
+FROM state
+WHERE ($1::text = '' OR type = $1)
+  AND (cardinality($2::int[]) = 0 OR status = ANY($2::int[]))
+  AND (cardinality($3::text[]) = 0 OR step = ANY($3::text[]))
+  AND ($4::timestamptz IS NULL OR created_at >= $4::timestamptz)
+  AND ($5::timestamptz IS NULL OR created_at < $5::timestamptz)
+  AND ($6::timestamptz IS NULL OR updated_at >= $6::timestamptz)
+  AND ($7::timestamptz IS NULL OR updated_at < $7::timestamptz)
+  AND ($8::boolean IS NULL OR (error IS NOT NULL) = $8::boolean)
+  AND ($9::timestamptz IS NULL
+    OR (created_at, id) > ($9::timestamptz, $10::uuid))
+ORDER BY created_at, id
+LIMIT $11
+`
+
+type ListStatesParams struct {
+	Type           string
+	Statuses       []int
+	Steps          []string
+	CreatedFrom    pgtype.Timestamptz
+	CreatedTo      pgtype.Timestamptz
+	UpdatedFrom    pgtype.Timestamptz
+	UpdatedTo      pgtype.Timestamptz
+	HasError       *bool
+	AfterCreatedAt pgtype.Timestamptz
+	AfterID        uuid.UUID
+	LimitCount     int32
+}
+
+type ListStatesRow struct {
+	ID             uuid.UUID
+	IdempotencyKey string
+	CreatedAt      time.Time
+	UpdatedAt      time.Time
+	Status         int
+	Step           string
+	Type           string
+	Data           []byte
+	FailData       []byte
+	MetaData       []byte
+	Error          *string
+	Version        int64
+	NextRunAt      pgtype.Timestamptz
+	FailedAttempts int
+	StepAttempts   int
+	Paused         bool
+	FailedStep     string
+	PanicAttempts  int
+}
+
+func (q *Queries) ListStates(ctx context.Context, arg ListStatesParams) ([]ListStatesRow, error) {
+	rows, err := q.db.Query(ctx, listStates,
+		arg.Type,
+		arg.Statuses,
+		arg.Steps,
+		arg.CreatedFrom,
+		arg.CreatedTo,
+		arg.UpdatedFrom,
+		arg.UpdatedTo,
+		arg.HasError,
+		arg.AfterCreatedAt,
+		arg.AfterID,
+		arg.LimitCount,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListStatesRow
+	for rows.Next() {
+		var i ListStatesRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.IdempotencyKey,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Status,
+			&i.Step,
+			&i.Type,
+			&i.Data,
+			&i.FailData,
+			&i.MetaData,
+			&i.Error,
+			&i.Version,
+			&i.NextRunAt,
+			&i.FailedAttempts,
+			&i.StepAttempts,
+			&i.Paused,
+			&i.FailedStep,
+			&i.PanicAttempts,
 		); err != nil {
 			return nil, err
 		}
@@ -175,12 +534,103 @@ func (q *Queries) GetStepExecuteInfos(ctx context.Context, stateID uuid.UUID) ([
 	return items, nil
 }
 
+const pauseType = `-- name: PauseType :exec
+INSERT INTO state_type_pause (type, paused_at)
+VALUES ($1, $2)
+ON CONFLICT (type) DO NOTHING
+`
+
+type PauseTypeParams struct {
+	Type     string
+	PausedAt time.Time
+}
+
+func (q *Queries) PauseType(ctx context.Context, arg PauseTypeParams) error {
+	_, err := q.db.Exec(ctx, pauseType, arg.Type, arg.PausedAt)
+	return err
+}
+
+const releaseState = `-- name: ReleaseState :exec
+UPDATE state
+SET lease_owner = NULL,
+    lease_expires_at = NULL
+WHERE id = $1
+  AND lease_owner = $2::text
+`
+
+type ReleaseStateParams struct {
+	ID         uuid.UUID
+	LeaseOwner string
+}
+
+func (q *Queries) ReleaseState(ctx context.Context, arg ReleaseStateParams) error {
+	_, err := q.db.Exec(ctx, releaseState, arg.ID, arg.LeaseOwner)
+	return err
+}
+
+const renewLease = `-- name: RenewLease :one
+UPDATE state
+SET lease_expires_at = $1::timestamptz
+WHERE id = $2
+  AND lease_owner = $3::text
+RETURNING id
+`
+
+type RenewLeaseParams struct {
+	LeaseExpiresAt pgtype.Timestamptz
+	ID             uuid.UUID
+	LeaseOwner     string
+}
+
+func (q *Queries) RenewLease(ctx context.Context, arg RenewLeaseParams) (uuid.UUID, error) {
+	row := q.db.QueryRow(ctx, renewLease, arg.LeaseExpiresAt, arg.ID, arg.LeaseOwner)
+	var id uuid.UUID
+	err := row.Scan(&id)
+	return id, err
+}
+
+const resumeType = `-- name: ResumeType :exec
+DELETE FROM state_type_pause
+WHERE type = $1
+`
+
+func (q *Queries) ResumeType(ctx context.Context, type_ string) error {
+	_, err := q.db.Exec(ctx, resumeType, type_)
+	return err
+}
+
+const savePauseEvent = `-- name: SavePauseEvent :exec
+INSERT INTO pause_event (type, state_id, paused, created_at, created_by, reason)
+VALUES ($1, $2, $3, $4, $5, $6)
+`
+
+type SavePauseEventParams struct {
+	Type      string
+	StateID   *uuid.UUID
+	Paused    bool
+	CreatedAt time.Time
+	CreatedBy string
+	Reason    string
+}
+
+func (q *Queries) SavePauseEvent(ctx context.Context, arg SavePauseEventParams) error {
+	_, err := q.db.Exec(ctx, savePauseEvent,
+		arg.Type,
+		arg.StateID,
+		arg.Paused,
+		arg.CreatedAt,
+		arg.CreatedBy,
+		arg.Reason,
+	)
+	return err
+}
+
 const saveStepExecuteInfo = `-- name: SaveStepExecuteInfo :exec
 
 INSERT INTO step_execute_info (
     state_id, start_executed_at, complete_executed_at,
-    error, preview_step, next_step
-) VALUES ($1, $2, $3, $4, $5, $6)
+    error, preview_step, next_step, reason, data_before, data_after
+) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
 `
 
 type SaveStepExecuteInfoParams struct {
@@ -190,6 +640,9 @@ type SaveStepExecuteInfoParams struct {
 	Error              *string
 	PreviewStep        string
 	NextStep           *string
+	Reason             *string
+	DataBefore         []byte
+	DataAfter          []byte
 }
 
 // ----------------------------------------------------------------------------------------------------------------------
@@ -201,10 +654,33 @@ func (q *Queries) SaveStepExecuteInfo(ctx context.Context, arg SaveStepExecuteIn
 		arg.Error,
 		arg.PreviewStep,
 		arg.NextStep,
+		arg.Reason,
+		arg.DataBefore,
+		arg.DataAfter,
 	)
 	return err
 }
 
+const setStatePaused = `-- name: SetStatePaused :one
+UPDATE state
+SET paused = $1
+WHERE id = $2 AND version = $3
+RETURNING id
+`
+
+type SetStatePausedParams struct {
+	Paused  bool
+	ID      uuid.UUID
+	Version int64
+}
+
+func (q *Queries) SetStatePaused(ctx context.Context, arg SetStatePausedParams) (uuid.UUID, error) {
+	row := q.db.QueryRow(ctx, setStatePaused, arg.Paused, arg.ID, arg.Version)
+	var id uuid.UUID
+	err := row.Scan(&id)
+	return id, err
+}
+
 const updateState = `-- name: UpdateState :one
 UPDATE state
 SET
@@ -214,20 +690,33 @@ SET
     data = $4,
     fail_data = $5,
     meta_data = $6,
-    error = $7
+    error = $7,
+    next_run_at = $10,
+    failed_attempts = $11,
+    step_attempts = $12,
+    failed_step = $13,
+    panic_attempts = $14,
+    version = version + 1
 WHERE id = $8
+  AND version = $9
 RETURNING id
 `
 
 type UpdateStateParams struct {
-	UpdatedAt time.Time
-	Status    int
-	Step      string
-	Data      []byte
-	FailData  []byte
-	MetaData  []byte
-	Error     *string
-	ID        uuid.UUID
+	UpdatedAt      time.Time
+	Status         int
+	Step           string
+	Data           []byte
+	FailData       []byte
+	MetaData       []byte
+	Error          *string
+	ID             uuid.UUID
+	Version        int64
+	NextRunAt      pgtype.Timestamptz
+	FailedAttempts int
+	StepAttempts   int
+	FailedStep     string
+	PanicAttempts  int
 }
 
 func (q *Queries) UpdateState(ctx context.Context, arg UpdateStateParams) (uuid.UUID, error) {
@@ -240,6 +729,12 @@ func (q *Queries) UpdateState(ctx context.Context, arg UpdateStateParams) (uuid.
 		arg.MetaData,
 		arg.Error,
 		arg.ID,
+		arg.Version,
+		arg.NextRunAt,
+		arg.FailedAttempts,
+		arg.StepAttempts,
+		arg.FailedStep,
+		arg.PanicAttempts,
 	)
 	var id uuid.UUID
 	err := row.Scan(&id)
@@ -0,0 +1,77 @@
+package statemachine
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/kkiling/statemachine/memstore"
+	"github.com/kkiling/statemachine/storage"
+)
+
+func TestStateMachine_RenewLease(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	cfg := Config{LeaseOwner: "worker_1", LeaseDuration: 30 * time.Millisecond}
+
+	t.Run("lease is renewed while step is running", func(t *testing.T) {
+		t.Parallel()
+
+		s := memstore.NewStorage()
+		sm := NewService[int, any, any, string, string, testOptions](cfg, s, newStepTestRunner("lease_test", testStepperStep{
+			OnStep: func(ctx context.Context, stepContext StepContext[int, any, any, string, string]) *StepResult[int, any, any, string] {
+				// Шаг выполняется дольше первоначальной аренды
+				time.Sleep(5 * cfg.LeaseDuration)
+
+				now := time.Now()
+				_, err := s.ClaimState(ctx, stepContext.State.ID,
+					storage.Lease{Owner: "worker_2", AcquiredAt: now, ExpiresAt: now.Add(time.Minute)})
+				require.ErrorIs(t, err, storage.ErrLocked)
+				return stepContext.Complete()
+			},
+		}))
+		state, err := sm.Create(ctx, testOptions{})
+		require.NoError(t, err)
+
+		res, executeErr, err := sm.Complete(ctx, state.ID)
+		require.NoError(t, err)
+		require.NoError(t, executeErr)
+		require.Equal(t, CompletedStatus, res.Status)
+	})
+
+	t.Run("lease lost", func(t *testing.T) {
+		t.Parallel()
+
+		s := memstore.NewStorage()
+		sm := NewService[int, any, any, string, string, testOptions](cfg, s, newStepTestRunner("lease_test", testStepperStep{
+			OnStep: func(ctx context.Context, stepContext StepContext[int, any, any, string, string]) *StepResult[int, any, any, string] {
+				// Стейт перехватывает другой обработчик
+				require.NoError(t, s.ReleaseState(ctx, stepContext.State.ID, cfg.LeaseOwner))
+				now := time.Now()
+				_, err := s.ClaimState(ctx, stepContext.State.ID,
+					storage.Lease{Owner: "worker_2", AcquiredAt: now, ExpiresAt: now.Add(time.Minute)})
+				require.NoError(t, err)
+
+				select {
+				case <-ctx.Done():
+				case <-time.After(5 * time.Second):
+					t.Error("step context was not cancelled")
+				}
+				return stepContext.Error(ctx.Err())
+			},
+		}))
+		state, err := sm.Create(ctx, testOptions{})
+		require.NoError(t, err)
+
+		_, _, err = sm.Complete(ctx, state.ID)
+		require.ErrorIs(t, err, ErrLeaseLost)
+
+		// Результат шага, выполнявшегося без аренды, не сохраняется
+		findState, err := sm.GetStateByID(ctx, state.ID)
+		require.NoError(t, err)
+		require.Zero(t, findState.FailedAttempts)
+	})
+}
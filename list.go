@@ -0,0 +1,92 @@
+package statemachine
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/samber/lo"
+
+	"github.com/kkiling/statemachine/storage"
+)
+
+const defaultListLimit = 100
+
+// ListCursor позиция стейта в выборке List, передается в ListFilter.After для получения следующей страницы
+type ListCursor = storage.ListCursor
+
+// ListFilter фильтр выборки стейтов типа стейт машины. Пустые значения полей не ограничивают выборку
+type ListFilter[StepT ~string] struct {
+	// Statuses допустимые статусы
+	Statuses []Status
+	// Steps допустимые шаги
+	Steps []StepT
+	// CreatedFrom начало диапазона времени создания (включительно)
+	CreatedFrom *time.Time
+	// CreatedTo конец диапазона времени создания (не включительно)
+	CreatedTo *time.Time
+	// UpdatedFrom начало диапазона времени обновления (включительно)
+	UpdatedFrom *time.Time
+	// UpdatedTo конец диапазона времени обновления (не включительно)
+	UpdatedTo *time.Time
+	// HasError true - только стейты с ошибкой, false - только без ошибки
+	HasError *bool
+	// After курсор предыдущей страницы (ListResult.Next), nil - первая страница
+	After *ListCursor
+	// Limit размер страницы (по умолчанию 100)
+	Limit int
+}
+
+// ListResult страница выборки стейтов
+type ListResult[DataT any, FailDataT any, MetaDataT any, StepT ~string, TypeT ~string] struct {
+	// States стейты в порядке создания
+	States []State[DataT, FailDataT, MetaDataT, StepT, TypeT]
+	// Next курсор следующей страницы, nil - страница последняя
+	Next *ListCursor
+}
+
+// List постраничная выборка стейтов типа стейт машины по фильтру в порядке создания.
+// Пагинация по курсору устойчива к созданию новых стейтов между запросами страниц
+func (i *StateMachine[DataT, FailDataT, MetaDataT, StepT, TypeT, CreateOptionsT]) List(
+	ctx context.Context,
+	filter ListFilter[StepT],
+) (*ListResult[DataT, FailDataT, MetaDataT, StepT, TypeT], error) {
+	limit := lo.Ternary(filter.Limit > 0, filter.Limit, defaultListLimit)
+
+	// Запрашиваем на один стейт больше, чтобы определить наличие следующей страницы
+	states, err := i.storage.ListStates(ctx, storage.ListStatesFilter{
+		Type:     string(i.runner.Type()),
+		Statuses: filter.Statuses,
+		Steps: lo.Map(filter.Steps, func(step StepT, _ int) string {
+			return string(step)
+		}),
+		CreatedFrom: filter.CreatedFrom,
+		CreatedTo:   filter.CreatedTo,
+		UpdatedFrom: filter.UpdatedFrom,
+		UpdatedTo:   filter.UpdatedTo,
+		HasError:    filter.HasError,
+		After:       filter.After,
+		Limit:       limit + 1,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("storage.ListStates: %w", err)
+	}
+
+	res := &ListResult[DataT, FailDataT, MetaDataT, StepT, TypeT]{}
+	if len(states) > limit {
+		states = states[:limit]
+		last := states[limit-1]
+		res.Next = &ListCursor{CreatedAt: last.CreatedAt, ID: last.ID}
+	}
+
+	res.States = make([]State[DataT, FailDataT, MetaDataT, StepT, TypeT], 0, len(states))
+	for _, state := range states {
+		mapped, err := mapStorageToState[DataT, FailDataT, MetaDataT, StepT, TypeT](&state)
+		if err != nil {
+			return nil, fmt.Errorf("mapStorageToState: %w", err)
+		}
+		res.States = append(res.States, *mapped)
+	}
+
+	return res, nil
+}
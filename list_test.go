@@ -0,0 +1,87 @@
+package statemachine
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/samber/lo"
+	"github.com/stretchr/testify/require"
+
+	"github.com/kkiling/statemachine/memstore"
+)
+
+func TestStateMachine_List(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	// Стейты с нулевыми данными фейлятся на шаге second
+	runner, otherRunner := newRetryFailedTestRunner(), newRetryFailedTestRunner()
+	runner.stateType, otherRunner.stateType = "list_test", "list_test_other"
+
+	s := memstore.NewStorage()
+	sm := NewService[int, any, any, string, string, testOptions](Config{}, s, runner)
+	other := NewService[int, any, any, string, string, testOptions](Config{}, s, otherRunner)
+
+	var created []uuid.UUID
+	for i := range 5 {
+		state, err := sm.Create(ctx, testOptions{key: uuid.NewString()})
+		require.NoError(t, err)
+		created = append(created, state.ID)
+		// Часть стейтов доводим до фейла
+		if i%2 == 0 {
+			_, _, err = sm.Complete(ctx, state.ID)
+			require.NoError(t, err)
+		}
+	}
+	// Стейты другого типа в выборку не попадают
+	_, err := other.Create(ctx, testOptions{key: uuid.NewString()})
+	require.NoError(t, err)
+
+	stateIDs := func(states []State[int, any, any, string, string]) []uuid.UUID {
+		return lo.Map(states, func(item State[int, any, any, string, string], _ int) uuid.UUID {
+			return item.ID
+		})
+	}
+
+	t.Run("pagination", func(t *testing.T) {
+		var res []uuid.UUID
+		filter := ListFilter[string]{Limit: 2}
+		for range len(created) {
+			page, err := sm.List(ctx, filter)
+			require.NoError(t, err)
+			require.LessOrEqual(t, len(page.States), filter.Limit)
+			res = append(res, stateIDs(page.States)...)
+			if page.Next == nil {
+				break
+			}
+			filter.After = page.Next
+		}
+		require.ElementsMatch(t, created, res)
+		require.Len(t, res, len(created))
+	})
+
+	t.Run("filter", func(t *testing.T) {
+		page, err := sm.List(ctx, ListFilter[string]{
+			Statuses: []Status{FailedStatus},
+			HasError: lo.ToPtr(false),
+		})
+		require.NoError(t, err)
+		require.Nil(t, page.Next)
+		require.ElementsMatch(t, []uuid.UUID{created[0], created[2], created[4]}, stateIDs(page.States))
+		for _, state := range page.States {
+			require.Equal(t, "second", state.FailedStep)
+		}
+
+		page, err = sm.List(ctx, ListFilter[string]{Steps: []string{"first"}})
+		require.NoError(t, err)
+		require.ElementsMatch(t, []uuid.UUID{created[1], created[3]}, stateIDs(page.States))
+	})
+
+	t.Run("empty", func(t *testing.T) {
+		page, err := sm.List(ctx, ListFilter[string]{Statuses: []Status{CancelledStatus}})
+		require.NoError(t, err)
+		require.Empty(t, page.States)
+		require.Nil(t, page.Next)
+	})
+}
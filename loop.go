@@ -0,0 +1,109 @@
+package statemachine
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/samber/lo"
+
+	"github.com/kkiling/statemachine/storage"
+)
+
+// loopPauseBy автор записи о приостановке стейта защитой от зацикливания
+const loopPauseBy = "statemachine"
+
+// loopGuard защита от зацикливания переходов между шагами за один вызов Complete
+type loopGuard struct {
+	// maxTransitions максимальное количество переходов
+	maxTransitions int
+	transitions    int
+	// seen пройденные шаги вместе с данными стейта на момент перехода
+	seen map[string]struct{}
+}
+
+// newLoopGuard защита от зацикливания для выполнения, начинающегося с шага step с данными data и metaData
+func newLoopGuard(maxTransitions int, step string, data, metaData any) (*loopGuard, error) {
+	dataSnapshot, err := marshalDataSnapshot(data)
+	if err != nil {
+		return nil, err
+	}
+	metaDataSnapshot, err := marshalDataSnapshot(metaData)
+	if err != nil {
+		return nil, err
+	}
+
+	g := &loopGuard{
+		maxTransitions: maxTransitions,
+		seen:           make(map[string]struct{}),
+	}
+	g.seen[loopKey(step, dataSnapshot, metaDataSnapshot)] = struct{}{}
+	return g, nil
+}
+
+func loopKey(step string, data, metaData []byte) string {
+	return fmt.Sprintf("%s\x00%s\x00%s", step, data, metaData)
+}
+
+// visit фиксирует переход на шаг step с данными data и metaData (JSON),
+// возвращает ошибку, если превышено количество переходов или шаг пройден повторно без изменения данных
+func (g *loopGuard) visit(step string, data, metaData []byte) error {
+	key := loopKey(step, data, metaData)
+	if _, ok := g.seen[key]; ok {
+		return fmt.Errorf("%w: step %s reached again without data change", ErrStepCycle, step)
+	}
+	g.seen[key] = struct{}{}
+
+	g.transitions++
+	if g.transitions >= g.maxTransitions {
+		return fmt.Errorf("%w: %d transitions", ErrTransitionLimitExceeded, g.transitions)
+	}
+	return nil
+}
+
+// park приостанавливает стейт после срабатывания защиты от зацикливания, сохраняя причину в ошибке стейта
+// и истории приостановок. Выполнение возобновляется вызовом StateMachine.Resume
+func (s *Stepper[DataT, FailDataT, MetaDataT, StepT, TypeT]) park(
+	ctx context.Context,
+	state State[DataT, FailDataT, MetaDataT, StepT, TypeT],
+	loopErr error,
+) (*State[DataT, FailDataT, MetaDataT, StepT, TypeT], error) {
+	state.Error = lo.ToPtr(loopErr.Error())
+
+	update, err := mapStateToStorageUpdate[DataT, FailDataT, MetaDataT, StepT, TypeT](&state)
+	if err != nil {
+		return nil, fmt.Errorf("mapStateToStorageUpdate: %w", err)
+	}
+
+	err = s.storage.RunTransaction(ctx, func(ctxTx context.Context) error {
+		if terr := s.storage.UpdateState(ctxTx, state.ID, update); terr != nil {
+			return fmt.Errorf("storage.UpdateState: %w", terr)
+		}
+		if terr := s.storage.SetStatePaused(ctxTx, state.ID, true, state.Version+1); terr != nil {
+			return fmt.Errorf("storage.SetStatePaused: %w", terr)
+		}
+		terr := s.storage.SavePauseEvent(ctxTx, storage.PauseEvent{
+			Type:      string(state.Type),
+			StateID:   &state.ID,
+			Paused:    true,
+			CreatedAt: s.clock.Now(),
+			CreatedBy: loopPauseBy,
+			Reason:    loopErr.Error(),
+		})
+		if terr != nil {
+			return fmt.Errorf("storage.SavePauseEvent: %w", terr)
+		}
+		return nil
+	})
+	switch {
+	case err == nil:
+	case errors.Is(err, storage.ErrConcurrentModification):
+		return nil, ErrConcurrentModification
+	default:
+		return nil, fmt.Errorf("storage.RunTransaction: %w", err)
+	}
+
+	state.Version++
+	state.Paused = true
+	return &state, nil
+}
@@ -0,0 +1,137 @@
+package statemachine
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/kkiling/statemachine/memstore"
+)
+
+func TestStateMachine_Loop(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	// next переход на шаг step с увеличением данных на inc
+	next := func(step string, inc int) StepFunc[int, any, any, string, string] {
+		return func(_ context.Context, stepContext StepContext[int, any, any, string, string]) *StepResult[int, any, any, string] {
+			return stepContext.Next(step).WithData(stepContext.State.Data + inc)
+		}
+	}
+
+	t.Run("cycle without data change", func(t *testing.T) {
+		t.Parallel()
+
+		s := memstore.NewStorage()
+		sm := NewService[int, any, any, string, string, testOptions](Config{}, s, newTestRunner("loop_test", "a", map[string]testStepperStep{
+			"a": {OnStep: next("b", 0)},
+			"b": {OnStep: next("a", 0)},
+		}))
+		state, err := sm.Create(ctx, testOptions{})
+		require.NoError(t, err)
+
+		res, executeErr, err := sm.Complete(ctx, state.ID)
+		require.NoError(t, err)
+		require.ErrorIs(t, executeErr, ErrStepCycle)
+
+		// Стейт приостановлен с ошибкой на шаге, замыкающем цикл
+		require.True(t, res.Paused)
+		require.Equal(t, "a", res.Step)
+		require.Equal(t, InProgressStatus, res.Status)
+		require.Equal(t, executeErr.Error(), *res.Error)
+
+		findState, err := sm.GetStateByID(ctx, state.ID)
+		require.NoError(t, err)
+		require.Equal(t, res, findState)
+
+		infos, err := s.GetStepExecuteInfos(ctx, state.ID)
+		require.NoError(t, err)
+		require.Len(t, infos, 2)
+
+		events, err := sm.GetPauseEvents(ctx)
+		require.NoError(t, err)
+		require.Len(t, events, 1)
+		require.Equal(t, &state.ID, events[0].StateID)
+		require.Equal(t, loopPauseBy, events[0].CreatedBy)
+		require.Equal(t, executeErr.Error(), events[0].Reason)
+
+		// Приостановленный стейт не выполняется до возобновления
+		_, _, err = sm.Complete(ctx, state.ID)
+		require.ErrorIs(t, err, ErrPaused)
+	})
+
+	t.Run("cycle with data change", func(t *testing.T) {
+		t.Parallel()
+
+		sm := NewService[int, any, any, string, string, testOptions](Config{}, memstore.NewStorage(), newTestRunner("loop_test", "a", map[string]testStepperStep{
+			"a": {OnStep: next("b", 1)},
+			"b": {
+				OnStep: func(_ context.Context, stepContext StepContext[int, any, any, string, string]) *StepResult[int, any, any, string] {
+					if stepContext.State.Data >= 5 {
+						return stepContext.Complete()
+					}
+					return stepContext.Next("a")
+				},
+			},
+		}))
+		state, err := sm.Create(ctx, testOptions{})
+		require.NoError(t, err)
+
+		res, executeErr, err := sm.Complete(ctx, state.ID)
+		require.NoError(t, err)
+		require.NoError(t, executeErr)
+		require.Equal(t, CompletedStatus, res.Status)
+		require.Equal(t, 5, res.Data)
+		require.False(t, res.Paused)
+	})
+
+	t.Run("transition limit", func(t *testing.T) {
+		t.Parallel()
+
+		s := memstore.NewStorage()
+		sm := NewService[int, any, any, string, string, testOptions](Config{MaxTransitions: 3}, s, newTestRunner("loop_test", "a", map[string]testStepperStep{
+			"a": {OnStep: next("b", 1)},
+			"b": {OnStep: next("a", 1)},
+		}))
+		state, err := sm.Create(ctx, testOptions{})
+		require.NoError(t, err)
+
+		res, executeErr, err := sm.Complete(ctx, state.ID)
+		require.NoError(t, err)
+		require.ErrorIs(t, executeErr, ErrTransitionLimitExceeded)
+		require.True(t, res.Paused)
+		require.Equal(t, 3, res.Data)
+
+		infos, err := s.GetStepExecuteInfos(ctx, state.ID)
+		require.NoError(t, err)
+		require.Len(t, infos, 3)
+
+		// После возобновления выполнение продолжается с новым лимитом переходов
+		_, err = sm.Resume(ctx, state.ID, PauseInfo{By: "operator", Reason: "checked"})
+		require.NoError(t, err)
+		res, executeErr, err = sm.Complete(ctx, state.ID)
+		require.NoError(t, err)
+		require.ErrorIs(t, executeErr, ErrTransitionLimitExceeded)
+		require.Equal(t, 6, res.Data)
+	})
+
+	t.Run("default transition limit", func(t *testing.T) {
+		t.Parallel()
+
+		// Ограничение нельзя отключить, 0 заменяется на ограничение по умолчанию
+		sm := NewService[int, any, any, string, string, testOptions](Config{MaxTransitions: 0}, memstore.NewStorage(), newTestRunner("loop_test", "a", map[string]testStepperStep{
+			"a": {OnStep: next("b", 1)},
+			"b": {OnStep: next("a", 1)},
+		}))
+		state, err := sm.Create(ctx, testOptions{})
+		require.NoError(t, err)
+
+		res, executeErr, err := sm.Complete(ctx, state.ID)
+		require.NoError(t, err)
+		require.ErrorIs(t, executeErr, ErrTransitionLimitExceeded)
+		require.True(t, res.Paused)
+		require.Equal(t, defaultMaxTransitions, res.Data)
+	})
+}
@@ -4,7 +4,7 @@ import (
 	"encoding/json"
 	"fmt"
 
-	"github.com/kkiling/statemachine/internal/storage"
+	"github.com/kkiling/statemachine/storage"
 )
 
 func mapStorageToState[DataT any, FailDataT any, MetaDataT any, StepT ~string, TypeT ~string](
@@ -48,6 +48,13 @@ func mapStorageToState[DataT any, FailDataT any, MetaDataT any, StepT ~string, T
 		FailData:       failData,
 		MetaData:       metaData,
 		Error:          state.Error,
+		Version:        state.Version,
+		NextRunAt:      state.NextRunAt,
+		FailedAttempts: state.FailedAttempts,
+		StepAttempts:   state.StepAttempts,
+		PanicAttempts:  state.PanicAttempts,
+		Paused:         state.Paused,
+		FailedStep:     StepT(state.FailedStep),
 	}, nil
 }
 
@@ -96,5 +103,50 @@ func mapStateToStorage[DataT any, FailDataT any, MetaDataT any, StepT ~string, T
 		FailData:       failData,
 		MetaData:       metaData,
 		Error:          state.Error,
+		Version:        state.Version,
+		NextRunAt:      state.NextRunAt,
+		FailedAttempts: state.FailedAttempts,
+		StepAttempts:   state.StepAttempts,
+		PanicAttempts:  state.PanicAttempts,
+		Paused:         state.Paused,
+		FailedStep:     string(state.FailedStep),
 	}, nil
 }
+
+// mapStateToStorageUpdate изменения стейта для сохранения с проверкой версии state.Version
+func mapStateToStorageUpdate[DataT any, FailDataT any, MetaDataT any, StepT ~string, TypeT ~string](
+	state *State[DataT, FailDataT, MetaDataT, StepT, TypeT],
+) (storage.UpdateState, error) {
+	res, err := mapStateToStorage[DataT, FailDataT, MetaDataT, StepT, TypeT](state)
+	if err != nil {
+		return storage.UpdateState{}, err
+	}
+
+	return storage.UpdateState{
+		UpdatedAt:      res.UpdatedAt,
+		Status:         res.Status,
+		Step:           res.Step,
+		Data:           res.Data,
+		FailData:       res.FailData,
+		MetaData:       res.MetaData,
+		Error:          res.Error,
+		Version:        res.Version,
+		NextRunAt:      res.NextRunAt,
+		FailedAttempts: res.FailedAttempts,
+		StepAttempts:   res.StepAttempts,
+		PanicAttempts:  res.PanicAttempts,
+		FailedStep:     res.FailedStep,
+	}, nil
+}
+
+// marshalDataSnapshot снимок данных стейта для истории выполнения (nil - данных нет)
+func marshalDataSnapshot(data any) ([]byte, error) {
+	res, err := json.Marshal(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal data snapshot: %w", err)
+	}
+	if string(res) == "null" {
+		return nil, nil
+	}
+	return res, nil
+}
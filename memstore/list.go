@@ -0,0 +1,68 @@
+package memstore
+
+import (
+	"bytes"
+	"context"
+	"slices"
+
+	"github.com/kkiling/statemachine/storage"
+)
+
+func (s *Storage) ListStates(ctx context.Context, filter storage.ListStatesFilter) ([]storage.State, error) {
+	var res []storage.State
+	err := s.run(ctx, func(data *snapshot) error {
+		for _, r := range data.states {
+			if matchListFilter(r.state, filter) {
+				res = append(res, r.state)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	slices.SortFunc(res, compareListCursor)
+	if len(res) > filter.Limit {
+		res = res[:filter.Limit]
+	}
+	for i := range res {
+		res[i] = copyState(res[i])
+	}
+	return res, nil
+}
+
+// compareListCursor порядок стейтов в выборке ListStates
+func compareListCursor(a, b storage.State) int {
+	if c := a.CreatedAt.Compare(b.CreatedAt); c != 0 {
+		return c
+	}
+	return bytes.Compare(a.ID[:], b.ID[:])
+}
+
+func matchListFilter(state storage.State, filter storage.ListStatesFilter) bool {
+	switch {
+	case filter.Type != "" && state.Type != filter.Type:
+		return false
+	case len(filter.Statuses) > 0 && !slices.Contains(filter.Statuses, state.Status):
+		return false
+	case len(filter.Steps) > 0 && !slices.Contains(filter.Steps, state.Step):
+		return false
+	case filter.CreatedFrom != nil && state.CreatedAt.Before(*filter.CreatedFrom):
+		return false
+	case filter.CreatedTo != nil && !state.CreatedAt.Before(*filter.CreatedTo):
+		return false
+	case filter.UpdatedFrom != nil && state.UpdatedAt.Before(*filter.UpdatedFrom):
+		return false
+	case filter.UpdatedTo != nil && !state.UpdatedAt.Before(*filter.UpdatedTo):
+		return false
+	case filter.HasError != nil && (state.Error != nil) != *filter.HasError:
+		return false
+	case filter.After != nil && compareListCursor(state, storage.State{
+		CreatedAt: filter.After.CreatedAt,
+		ID:        filter.After.ID,
+	}) <= 0:
+		return false
+	}
+	return true
+}
@@ -0,0 +1,82 @@
+package memstore
+
+import (
+	"context"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/kkiling/statemachine/storage"
+)
+
+func (s *Storage) SetStatePaused(ctx context.Context, stateID uuid.UUID, paused bool, version int64) error {
+	return s.run(ctx, func(data *snapshot) error {
+		r, ok := data.states[stateID]
+		if !ok {
+			return storage.ErrNotFound
+		}
+		if r.state.Version != version {
+			return storage.ErrConcurrentModification
+		}
+		r.state.Paused = paused
+		data.states[stateID] = r
+		return nil
+	})
+}
+
+func (s *Storage) PauseType(ctx context.Context, stateType string, pausedAt time.Time) error {
+	return s.run(ctx, func(data *snapshot) error {
+		if _, ok := data.pausedTypes[stateType]; !ok {
+			data.pausedTypes[stateType] = pausedAt
+		}
+		return nil
+	})
+}
+
+func (s *Storage) ResumeType(ctx context.Context, stateType string) error {
+	return s.run(ctx, func(data *snapshot) error {
+		delete(data.pausedTypes, stateType)
+		return nil
+	})
+}
+
+func (s *Storage) IsTypePaused(ctx context.Context, stateType string) (bool, error) {
+	var res bool
+	err := s.run(ctx, func(data *snapshot) error {
+		_, res = data.pausedTypes[stateType]
+		return nil
+	})
+	return res, err
+}
+
+func (s *Storage) SavePauseEvent(ctx context.Context, event storage.PauseEvent) error {
+	return s.run(ctx, func(data *snapshot) error {
+		if event.StateID != nil {
+			if _, ok := data.states[*event.StateID]; !ok {
+				return storage.ErrForeignKeyViolation
+			}
+		}
+		event.StateID = copyPtr(event.StateID)
+		data.pauseEvents = append(data.pauseEvents, event)
+		return nil
+	})
+}
+
+func (s *Storage) GetPauseEvents(ctx context.Context, stateType string) ([]storage.PauseEvent, error) {
+	var res []storage.PauseEvent
+	err := s.run(ctx, func(data *snapshot) error {
+		for _, event := range data.pauseEvents {
+			if event.Type != stateType {
+				continue
+			}
+			event.StateID = copyPtr(event.StateID)
+			res = append(res, event)
+		}
+		slices.SortStableFunc(res, func(a, b storage.PauseEvent) int {
+			return a.CreatedAt.Compare(b.CreatedAt)
+		})
+		return nil
+	})
+	return res, err
+}
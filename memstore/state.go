@@ -0,0 +1,230 @@
+package memstore
+
+import (
+	"bytes"
+	"context"
+	"slices"
+
+	"github.com/google/uuid"
+	"github.com/samber/lo"
+
+	"github.com/kkiling/statemachine/storage"
+)
+
+func (s *Storage) CreateState(ctx context.Context, state *storage.State) error {
+	return s.run(ctx, func(data *snapshot) error {
+		if _, ok := data.states[state.ID]; ok {
+			return storage.ErrAlreadyExists
+		}
+		if _, ok := data.idempotencyKeys[state.IdempotencyKey]; ok {
+			return storage.ErrAlreadyExists
+		}
+
+		newState := copyState(*state)
+		// Поля, которые при создании заполняются значениями по умолчанию
+		newState.Error = nil
+		newState.Version = 0
+		newState.NextRunAt = nil
+		newState.FailedAttempts = 0
+		newState.StepAttempts = 0
+		newState.PanicAttempts = 0
+		newState.Paused = false
+		newState.FailedStep = ""
+
+		data.states[state.ID] = record{state: newState}
+		data.idempotencyKeys[state.IdempotencyKey] = state.ID
+		return nil
+	})
+}
+
+func (s *Storage) GetStateByIdempotencyKey(ctx context.Context, idempotencyKey string) (*storage.State, error) {
+	var res storage.State
+	err := s.run(ctx, func(data *snapshot) error {
+		id, ok := data.idempotencyKeys[idempotencyKey]
+		if !ok {
+			return storage.ErrNotFound
+		}
+		res = copyState(data.states[id].state)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
+
+func (s *Storage) GetStateByID(ctx context.Context, stateID uuid.UUID) (*storage.State, error) {
+	var res storage.State
+	err := s.run(ctx, func(data *snapshot) error {
+		r, ok := data.states[stateID]
+		if !ok {
+			return storage.ErrNotFound
+		}
+		res = copyState(r.state)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
+
+func (s *Storage) SaveStepExecuteInfo(ctx context.Context, execute storage.StepExecuteInfo) error {
+	return s.run(ctx, func(data *snapshot) error {
+		if _, ok := data.states[execute.StateID]; !ok {
+			return storage.ErrForeignKeyViolation
+		}
+		execute.Error = copyPtr(execute.Error)
+		execute.NextStep = copyPtr(execute.NextStep)
+		execute.Reason = copyPtr(execute.Reason)
+		execute.DataBefore = copyBytes(execute.DataBefore)
+		execute.DataAfter = copyBytes(execute.DataAfter)
+		data.executeInfos[execute.StateID] = append(data.executeInfos[execute.StateID], execute)
+		return nil
+	})
+}
+
+func (s *Storage) GetStepExecuteInfos(ctx context.Context, stateID uuid.UUID) ([]storage.StepExecuteInfo, error) {
+	var res []storage.StepExecuteInfo
+	err := s.run(ctx, func(data *snapshot) error {
+		res = slices.Clone(data.executeInfos[stateID])
+		slices.SortStableFunc(res, func(a, b storage.StepExecuteInfo) int {
+			return a.StartExecutedAt.Compare(b.StartExecutedAt)
+		})
+		return nil
+	})
+	return res, err
+}
+
+func (s *Storage) UpdateState(ctx context.Context, stateID uuid.UUID, state storage.UpdateState) error {
+	return s.run(ctx, func(data *snapshot) error {
+		r, ok := data.states[stateID]
+		if !ok {
+			return storage.ErrNotFound
+		}
+		if r.state.Version != state.Version {
+			return storage.ErrConcurrentModification
+		}
+
+		r.state = copyState(storage.State{
+			ID:             r.state.ID,
+			IdempotencyKey: r.state.IdempotencyKey,
+			CreatedAt:      r.state.CreatedAt,
+			Type:           r.state.Type,
+			UpdatedAt:      state.UpdatedAt,
+			Status:         state.Status,
+			Step:           state.Step,
+			Data:           state.Data,
+			FailData:       state.FailData,
+			MetaData:       state.MetaData,
+			Error:          state.Error,
+			Version:        r.state.Version + 1,
+			NextRunAt:      state.NextRunAt,
+			FailedAttempts: state.FailedAttempts,
+			StepAttempts:   state.StepAttempts,
+			PanicAttempts:  state.PanicAttempts,
+			Paused:         r.state.Paused,
+			FailedStep:     state.FailedStep,
+		})
+		data.states[stateID] = r
+		return nil
+	})
+}
+
+func (s *Storage) ClaimState(ctx context.Context, stateID uuid.UUID, lease storage.Lease) (*storage.State, error) {
+	var res storage.State
+	err := s.run(ctx, func(data *snapshot) error {
+		r, ok := data.states[stateID]
+		if !ok {
+			return storage.ErrNotFound
+		}
+		if !r.leaseAvailable(lease) {
+			return storage.ErrLocked
+		}
+
+		r.leaseOwner = lease.Owner
+		r.leaseExpiresAt = lo.ToPtr(lease.ExpiresAt)
+		data.states[stateID] = r
+		res = copyState(r.state)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
+
+func (s *Storage) ClaimReadyStates(ctx context.Context, filter storage.ReadyStatesFilter, lease storage.Lease) ([]storage.State, error) {
+	var res []storage.State
+	err := s.run(ctx, func(data *snapshot) error {
+		ready := make([]record, 0)
+		for _, r := range data.states {
+			if r.state.Type != filter.Type || !slices.Contains(filter.Statuses, r.state.Status) {
+				continue
+			}
+			if !r.leaseAvailable(lease) {
+				continue
+			}
+			if r.state.NextRunAt != nil && r.state.NextRunAt.After(lease.AcquiredAt) {
+				continue
+			}
+			if _, ok := data.pausedTypes[r.state.Type]; ok || r.state.Paused {
+				continue
+			}
+			ready = append(ready, r)
+		}
+
+		slices.SortFunc(ready, func(a, b record) int {
+			if c := a.state.UpdatedAt.Compare(b.state.UpdatedAt); c != 0 {
+				return c
+			}
+			return bytes.Compare(a.state.ID[:], b.state.ID[:])
+		})
+		if len(ready) > filter.Limit {
+			ready = ready[:filter.Limit]
+		}
+
+		for _, r := range ready {
+			r.leaseOwner = lease.Owner
+			r.leaseExpiresAt = lo.ToPtr(lease.ExpiresAt)
+			data.states[r.state.ID] = r
+			res = append(res, copyState(r.state))
+		}
+		return nil
+	})
+	return res, err
+}
+
+func (s *Storage) RenewLease(ctx context.Context, stateID uuid.UUID, lease storage.Lease) error {
+	return s.run(ctx, func(data *snapshot) error {
+		r, ok := data.states[stateID]
+		if !ok {
+			return storage.ErrNotFound
+		}
+		if r.leaseExpiresAt == nil || r.leaseOwner != lease.Owner {
+			return storage.ErrLocked
+		}
+
+		r.leaseExpiresAt = lo.ToPtr(lease.ExpiresAt)
+		data.states[stateID] = r
+		return nil
+	})
+}
+
+func (s *Storage) ReleaseState(ctx context.Context, stateID uuid.UUID, owner string) error {
+	return s.run(ctx, func(data *snapshot) error {
+		r, ok := data.states[stateID]
+		if !ok || r.leaseOwner != owner {
+			return nil
+		}
+		r.leaseOwner = ""
+		r.leaseExpiresAt = nil
+		data.states[stateID] = r
+		return nil
+	})
+}
+
+// leaseAvailable стейт не арендован, либо аренда истекла к моменту захвата
+func (r record) leaseAvailable(lease storage.Lease) bool {
+	return r.leaseExpiresAt == nil || !r.leaseExpiresAt.After(lease.AcquiredAt)
+}
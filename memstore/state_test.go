@@ -0,0 +1,49 @@
+package memstore_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+
+	"github.com/kkiling/statemachine/memstore"
+	"github.com/kkiling/statemachine/storage"
+	"github.com/kkiling/statemachine/storagetest"
+)
+
+func TestStorage(t *testing.T) {
+	t.Parallel()
+
+	storagetest.Run(t, func(*testing.T) storagetest.Storage {
+		return memstore.NewStorage()
+	})
+}
+
+func TestGetStateByID_ReturnsCopy(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	s := memstore.NewStorage()
+
+	state := &storage.State{
+		ID:             uuid.New(),
+		IdempotencyKey: uuid.NewString(),
+		CreatedAt:      time.Now(),
+		UpdatedAt:      time.Now(),
+		Step:           "first",
+		Type:           "test",
+		Data:           []byte(`{"counter":0}`),
+	}
+	require.NoError(t, s.CreateState(ctx, state))
+
+	findState, err := s.GetStateByID(ctx, state.ID)
+	require.NoError(t, err)
+	// Изменение полученных данных не затрагивает хранилище
+	findState.Data[0] = 'x'
+
+	findState, err = s.GetStateByID(ctx, state.ID)
+	require.NoError(t, err)
+	require.Equal(t, state.Data, findState.Data)
+}
@@ -0,0 +1,149 @@
+// Package memstore потокобезопасная реализация statemachine.Storage в памяти процесса
+// для юнит тестов и локальной разработки
+package memstore
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/kkiling/statemachine/storage"
+)
+
+type txKey struct{}
+
+// record стейт вместе с его арендой
+type record struct {
+	state          storage.State
+	leaseOwner     string
+	leaseExpiresAt *time.Time
+}
+
+// snapshot данные хранилища
+type snapshot struct {
+	states          map[uuid.UUID]record
+	idempotencyKeys map[string]uuid.UUID
+	executeInfos    map[uuid.UUID][]storage.StepExecuteInfo
+	pausedTypes     map[string]time.Time
+	pauseEvents     []storage.PauseEvent
+}
+
+// tx транзакция, работающая с копией данных хранилища
+type tx struct {
+	owner *Storage
+	data  *snapshot
+}
+
+// Storage хранилище в памяти.
+// Транзакции выполняются последовательно: на время RunTransaction хранилище блокируется,
+// поэтому внутри txFunc все вызовы хранилища должны выполняться с ctxTx
+type Storage struct {
+	mu   sync.Mutex
+	data *snapshot
+}
+
+func NewStorage() *Storage {
+	return &Storage{
+		data: newSnapshot(),
+	}
+}
+
+func newSnapshot() *snapshot {
+	return &snapshot{
+		states:          make(map[uuid.UUID]record),
+		idempotencyKeys: make(map[string]uuid.UUID),
+		executeInfos:    make(map[uuid.UUID][]storage.StepExecuteInfo),
+		pausedTypes:     make(map[string]time.Time),
+	}
+}
+
+// clone копия данных, изменения которой не затрагивают исходные
+func (d *snapshot) clone() *snapshot {
+	res := &snapshot{
+		states:          make(map[uuid.UUID]record, len(d.states)),
+		idempotencyKeys: make(map[string]uuid.UUID, len(d.idempotencyKeys)),
+		executeInfos:    make(map[uuid.UUID][]storage.StepExecuteInfo, len(d.executeInfos)),
+		pausedTypes:     make(map[string]time.Time, len(d.pausedTypes)),
+		pauseEvents:     append([]storage.PauseEvent(nil), d.pauseEvents...),
+	}
+	for id, r := range d.states {
+		res.states[id] = r
+	}
+	for key, id := range d.idempotencyKeys {
+		res.idempotencyKeys[key] = id
+	}
+	for id, infos := range d.executeInfos {
+		res.executeInfos[id] = append([]storage.StepExecuteInfo(nil), infos...)
+	}
+	for stateType, pausedAt := range d.pausedTypes {
+		res.pausedTypes[stateType] = pausedAt
+	}
+	return res
+}
+
+// RunTransaction выполняет txFunc над копией данных, изменения применяются только если txFunc не вернула ошибку.
+// Вложенный вызов выполняется в рамках внешней транзакции
+func (s *Storage) RunTransaction(ctx context.Context, txFunc func(ctxTx context.Context) error) error {
+	if t, ok := ctx.Value(txKey{}).(*tx); ok && t.owner == s {
+		return txFunc(ctx)
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	t := &tx{
+		owner: s,
+		data:  s.data.clone(),
+	}
+	if err := txFunc(context.WithValue(ctx, txKey{}, t)); err != nil {
+		return err
+	}
+
+	s.data = t.data
+	return nil
+}
+
+// run выполняет fn над данными текущей транзакции, либо над данными хранилища под блокировкой
+func (s *Storage) run(ctx context.Context, fn func(data *snapshot) error) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if t, ok := ctx.Value(txKey{}).(*tx); ok && t.owner == s {
+		return fn(t.data)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return fn(s.data)
+}
+
+// copyState копия стейта, не разделяющая память с исходным
+func copyState(state storage.State) storage.State {
+	state.Data = copyBytes(state.Data)
+	state.FailData = copyBytes(state.FailData)
+	state.MetaData = copyBytes(state.MetaData)
+	state.Error = copyPtr(state.Error)
+	state.NextRunAt = copyPtr(state.NextRunAt)
+	return state
+}
+
+func copyBytes(b []byte) []byte {
+	if len(b) == 0 {
+		return nil
+	}
+	return append([]byte(nil), b...)
+}
+
+func copyPtr[T any](v *T) *T {
+	if v == nil {
+		return nil
+	}
+	res := *v
+	return &res
+}
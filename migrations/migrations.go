@@ -0,0 +1,16 @@
+// Package migrations миграции схемы хранилищ стейт машины в формате goose.
+// Миграции встроены в библиотеку и применяются через statemachine.Migrate,
+// либо могут быть переданы в собственный инструмент миграций (например goose.SetBaseFS)
+package migrations
+
+import "embed"
+
+// PostgreSQL миграции для PostgreSQL, расположены в директории postgresql
+//
+//go:embed postgresql/*.sql
+var PostgreSQL embed.FS
+
+// SQLite миграции для SQLite, расположены в директории sqlite
+//
+//go:embed sqlite/*.sql
+var SQLite embed.FS
@@ -1,6 +1,6 @@
 -- +goose Up
 -- +goose StatementBegin
-CREATE TABLE state (
+CREATE TABLE IF NOT EXISTS state (
     id UUID PRIMARY KEY,
     idempotency_key TEXT NOT NULL,
     created_at TIMESTAMPTZ NOT NULL,
@@ -14,7 +14,7 @@ CREATE TABLE state (
     meta_data JSONB
 );
 
-CREATE UNIQUE INDEX idx_state_idempotency_key ON state(idempotency_key);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_state_idempotency_key ON state(idempotency_key);
 -- +goose StatementEnd
 
 -- +goose Down
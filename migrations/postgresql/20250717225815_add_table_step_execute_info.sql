@@ -1,6 +1,6 @@
 -- +goose Up
 -- +goose StatementBegin
-CREATE TABLE step_execute_info (
+CREATE TABLE IF NOT EXISTS step_execute_info (
     id SERIAL PRIMARY KEY,
     state_id UUID NOT NULL,
     start_executed_at TIMESTAMPTZ NOT NULL,
@@ -11,7 +11,7 @@ CREATE TABLE step_execute_info (
     FOREIGN KEY (state_id) REFERENCES state(id) ON DELETE CASCADE
 );
 
-CREATE INDEX idx_step_execute_state_id ON step_execute_info(state_id);
+CREATE INDEX IF NOT EXISTS idx_step_execute_state_id ON step_execute_info(state_id);
 -- +goose StatementEnd
 
 -- +goose Down
@@ -0,0 +1,11 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE state ADD COLUMN IF NOT EXISTS lease_owner TEXT;
+ALTER TABLE state ADD COLUMN IF NOT EXISTS lease_expires_at TIMESTAMPTZ;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE state DROP COLUMN IF EXISTS lease_expires_at;
+ALTER TABLE state DROP COLUMN IF EXISTS lease_owner;
+-- +goose StatementEnd
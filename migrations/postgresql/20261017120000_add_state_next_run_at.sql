@@ -0,0 +1,9 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE state ADD COLUMN IF NOT EXISTS next_run_at TIMESTAMPTZ;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE state DROP COLUMN IF EXISTS next_run_at;
+-- +goose StatementEnd
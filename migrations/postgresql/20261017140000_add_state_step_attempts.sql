@@ -0,0 +1,9 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE state ADD COLUMN IF NOT EXISTS step_attempts INTEGER NOT NULL DEFAULT 0;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE state DROP COLUMN IF EXISTS step_attempts;
+-- +goose StatementEnd
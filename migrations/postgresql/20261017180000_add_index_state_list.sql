@@ -0,0 +1,12 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Индексы для постраничной выборки стейтов в порядке создания
+CREATE INDEX IF NOT EXISTS idx_state_type_created_at_id ON state(type, created_at, id);
+CREATE INDEX IF NOT EXISTS idx_state_created_at_id ON state(created_at, id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS idx_state_created_at_id;
+DROP INDEX IF EXISTS idx_state_type_created_at_id;
+-- +goose StatementEnd
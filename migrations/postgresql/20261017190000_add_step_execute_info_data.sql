@@ -0,0 +1,11 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE step_execute_info ADD COLUMN IF NOT EXISTS data_before JSONB;
+ALTER TABLE step_execute_info ADD COLUMN IF NOT EXISTS data_after JSONB;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE step_execute_info DROP COLUMN IF EXISTS data_after;
+ALTER TABLE step_execute_info DROP COLUMN IF EXISTS data_before;
+-- +goose StatementEnd
@@ -0,0 +1,45 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Время хранится в наносекундах unix time (UTC), что бы сравнение в запросах не зависело от формата и часового пояса
+CREATE TABLE IF NOT EXISTS state (
+    id TEXT PRIMARY KEY,
+    idempotency_key TEXT NOT NULL,
+    created_at INTEGER NOT NULL,
+    updated_at INTEGER NOT NULL,
+    status INTEGER NOT NULL,
+    step TEXT NOT NULL,
+    type TEXT NOT NULL,
+    error TEXT,
+    data BLOB,
+    fail_data BLOB,
+    meta_data BLOB,
+    lease_owner TEXT,
+    lease_expires_at INTEGER,
+    version INTEGER NOT NULL DEFAULT 0,
+    next_run_at INTEGER,
+    failed_attempts INTEGER NOT NULL DEFAULT 0,
+    step_attempts INTEGER NOT NULL DEFAULT 0
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_state_idempotency_key ON state(idempotency_key);
+CREATE INDEX IF NOT EXISTS idx_state_type_status_updated_at ON state(type, status, updated_at);
+
+CREATE TABLE IF NOT EXISTS step_execute_info (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    state_id TEXT NOT NULL,
+    start_executed_at INTEGER NOT NULL,
+    complete_executed_at INTEGER NOT NULL,
+    error TEXT,
+    preview_step TEXT NOT NULL,
+    next_step TEXT,
+    FOREIGN KEY (state_id) REFERENCES state(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_step_execute_state_id ON step_execute_info(state_id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS step_execute_info;
+DROP TABLE IF EXISTS state;
+-- +goose StatementEnd
@@ -0,0 +1,29 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE state ADD COLUMN paused BOOLEAN NOT NULL DEFAULT FALSE;
+
+CREATE TABLE IF NOT EXISTS state_type_pause (
+    type TEXT PRIMARY KEY,
+    paused_at INTEGER NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS pause_event (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    type TEXT NOT NULL,
+    state_id TEXT,
+    paused BOOLEAN NOT NULL,
+    created_at INTEGER NOT NULL,
+    created_by TEXT NOT NULL,
+    reason TEXT NOT NULL,
+    FOREIGN KEY (state_id) REFERENCES state(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_pause_event_type ON pause_event(type);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS pause_event;
+DROP TABLE IF EXISTS state_type_pause;
+ALTER TABLE state DROP COLUMN paused;
+-- +goose StatementEnd
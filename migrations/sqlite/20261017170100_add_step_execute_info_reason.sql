@@ -0,0 +1,9 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE step_execute_info ADD COLUMN reason TEXT;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE step_execute_info DROP COLUMN reason;
+-- +goose StatementEnd
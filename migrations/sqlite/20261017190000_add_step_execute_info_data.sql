@@ -0,0 +1,11 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE step_execute_info ADD COLUMN data_before BLOB;
+ALTER TABLE step_execute_info ADD COLUMN data_after BLOB;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE step_execute_info DROP COLUMN data_after;
+ALTER TABLE step_execute_info DROP COLUMN data_before;
+-- +goose StatementEnd
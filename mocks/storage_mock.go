@@ -12,7 +12,7 @@ import (
 	gomock "github.com/golang/mock/gomock"
 	uuid "github.com/google/uuid"
 
-	storage "github.com/kkiling/statemachine/internal/storage"
+	storage "github.com/kkiling/statemachine/storage"
 )
 
 // MockStorage is a mock of Storage interface.
@@ -38,6 +38,36 @@ func (m *MockStorage) EXPECT() *MockStorageMockRecorder {
 	return m.recorder
 }
 
+// ClaimReadyStates mocks base method.
+func (m *MockStorage) ClaimReadyStates(ctx context.Context, filter storage.ReadyStatesFilter, lease storage.Lease) ([]storage.State, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ClaimReadyStates", ctx, filter, lease)
+	ret0, _ := ret[0].([]storage.State)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ClaimReadyStates indicates an expected call of ClaimReadyStates.
+func (mr *MockStorageMockRecorder) ClaimReadyStates(ctx, filter, lease interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ClaimReadyStates", reflect.TypeOf((*MockStorage)(nil).ClaimReadyStates), ctx, filter, lease)
+}
+
+// ClaimState mocks base method.
+func (m *MockStorage) ClaimState(ctx context.Context, stateID uuid.UUID, lease storage.Lease) (*storage.State, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ClaimState", ctx, stateID, lease)
+	ret0, _ := ret[0].(*storage.State)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ClaimState indicates an expected call of ClaimState.
+func (mr *MockStorageMockRecorder) ClaimState(ctx, stateID, lease interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ClaimState", reflect.TypeOf((*MockStorage)(nil).ClaimState), ctx, stateID, lease)
+}
+
 // CreateState mocks base method.
 func (m *MockStorage) CreateState(ctx context.Context, state *storage.State) error {
 	m.ctrl.T.Helper()
@@ -52,6 +82,21 @@ func (mr *MockStorageMockRecorder) CreateState(ctx, state interface{}) *gomock.C
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateState", reflect.TypeOf((*MockStorage)(nil).CreateState), ctx, state)
 }
 
+// GetPauseEvents mocks base method.
+func (m *MockStorage) GetPauseEvents(ctx context.Context, stateType string) ([]storage.PauseEvent, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetPauseEvents", ctx, stateType)
+	ret0, _ := ret[0].([]storage.PauseEvent)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetPauseEvents indicates an expected call of GetPauseEvents.
+func (mr *MockStorageMockRecorder) GetPauseEvents(ctx, stateType interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetPauseEvents", reflect.TypeOf((*MockStorage)(nil).GetPauseEvents), ctx, stateType)
+}
+
 // GetStateByID mocks base method.
 func (m *MockStorage) GetStateByID(ctx context.Context, stateID uuid.UUID) (*storage.State, error) {
 	m.ctrl.T.Helper()
@@ -82,6 +127,107 @@ func (mr *MockStorageMockRecorder) GetStateByIdempotencyKey(ctx, idempotencyKey
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetStateByIdempotencyKey", reflect.TypeOf((*MockStorage)(nil).GetStateByIdempotencyKey), ctx, idempotencyKey)
 }
 
+// GetStepExecuteInfos mocks base method.
+func (m *MockStorage) GetStepExecuteInfos(ctx context.Context, stateID uuid.UUID) ([]storage.StepExecuteInfo, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetStepExecuteInfos", ctx, stateID)
+	ret0, _ := ret[0].([]storage.StepExecuteInfo)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetStepExecuteInfos indicates an expected call of GetStepExecuteInfos.
+func (mr *MockStorageMockRecorder) GetStepExecuteInfos(ctx, stateID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetStepExecuteInfos", reflect.TypeOf((*MockStorage)(nil).GetStepExecuteInfos), ctx, stateID)
+}
+
+// IsTypePaused mocks base method.
+func (m *MockStorage) IsTypePaused(ctx context.Context, stateType string) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "IsTypePaused", ctx, stateType)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// IsTypePaused indicates an expected call of IsTypePaused.
+func (mr *MockStorageMockRecorder) IsTypePaused(ctx, stateType interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "IsTypePaused", reflect.TypeOf((*MockStorage)(nil).IsTypePaused), ctx, stateType)
+}
+
+// ListStates mocks base method.
+func (m *MockStorage) ListStates(ctx context.Context, filter storage.ListStatesFilter) ([]storage.State, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListStates", ctx, filter)
+	ret0, _ := ret[0].([]storage.State)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListStates indicates an expected call of ListStates.
+func (mr *MockStorageMockRecorder) ListStates(ctx, filter interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListStates", reflect.TypeOf((*MockStorage)(nil).ListStates), ctx, filter)
+}
+
+// PauseType mocks base method.
+func (m *MockStorage) PauseType(ctx context.Context, stateType string, pausedAt time.Time) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "PauseType", ctx, stateType, pausedAt)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// PauseType indicates an expected call of PauseType.
+func (mr *MockStorageMockRecorder) PauseType(ctx, stateType, pausedAt interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PauseType", reflect.TypeOf((*MockStorage)(nil).PauseType), ctx, stateType, pausedAt)
+}
+
+// ReleaseState mocks base method.
+func (m *MockStorage) ReleaseState(ctx context.Context, stateID uuid.UUID, owner string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ReleaseState", ctx, stateID, owner)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ReleaseState indicates an expected call of ReleaseState.
+func (mr *MockStorageMockRecorder) ReleaseState(ctx, stateID, owner interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReleaseState", reflect.TypeOf((*MockStorage)(nil).ReleaseState), ctx, stateID, owner)
+}
+
+// RenewLease mocks base method.
+func (m *MockStorage) RenewLease(ctx context.Context, stateID uuid.UUID, lease storage.Lease) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RenewLease", ctx, stateID, lease)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RenewLease indicates an expected call of RenewLease.
+func (mr *MockStorageMockRecorder) RenewLease(ctx, stateID, lease interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RenewLease", reflect.TypeOf((*MockStorage)(nil).RenewLease), ctx, stateID, lease)
+}
+
+// ResumeType mocks base method.
+func (m *MockStorage) ResumeType(ctx context.Context, stateType string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ResumeType", ctx, stateType)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ResumeType indicates an expected call of ResumeType.
+func (mr *MockStorageMockRecorder) ResumeType(ctx, stateType interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ResumeType", reflect.TypeOf((*MockStorage)(nil).ResumeType), ctx, stateType)
+}
+
 // RunTransaction mocks base method.
 func (m *MockStorage) RunTransaction(ctx context.Context, txFunc func(context.Context) error) error {
 	m.ctrl.T.Helper()
@@ -96,6 +242,20 @@ func (mr *MockStorageMockRecorder) RunTransaction(ctx, txFunc interface{}) *gomo
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RunTransaction", reflect.TypeOf((*MockStorage)(nil).RunTransaction), ctx, txFunc)
 }
 
+// SavePauseEvent mocks base method.
+func (m *MockStorage) SavePauseEvent(ctx context.Context, event storage.PauseEvent) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SavePauseEvent", ctx, event)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SavePauseEvent indicates an expected call of SavePauseEvent.
+func (mr *MockStorageMockRecorder) SavePauseEvent(ctx, event interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SavePauseEvent", reflect.TypeOf((*MockStorage)(nil).SavePauseEvent), ctx, event)
+}
+
 // SaveStepExecuteInfo mocks base method.
 func (m *MockStorage) SaveStepExecuteInfo(ctx context.Context, execute storage.StepExecuteInfo) error {
 	m.ctrl.T.Helper()
@@ -110,6 +270,20 @@ func (mr *MockStorageMockRecorder) SaveStepExecuteInfo(ctx, execute interface{})
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SaveStepExecuteInfo", reflect.TypeOf((*MockStorage)(nil).SaveStepExecuteInfo), ctx, execute)
 }
 
+// SetStatePaused mocks base method.
+func (m *MockStorage) SetStatePaused(ctx context.Context, stateID uuid.UUID, paused bool, version int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetStatePaused", ctx, stateID, paused, version)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetStatePaused indicates an expected call of SetStatePaused.
+func (mr *MockStorageMockRecorder) SetStatePaused(ctx, stateID, paused, version interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetStatePaused", reflect.TypeOf((*MockStorage)(nil).SetStatePaused), ctx, stateID, paused, version)
+}
+
 // UpdateState mocks base method.
 func (m *MockStorage) UpdateState(ctx context.Context, stateID uuid.UUID, state storage.UpdateState) error {
 	m.ctrl.T.Helper()
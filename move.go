@@ -0,0 +1,67 @@
+package statemachine
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/samber/lo"
+
+	"github.com/kkiling/statemachine/storage"
+)
+
+// MoveToStep переводит стейт на шаг step в обход шагов стейт машины, например чтобы пропустить
+// сломанный вызов внешней системы или повторить пройденный шаг. Переход сохраняется в истории выполнения
+// с причиной reason и проверкой версии стейта, как и обычный переход между шагами.
+// Если шаг стейта выполняется в этом экземпляре стейт машины, его контекст отменяется,
+// а выполнение завершается ошибкой ErrStateMoved без сохранения результата.
+// Для незарегистрированного шага возвращает ErrUnknownStep, для стейта в терминальном статусе - ErrInTerminalStatus
+func (i *StateMachine[DataT, FailDataT, MetaDataT, StepT, TypeT, CreateOptionsT]) MoveToStep(
+	ctx context.Context,
+	stateID uuid.UUID,
+	step StepT,
+	reason string,
+) (*State[DataT, FailDataT, MetaDataT, StepT, TypeT], error) {
+	if _, ok := i.runner.StepRegistration(StepRegistrationParams{}).Steps[step]; !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownStep, step)
+	}
+
+	findState, err := i.GetStateByID(ctx, stateID)
+	if err != nil {
+		return nil, fmt.Errorf("GetStateByID: %w", err)
+	}
+	if findState == nil {
+		return nil, fmt.Errorf("state not found: %w", ErrNotFound)
+	}
+	if isTerminalStatus(findState.Status) {
+		return nil, ErrInTerminalStatus
+	}
+
+	now := i.clock.Now()
+	newState := *findState
+	if newState.Status == NewStatus {
+		newState.Status = InProgressStatus
+	}
+	newState.Step = step
+	newState.UpdatedAt = now
+	newState.Error = nil
+	newState.NextRunAt = nil
+	newState.FailedAttempts = 0
+	newState.StepAttempts = 0
+	newState.PanicAttempts = 0
+
+	err = i.saveState(ctx, &newState, storage.StepExecuteInfo{
+		StateID:            stateID,
+		StartExecutedAt:    now,
+		CompleteExecutedAt: now,
+		PreviewStep:        string(findState.Step),
+		NextStep:           lo.ToPtr(string(step)),
+		Reason:             lo.ToPtr(reason),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	i.running.cancel(stateID, ErrStateMoved)
+	return &newState, nil
+}
@@ -0,0 +1,124 @@
+package statemachine
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/samber/lo"
+	"github.com/stretchr/testify/require"
+
+	"github.com/kkiling/statemachine/memstore"
+)
+
+func TestStateMachine_MoveToStep(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	t.Run("skip step", func(t *testing.T) {
+		t.Parallel()
+
+		s := memstore.NewStorage()
+		sm := NewService[int, any, any, string, string, testOptions](Config{}, s, newRetryFailedTestRunner())
+		state, err := sm.Create(ctx, testOptions{})
+		require.NoError(t, err)
+
+		moveState, err := sm.MoveToStep(ctx, state.ID, "second", "skip broken call")
+		require.NoError(t, err)
+		require.Equal(t, InProgressStatus, moveState.Status)
+		require.Equal(t, "second", moveState.Step)
+		require.Equal(t, state.Version+1, moveState.Version)
+
+		findState, err := sm.GetStateByID(ctx, state.ID)
+		require.NoError(t, err)
+		require.Equal(t, moveState, findState)
+
+		// Переход сохраняется в истории выполнения
+		infos, err := s.GetStepExecuteInfos(ctx, state.ID)
+		require.NoError(t, err)
+		require.Len(t, infos, 1)
+		require.Equal(t, "first", infos[0].PreviewStep)
+		require.Equal(t, lo.ToPtr("second"), infos[0].NextStep)
+		require.Equal(t, lo.ToPtr("skip broken call"), infos[0].Reason)
+		require.Nil(t, infos[0].Error)
+	})
+
+	t.Run("unknown step", func(t *testing.T) {
+		t.Parallel()
+
+		sm := NewService[int, any, any, string, string, testOptions](Config{}, memstore.NewStorage(), newRetryFailedTestRunner())
+		state, err := sm.Create(ctx, testOptions{})
+		require.NoError(t, err)
+
+		_, err = sm.MoveToStep(ctx, state.ID, "unknown", "typo")
+		require.ErrorIs(t, err, ErrUnknownStep)
+	})
+
+	t.Run("state in terminal status", func(t *testing.T) {
+		t.Parallel()
+
+		sm := NewService[int, any, any, string, string, testOptions](Config{}, memstore.NewStorage(), newRetryFailedTestRunner())
+		state, err := sm.Create(ctx, testOptions{})
+		require.NoError(t, err)
+		_, _, err = sm.Complete(ctx, state.ID)
+		require.NoError(t, err)
+
+		_, err = sm.MoveToStep(ctx, state.ID, "first", "too late")
+		require.ErrorIs(t, err, ErrInTerminalStatus)
+	})
+
+	t.Run("state not found", func(t *testing.T) {
+		t.Parallel()
+
+		sm := NewService[int, any, any, string, string, testOptions](Config{}, memstore.NewStorage(), newRetryFailedTestRunner())
+
+		_, err := sm.MoveToStep(ctx, uuid.New(), "first", "unknown state")
+		require.ErrorIs(t, err, ErrNotFound)
+	})
+
+	t.Run("move executing step", func(t *testing.T) {
+		t.Parallel()
+
+		started := make(chan struct{})
+		s := memstore.NewStorage()
+		sm := NewService[int, any, any, string, string, testOptions](Config{}, s, newRetryFailedTestRunner().withStep("first", testStepperStep{
+			OnStep: func(ctx context.Context, stepContext testStepperContext) *testStepperResult {
+				close(started)
+				// Шаг выполняется до отмены контекста
+				<-ctx.Done()
+				return stepContext.Error(ctx.Err())
+			},
+		}))
+		state, err := sm.Create(ctx, testOptions{})
+		require.NoError(t, err)
+
+		completeErr := make(chan error)
+		go func() {
+			_, _, err := sm.Complete(ctx, state.ID)
+			completeErr <- err
+		}()
+
+		select {
+		case <-started:
+		case <-time.After(5 * time.Second):
+			t.Fatal("step was not started")
+		}
+
+		_, err = sm.MoveToStep(ctx, state.ID, "second", "skip hanging call")
+		require.NoError(t, err)
+		require.ErrorIs(t, <-completeErr, ErrStateMoved)
+
+		// Результат прерванного шага не сохраняется, стейт остается на новом шаге
+		findState, err := sm.GetStateByID(ctx, state.ID)
+		require.NoError(t, err)
+		require.Equal(t, "second", findState.Step)
+		require.Zero(t, findState.FailedAttempts)
+
+		infos, err := s.GetStepExecuteInfos(ctx, state.ID)
+		require.NoError(t, err)
+		require.Len(t, infos, 1)
+		require.Equal(t, lo.ToPtr("skip hanging call"), infos[0].Reason)
+	})
+}
@@ -0,0 +1,67 @@
+package statemachine
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime/debug"
+)
+
+// PanicPolicy политика обработки паники в функции шага. Паника обрабатывается как ошибка выполнения шага
+// (*StepPanicError) и сохраняется в истории выполнения вместе со стеком вызовов
+type PanicPolicy struct {
+	// MaxPanics количество выполнений шага подряд, завершившихся паникой, начиная с которого паника
+	// переводит стейт в статус фейла. Учитываются только паники: любой другой результат шага, в том числе
+	// ошибка без паники, сбрасывает счетчик (1 - фейл после первой паники,
+	// 0 - паника повторяется по RetryPolicy как обычная ошибка)
+	MaxPanics int
+}
+
+// isExhausted нужно ли перевести стейт в статус фейла после ошибки err,
+// panicAttempts - количество паник шага подряд, включая err
+func (p *PanicPolicy) isExhausted(err error, panicAttempts int) bool {
+	if p == nil || p.MaxPanics <= 0 {
+		return false
+	}
+	return isStepPanic(err) && panicAttempts >= p.MaxPanics
+}
+
+// isStepPanic завершилось ли выполнение шага паникой
+func isStepPanic(err error) bool {
+	var panicErr *StepPanicError
+	return errors.As(err, &panicErr)
+}
+
+// stepPanicPrefix префикс текста ошибки паники в функции шага
+const stepPanicPrefix = "step panic: "
+
+// StepPanicError паника в функции шага
+type StepPanicError struct {
+	// Value значение, переданное в panic
+	Value any
+	// Stack стек вызовов в момент паники
+	Stack []byte
+}
+
+func (e *StepPanicError) Error() string {
+	return fmt.Sprintf(stepPanicPrefix+"%v\n%s", e.Value, e.Stack)
+}
+
+func (e *StepPanicError) Unwrap() error {
+	err, _ := e.Value.(error)
+	return err
+}
+
+// callStep выполняет функцию шага, паника в функции шага возвращается как ошибка выполнения шага
+func callStep[DataT any, FailDataT any, MetaDataT any, StepT ~string, TypeT ~string](
+	ctx context.Context,
+	onStep StepFunc[DataT, FailDataT, MetaDataT, StepT, TypeT],
+	stepCtx StepContext[DataT, FailDataT, MetaDataT, StepT, TypeT],
+) (res *StepResult[DataT, FailDataT, MetaDataT, StepT]) {
+	defer func() {
+		if r := recover(); r != nil {
+			res = stepCtx.Error(&StepPanicError{Value: r, Stack: debug.Stack()})
+		}
+	}()
+	return onStep(ctx, stepCtx)
+}
@@ -0,0 +1,154 @@
+package statemachine
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/kkiling/statemachine/memstore"
+)
+
+func TestStateMachine_StepPanic(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	panicStep := func(context.Context, StepContext[int, any, any, string, string]) *StepResult[int, any, any, string] {
+		panic("boom")
+	}
+
+	t.Run("panic is recorded as step error", func(t *testing.T) {
+		t.Parallel()
+
+		s := memstore.NewStorage()
+		sm := NewService[int, any, any, string, string, testOptions](Config{}, s, newStepTestRunner("panic_test", testStepperStep{OnStep: panicStep}))
+		state, err := sm.Create(ctx, testOptions{})
+		require.NoError(t, err)
+
+		// Паника не прерывает выполнение, стейт остается на шаге и может быть выполнен повторно
+		for attempt := 1; attempt <= 3; attempt++ {
+			res, executeErr, err := sm.Complete(ctx, state.ID)
+			require.NoError(t, err)
+
+			var panicErr *StepPanicError
+			require.ErrorAs(t, executeErr, &panicErr)
+			require.Equal(t, "boom", panicErr.Value)
+			require.Contains(t, string(panicErr.Stack), "panic_test.go")
+
+			require.Equal(t, NewStatus, res.Status)
+			require.Equal(t, "first", res.Step)
+			require.Equal(t, attempt, res.FailedAttempts)
+			require.Contains(t, *res.Error, "step panic: boom")
+		}
+
+		// Паника со стеком вызовов сохраняется в истории выполнения
+		infos, err := s.GetStepExecuteInfos(ctx, state.ID)
+		require.NoError(t, err)
+		require.Len(t, infos, 3)
+		require.NotNil(t, infos[0].Error)
+		require.Contains(t, *infos[0].Error, "step panic: boom")
+		require.Contains(t, *infos[0].Error, "panic_test.go")
+	})
+
+	t.Run("panic with error value", func(t *testing.T) {
+		t.Parallel()
+
+		panicValue := errors.New("nil map")
+		sm := NewService[int, any, any, string, string, testOptions](Config{}, memstore.NewStorage(), newStepTestRunner("panic_test", testStepperStep{
+			OnStep: func(context.Context, StepContext[int, any, any, string, string]) *StepResult[int, any, any, string] {
+				panic(panicValue)
+			},
+		}))
+		state, err := sm.Create(ctx, testOptions{})
+		require.NoError(t, err)
+
+		_, executeErr, err := sm.Complete(ctx, state.ID)
+		require.NoError(t, err)
+		require.ErrorIs(t, executeErr, panicValue)
+	})
+
+	t.Run("fail after repeated panics", func(t *testing.T) {
+		t.Parallel()
+
+		sm := NewService[int, any, any, string, string, testOptions](Config{}, memstore.NewStorage(), newStepTestRunner("panic_test", testStepperStep{
+			OnStep:      panicStep,
+			PanicPolicy: &PanicPolicy{MaxPanics: 2},
+		}))
+		state, err := sm.Create(ctx, testOptions{})
+		require.NoError(t, err)
+
+		res, executeErr, err := sm.Complete(ctx, state.ID)
+		require.NoError(t, err)
+		require.NotErrorIs(t, executeErr, ErrPanicAttemptsExhausted)
+		require.Equal(t, NewStatus, res.Status)
+
+		res, executeErr, err = sm.Complete(ctx, state.ID)
+		require.NoError(t, err)
+		require.ErrorIs(t, executeErr, ErrPanicAttemptsExhausted)
+		var failErr *StepFailedError
+		require.ErrorAs(t, executeErr, &failErr)
+		require.Equal(t, 2, failErr.Attempts)
+		require.Equal(t, FailedStatus, res.Status)
+		require.Equal(t, "first", res.FailedStep)
+	})
+
+	t.Run("panic policy ignores step errors", func(t *testing.T) {
+		t.Parallel()
+
+		stepErr := errors.New("external service unavailable")
+		sm := NewService[int, any, any, string, string, testOptions](Config{}, memstore.NewStorage(), newStepTestRunner("panic_test", testStepperStep{
+			OnStep: func(_ context.Context, stepContext StepContext[int, any, any, string, string]) *StepResult[int, any, any, string] {
+				return stepContext.Error(stepErr)
+			},
+			PanicPolicy: &PanicPolicy{MaxPanics: 1},
+		}))
+		state, err := sm.Create(ctx, testOptions{})
+		require.NoError(t, err)
+
+		res, executeErr, err := sm.Complete(ctx, state.ID)
+		require.NoError(t, err)
+		require.ErrorIs(t, executeErr, stepErr)
+		require.Equal(t, NewStatus, res.Status)
+	})
+
+	t.Run("only consecutive panics are counted", func(t *testing.T) {
+		t.Parallel()
+
+		stepErr := errors.New("external service unavailable")
+		// Результаты выполнений шага: false - ошибка, true - паника
+		panics := []bool{false, false, true, false, true, true}
+		var calls int
+		sm := NewService[int, any, any, string, string, testOptions](Config{}, memstore.NewStorage(), newStepTestRunner("panic_test", testStepperStep{
+			OnStep: func(_ context.Context, stepContext StepContext[int, any, any, string, string]) *StepResult[int, any, any, string] {
+				calls++
+				if panics[calls-1] {
+					panic("boom")
+				}
+				return stepContext.Error(stepErr)
+			},
+			PanicPolicy: &PanicPolicy{MaxPanics: 2},
+		}))
+		state, err := sm.Create(ctx, testOptions{})
+		require.NoError(t, err)
+
+		// Ошибки не учитываются, а ошибка после паники сбрасывает счетчик паник
+		for _, wantPanics := range []int{0, 0, 1, 0, 1} {
+			res, executeErr, err := sm.Complete(ctx, state.ID)
+			require.NoError(t, err)
+			require.NotErrorIs(t, executeErr, ErrPanicAttemptsExhausted)
+			require.Equal(t, NewStatus, res.Status)
+			require.Equal(t, wantPanics, res.PanicAttempts)
+		}
+
+		// Вторая паника подряд фейлит стейт
+		res, executeErr, err := sm.Complete(ctx, state.ID)
+		require.NoError(t, err)
+		require.ErrorIs(t, executeErr, ErrPanicAttemptsExhausted)
+		var failErr *StepFailedError
+		require.ErrorAs(t, executeErr, &failErr)
+		require.Equal(t, 6, failErr.Attempts)
+		require.Equal(t, FailedStatus, res.Status)
+		require.Zero(t, res.PanicAttempts)
+	})
+}
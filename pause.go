@@ -0,0 +1,175 @@
+package statemachine
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+
+	"github.com/kkiling/statemachine/storage"
+)
+
+// PauseInfo сведения о приостановке или возобновлении выполнения, сохраняются в истории приостановок
+type PauseInfo struct {
+	// By кто выполняет действие
+	By string
+	// Reason причина действия
+	Reason string
+}
+
+// PauseEvent запись истории приостановок и возобновлений
+type PauseEvent = storage.PauseEvent
+
+// Pause приостанавливает выполнение стейта: Complete и Worker не продвигают его до вызова Resume,
+// Complete возвращает ErrPaused. Уже выполняющийся шаг не прерывается.
+// Для стейта в терминальном статусе возвращает ErrInTerminalStatus
+func (i *StateMachine[DataT, FailDataT, MetaDataT, StepT, TypeT, CreateOptionsT]) Pause(
+	ctx context.Context,
+	stateID uuid.UUID,
+	info PauseInfo,
+) (*State[DataT, FailDataT, MetaDataT, StepT, TypeT], error) {
+	return i.setPaused(ctx, stateID, true, info)
+}
+
+// Resume возобновляет выполнение стейта, приостановленного вызовом Pause
+func (i *StateMachine[DataT, FailDataT, MetaDataT, StepT, TypeT, CreateOptionsT]) Resume(
+	ctx context.Context,
+	stateID uuid.UUID,
+	info PauseInfo,
+) (*State[DataT, FailDataT, MetaDataT, StepT, TypeT], error) {
+	return i.setPaused(ctx, stateID, false, info)
+}
+
+func (i *StateMachine[DataT, FailDataT, MetaDataT, StepT, TypeT, CreateOptionsT]) setPaused(
+	ctx context.Context,
+	stateID uuid.UUID,
+	paused bool,
+	info PauseInfo,
+) (res *State[DataT, FailDataT, MetaDataT, StepT, TypeT], err error) {
+	err = retryOnConflict(ctx, func() error {
+		return i.storage.RunTransaction(ctx, func(ctxTx context.Context) error {
+			findState, terr := i.GetStateByID(ctxTx, stateID)
+			if terr != nil {
+				return fmt.Errorf("GetStateByID: %w", terr)
+			}
+			if findState == nil {
+				return fmt.Errorf("state not found: %w", ErrNotFound)
+			}
+			if isTerminalStatus(findState.Status) {
+				return ErrInTerminalStatus
+			}
+
+			res = findState
+			// Повторная приостановка или возобновление ничего не меняет и не попадает в историю
+			if findState.Paused == paused {
+				return nil
+			}
+
+			// Признак изменяется, только если стейт не изменился с момента чтения
+			terr = i.storage.SetStatePaused(ctxTx, stateID, paused, findState.Version)
+			if errors.Is(terr, storage.ErrConcurrentModification) {
+				return ErrConcurrentModification
+			}
+			if terr != nil {
+				return fmt.Errorf("storage.SetStatePaused: %w", terr)
+			}
+			terr = i.storage.SavePauseEvent(ctxTx, storage.PauseEvent{
+				Type:      string(findState.Type),
+				StateID:   &stateID,
+				Paused:    paused,
+				CreatedAt: i.clock.Now(),
+				CreatedBy: info.By,
+				Reason:    info.Reason,
+			})
+			if terr != nil {
+				return fmt.Errorf("storage.SavePauseEvent: %w", terr)
+			}
+
+			res.Paused = paused
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
+
+// PauseType приостанавливает выполнение всех стейтов типа стейт машины, в том числе созданных после вызова.
+// Приостановка хранится в базе и действует на все экземпляры стейт машины до вызова ResumeType
+func (i *StateMachine[DataT, FailDataT, MetaDataT, StepT, TypeT, CreateOptionsT]) PauseType(
+	ctx context.Context,
+	info PauseInfo,
+) error {
+	return i.setTypePaused(ctx, true, info)
+}
+
+// ResumeType возобновляет выполнение стейтов типа стейт машины
+func (i *StateMachine[DataT, FailDataT, MetaDataT, StepT, TypeT, CreateOptionsT]) ResumeType(
+	ctx context.Context,
+	info PauseInfo,
+) error {
+	return i.setTypePaused(ctx, false, info)
+}
+
+// IsTypePaused приостановлено ли выполнение стейтов типа стейт машины
+func (i *StateMachine[DataT, FailDataT, MetaDataT, StepT, TypeT, CreateOptionsT]) IsTypePaused(ctx context.Context) (bool, error) {
+	paused, err := i.storage.IsTypePaused(ctx, string(i.runner.Type()))
+	if err != nil {
+		return false, fmt.Errorf("storage.IsTypePaused: %w", err)
+	}
+	return paused, nil
+}
+
+// GetPauseEvents история приостановок и возобновлений стейтов типа стейт машины и всего типа
+func (i *StateMachine[DataT, FailDataT, MetaDataT, StepT, TypeT, CreateOptionsT]) GetPauseEvents(ctx context.Context) ([]PauseEvent, error) {
+	events, err := i.storage.GetPauseEvents(ctx, string(i.runner.Type()))
+	if err != nil {
+		return nil, fmt.Errorf("storage.GetPauseEvents: %w", err)
+	}
+	return events, nil
+}
+
+func (i *StateMachine[DataT, FailDataT, MetaDataT, StepT, TypeT, CreateOptionsT]) setTypePaused(
+	ctx context.Context,
+	paused bool,
+	info PauseInfo,
+) error {
+	stateType := string(i.runner.Type())
+
+	return i.storage.RunTransaction(ctx, func(ctxTx context.Context) error {
+		current, err := i.storage.IsTypePaused(ctxTx, stateType)
+		if err != nil {
+			return fmt.Errorf("storage.IsTypePaused: %w", err)
+		}
+		// Повторная приостановка или возобновление ничего не меняет и не попадает в историю
+		if current == paused {
+			return nil
+		}
+
+		now := i.clock.Now()
+		if paused {
+			if err = i.storage.PauseType(ctxTx, stateType, now); err != nil {
+				return fmt.Errorf("storage.PauseType: %w", err)
+			}
+		} else {
+			if err = i.storage.ResumeType(ctxTx, stateType); err != nil {
+				return fmt.Errorf("storage.ResumeType: %w", err)
+			}
+		}
+
+		err = i.storage.SavePauseEvent(ctxTx, storage.PauseEvent{
+			Type:      stateType,
+			Paused:    paused,
+			CreatedAt: now,
+			CreatedBy: info.By,
+			Reason:    info.Reason,
+		})
+		if err != nil {
+			return fmt.Errorf("storage.SavePauseEvent: %w", err)
+		}
+		return nil
+	})
+}
@@ -0,0 +1,149 @@
+package statemachine
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/kkiling/statemachine/memstore"
+)
+
+func TestStateMachine_Pause(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	info := PauseInfo{By: "operator", Reason: "incident"}
+
+	newService := func() *StateMachine[int, any, any, string, string, testOptions] {
+		return NewService[int, any, any, string, string, testOptions](Config{}, memstore.NewStorage(), newStepTestRunner("pause_test", testStepperStep{
+			OnStep: func(_ context.Context, stepContext StepContext[int, any, any, string, string]) *StepResult[int, any, any, string] {
+				return stepContext.Complete()
+			},
+		}))
+	}
+
+	t.Run("pause and resume state", func(t *testing.T) {
+		t.Parallel()
+
+		sm := newService()
+		state, err := sm.Create(ctx, testOptions{})
+		require.NoError(t, err)
+
+		pausedState, err := sm.Pause(ctx, state.ID, info)
+		require.NoError(t, err)
+		require.True(t, pausedState.Paused)
+		// Повторная приостановка не попадает в историю
+		_, err = sm.Pause(ctx, state.ID, info)
+		require.NoError(t, err)
+
+		_, _, err = sm.Complete(ctx, state.ID)
+		require.ErrorIs(t, err, ErrPaused)
+
+		resumedState, err := sm.Resume(ctx, state.ID, PauseInfo{By: "admin", Reason: "resolved"})
+		require.NoError(t, err)
+		require.False(t, resumedState.Paused)
+
+		completeState, _, err := sm.Complete(ctx, state.ID)
+		require.NoError(t, err)
+		require.Equal(t, CompletedStatus, completeState.Status)
+
+		events, err := sm.GetPauseEvents(ctx)
+		require.NoError(t, err)
+		require.Len(t, events, 2)
+		require.Equal(t, &state.ID, events[0].StateID)
+		require.True(t, events[0].Paused)
+		require.Equal(t, "operator", events[0].CreatedBy)
+		require.Equal(t, "incident", events[0].Reason)
+		require.Equal(t, &state.ID, events[1].StateID)
+		require.False(t, events[1].Paused)
+		require.Equal(t, "admin", events[1].CreatedBy)
+		require.Equal(t, "resolved", events[1].Reason)
+	})
+
+	t.Run("state in terminal status", func(t *testing.T) {
+		t.Parallel()
+
+		sm := newService()
+		state, err := sm.Create(ctx, testOptions{})
+		require.NoError(t, err)
+		_, _, err = sm.Complete(ctx, state.ID)
+		require.NoError(t, err)
+
+		_, err = sm.Pause(ctx, state.ID, info)
+		require.ErrorIs(t, err, ErrInTerminalStatus)
+	})
+
+	t.Run("pause and resume type", func(t *testing.T) {
+		t.Parallel()
+
+		sm := newService()
+		state, err := sm.Create(ctx, testOptions{})
+		require.NoError(t, err)
+
+		require.NoError(t, sm.PauseType(ctx, info))
+		paused, err := sm.IsTypePaused(ctx)
+		require.NoError(t, err)
+		require.True(t, paused)
+
+		_, _, err = sm.Complete(ctx, state.ID)
+		require.ErrorIs(t, err, ErrPaused)
+
+		require.NoError(t, sm.ResumeType(ctx, info))
+		_, _, err = sm.Complete(ctx, state.ID)
+		require.NoError(t, err)
+
+		events, err := sm.GetPauseEvents(ctx)
+		require.NoError(t, err)
+		require.Len(t, events, 2)
+		require.Nil(t, events[0].StateID)
+		require.True(t, events[0].Paused)
+		require.Nil(t, events[1].StateID)
+		require.False(t, events[1].Paused)
+	})
+
+	t.Run("pause state between chained steps", func(t *testing.T) {
+		t.Parallel()
+
+		var sm *StateMachine[int, any, any, string, string, testOptions]
+		executed := make([]string, 0, 3)
+		step := func(name, next string) StepFunc[int, any, any, string, string] {
+			return func(ctx context.Context, stepContext StepContext[int, any, any, string, string]) *StepResult[int, any, any, string] {
+				executed = append(executed, name)
+				if name == "b" {
+					// Стейт приостанавливается, пока выполняется цепочка шагов
+					_, err := sm.Pause(ctx, stepContext.State.ID, info)
+					require.NoError(t, err)
+				}
+				if next == "" {
+					return stepContext.Complete()
+				}
+				return stepContext.Next(next)
+			}
+		}
+		sm = NewService[int, any, any, string, string, testOptions](Config{}, memstore.NewStorage(), newTestRunner("pause_test", "a", map[string]testStepperStep{
+			"a": {OnStep: step("a", "b")},
+			"b": {OnStep: step("b", "c")},
+			"c": {OnStep: step("c", "")},
+		}))
+		state, err := sm.Create(ctx, testOptions{})
+		require.NoError(t, err)
+
+		_, _, err = sm.Complete(ctx, state.ID)
+		require.ErrorIs(t, err, ErrPaused)
+		require.Equal(t, []string{"a", "b"}, executed)
+
+		findState, err := sm.GetStateByID(ctx, state.ID)
+		require.NoError(t, err)
+		require.True(t, findState.Paused)
+		require.Equal(t, "c", findState.Step)
+		require.Equal(t, InProgressStatus, findState.Status)
+
+		_, err = sm.Resume(ctx, state.ID, info)
+		require.NoError(t, err)
+		completeState, _, err := sm.Complete(ctx, state.ID)
+		require.NoError(t, err)
+		require.Equal(t, CompletedStatus, completeState.Status)
+		require.Equal(t, []string{"a", "b", "c"}, executed)
+	})
+}
@@ -6,14 +6,14 @@ VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10);
 
 -- name: GetStateByIdempotencyKey :one
 SELECT id, idempotency_key, created_at, updated_at,
-       status, step, type, data, fail_data, meta_data, error
+       status, step, type, data, fail_data, meta_data, error, version, next_run_at, failed_attempts, step_attempts, paused, failed_step, panic_attempts
 FROM state
 WHERE idempotency_key = $1
 LIMIT 1;
 
 -- name: GetStateByID :one
 SELECT id, idempotency_key, created_at, updated_at,
-       status, step, type, data, fail_data, meta_data, error
+       status, step, type, data, fail_data, meta_data, error, version, next_run_at, failed_attempts, step_attempts, paused, failed_step, panic_attempts
 FROM state
 WHERE id = $1
 LIMIT 1;
@@ -27,22 +27,117 @@ SET
     data = $4,
     fail_data = $5,
     meta_data = $6,
-    error = $7
+    error = $7,
+    next_run_at = $10,
+    failed_attempts = $11,
+    step_attempts = $12,
+    failed_step = $13,
+    panic_attempts = $14,
+    version = version + 1
 WHERE id = $8
+  AND version = $9
 RETURNING id;
 ------------------------------------------------------------------------------------------------------------------------
 
 -- name: SaveStepExecuteInfo :exec
 INSERT INTO step_execute_info (
     state_id, start_executed_at, complete_executed_at,
-    error, preview_step, next_step
-) VALUES ($1, $2, $3, $4, $5, $6);
+    error, preview_step, next_step, reason, data_before, data_after
+) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9);
 
 -- name: GetStepExecuteInfos :many
 SELECT
     state_id, start_executed_at, complete_executed_at,
-    error, preview_step, next_step
+    error, preview_step, next_step, reason, data_before, data_after
 FROM step_execute_info
 WHERE state_id = $1
-ORDER BY start_executed_at;
+ORDER BY start_executed_at, id;
 
+
+-- name: ClaimReadyStates :many
+UPDATE state
+SET lease_owner = @lease_owner::text,
+    lease_expires_at = @lease_expires_at::timestamptz
+WHERE id IN (
+    SELECT s.id
+    FROM state s
+    WHERE s.type = @type
+      AND s.status = ANY(@statuses::int[])
+      AND (s.lease_expires_at IS NULL OR s.lease_expires_at <= @acquired_at::timestamptz)
+      AND (s.next_run_at IS NULL OR s.next_run_at <= @acquired_at::timestamptz)
+      AND NOT s.paused
+      AND NOT EXISTS (SELECT 1 FROM state_type_pause p WHERE p.type = s.type)
+    ORDER BY s.updated_at, s.id
+    LIMIT @limit_count
+    FOR UPDATE SKIP LOCKED
+)
+RETURNING id, idempotency_key, created_at, updated_at,
+          status, step, type, data, fail_data, meta_data, error, version, next_run_at, failed_attempts, step_attempts, paused, failed_step, panic_attempts;
+
+-- name: ClaimState :one
+UPDATE state
+SET lease_owner = @lease_owner::text,
+    lease_expires_at = @lease_expires_at::timestamptz
+WHERE id = @id
+  AND (lease_expires_at IS NULL OR lease_expires_at <= @acquired_at::timestamptz)
+RETURNING id, idempotency_key, created_at, updated_at,
+          status, step, type, data, fail_data, meta_data, error, version, next_run_at, failed_attempts, step_attempts, paused, failed_step, panic_attempts;
+
+-- name: ReleaseState :exec
+UPDATE state
+SET lease_owner = NULL,
+    lease_expires_at = NULL
+WHERE id = @id
+  AND lease_owner = @lease_owner::text;
+
+-- name: RenewLease :one
+UPDATE state
+SET lease_expires_at = @lease_expires_at::timestamptz
+WHERE id = @id
+  AND lease_owner = @lease_owner::text
+RETURNING id;
+
+-- name: SetStatePaused :one
+UPDATE state
+SET paused = @paused
+WHERE id = @id AND version = @version
+RETURNING id;
+
+-- name: PauseType :exec
+INSERT INTO state_type_pause (type, paused_at)
+VALUES ($1, $2)
+ON CONFLICT (type) DO NOTHING;
+
+-- name: ResumeType :exec
+DELETE FROM state_type_pause
+WHERE type = $1;
+
+-- name: IsTypePaused :one
+SELECT EXISTS (SELECT 1 FROM state_type_pause WHERE type = $1);
+
+-- name: SavePauseEvent :exec
+INSERT INTO pause_event (type, state_id, paused, created_at, created_by, reason)
+VALUES ($1, $2, $3, $4, $5, $6);
+
+-- name: GetPauseEvents :many
+SELECT type, state_id, paused, created_at, created_by, reason
+FROM pause_event
+WHERE type = $1
+ORDER BY created_at, id;
+
+-- name: ListStates :many
+SELECT id, idempotency_key, created_at, updated_at,
+       status, step, type, data, fail_data, meta_data, error, version, next_run_at, failed_attempts, step_attempts, paused, failed_step, panic_attempts
+FROM state
+WHERE (@type::text = '' OR type = @type)
+  AND (cardinality(@statuses::int[]) = 0 OR status = ANY(@statuses::int[]))
+  AND (cardinality(@steps::text[]) = 0 OR step = ANY(@steps::text[]))
+  AND (sqlc.narg('created_from')::timestamptz IS NULL OR created_at >= sqlc.narg('created_from')::timestamptz)
+  AND (sqlc.narg('created_to')::timestamptz IS NULL OR created_at < sqlc.narg('created_to')::timestamptz)
+  AND (sqlc.narg('updated_from')::timestamptz IS NULL OR updated_at >= sqlc.narg('updated_from')::timestamptz)
+  AND (sqlc.narg('updated_to')::timestamptz IS NULL OR updated_at < sqlc.narg('updated_to')::timestamptz)
+  AND (sqlc.narg('has_error')::boolean IS NULL OR (error IS NOT NULL) = sqlc.narg('has_error')::boolean)
+  AND (sqlc.narg('after_created_at')::timestamptz IS NULL
+    OR (created_at, id) > (sqlc.narg('after_created_at')::timestamptz, @after_id::uuid))
+ORDER BY created_at, id
+LIMIT @limit_count;
@@ -0,0 +1,122 @@
+-- name: CreateState :exec
+INSERT INTO state (id, idempotency_key, created_at, updated_at,
+                   status, step, type, data, fail_data, meta_data)
+VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?);
+
+-- name: GetStateByIdempotencyKey :one
+SELECT id, idempotency_key, created_at, updated_at,
+       status, step, type, data, fail_data, meta_data, error, version, next_run_at, failed_attempts, step_attempts, paused, failed_step, panic_attempts
+FROM state
+WHERE idempotency_key = ?
+LIMIT 1;
+
+-- name: GetStateByID :one
+SELECT id, idempotency_key, created_at, updated_at,
+       status, step, type, data, fail_data, meta_data, error, version, next_run_at, failed_attempts, step_attempts, paused, failed_step, panic_attempts
+FROM state
+WHERE id = ?
+LIMIT 1;
+
+-- name: UpdateState :one
+UPDATE state
+SET
+    updated_at = @updated_at,
+    status = @status,
+    step = @step,
+    data = @data,
+    fail_data = @fail_data,
+    meta_data = @meta_data,
+    error = @error,
+    next_run_at = @next_run_at,
+    failed_attempts = @failed_attempts,
+    step_attempts = @step_attempts,
+    failed_step = @failed_step,
+    panic_attempts = @panic_attempts,
+    version = version + 1
+WHERE id = @id
+  AND version = @version
+RETURNING id;
+
+-- name: SaveStepExecuteInfo :exec
+INSERT INTO step_execute_info (
+    state_id, start_executed_at, complete_executed_at,
+    error, preview_step, next_step, reason, data_before, data_after
+) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?);
+
+-- name: GetStepExecuteInfos :many
+SELECT
+    state_id, start_executed_at, complete_executed_at,
+    error, preview_step, next_step, reason, data_before, data_after
+FROM step_execute_info
+WHERE state_id = ?
+ORDER BY start_executed_at, id;
+
+-- name: ClaimReadyStates :many
+UPDATE state
+SET lease_owner = ?,
+    lease_expires_at = ?
+WHERE id IN (
+    SELECT s.id
+    FROM state s
+    WHERE s.type = ?
+      AND s.status IN (sqlc.slice('statuses'))
+      AND (s.lease_expires_at IS NULL OR s.lease_expires_at <= ?)
+      AND (s.next_run_at IS NULL OR s.next_run_at <= ?)
+      AND NOT s.paused
+      AND NOT EXISTS (SELECT 1 FROM state_type_pause p WHERE p.type = s.type)
+    ORDER BY s.updated_at, s.id
+    LIMIT ?
+)
+RETURNING id, idempotency_key, created_at, updated_at,
+          status, step, type, data, fail_data, meta_data, error, version, next_run_at, failed_attempts, step_attempts, paused, failed_step, panic_attempts;
+
+-- name: ClaimState :one
+UPDATE state
+SET lease_owner = @lease_owner,
+    lease_expires_at = @lease_expires_at
+WHERE id = @id
+  AND (lease_expires_at IS NULL OR lease_expires_at <= @acquired_at)
+RETURNING id, idempotency_key, created_at, updated_at,
+          status, step, type, data, fail_data, meta_data, error, version, next_run_at, failed_attempts, step_attempts, paused, failed_step, panic_attempts;
+
+-- name: ReleaseState :exec
+UPDATE state
+SET lease_owner = NULL,
+    lease_expires_at = NULL
+WHERE id = @id
+  AND lease_owner = @lease_owner;
+
+-- name: RenewLease :one
+UPDATE state
+SET lease_expires_at = @lease_expires_at
+WHERE id = @id
+  AND lease_owner = @lease_owner
+RETURNING id;
+
+-- name: SetStatePaused :one
+UPDATE state
+SET paused = @paused
+WHERE id = @id AND version = @version
+RETURNING id;
+
+-- name: PauseType :exec
+INSERT INTO state_type_pause (type, paused_at)
+VALUES (?, ?)
+ON CONFLICT (type) DO NOTHING;
+
+-- name: ResumeType :exec
+DELETE FROM state_type_pause
+WHERE type = ?;
+
+-- name: IsTypePaused :one
+SELECT EXISTS (SELECT 1 FROM state_type_pause WHERE type = ?);
+
+-- name: SavePauseEvent :exec
+INSERT INTO pause_event (type, state_id, paused, created_at, created_by, reason)
+VALUES (?, ?, ?, ?, ?, ?);
+
+-- name: GetPauseEvents :many
+SELECT type, state_id, paused, created_at, created_by, reason
+FROM pause_event
+WHERE type = ?
+ORDER BY created_at, id;
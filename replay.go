@@ -0,0 +1,310 @@
+package statemachine
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// StepOutcome исход выполнения шага
+type StepOutcome string
+
+const (
+	// StepOutcomeEmpty шаг не продвинул стейт и будет выполнен еще раз (Empty, RetryAfter, ScheduleAt)
+	StepOutcomeEmpty StepOutcome = "empty"
+	// StepOutcomeError шаг вернул ошибку
+	StepOutcomeError StepOutcome = "error"
+	// StepOutcomeNext стейт перешел на следующий шаг
+	StepOutcomeNext StepOutcome = "next"
+	// StepOutcomeFail стейт переведен в статус фейла
+	StepOutcomeFail StepOutcome = "fail"
+	// StepOutcomeComplete стейт переведен в статус успешного завершения
+	StepOutcomeComplete StepOutcome = "complete"
+)
+
+func stepOutcome(state stepState) StepOutcome {
+	switch state {
+	case errorStepState:
+		return StepOutcomeError
+	case nextStepState:
+		return StepOutcomeNext
+	case failStepState:
+		return StepOutcomeFail
+	case completeStepState:
+		return StepOutcomeComplete
+	default:
+		return StepOutcomeEmpty
+	}
+}
+
+// ReplayResult результат выполнения шага
+type ReplayResult[StepT ~string] struct {
+	// Outcome исход выполнения шага
+	Outcome StepOutcome
+	// NextStep шаг, на который перешел стейт (nil - перехода не было)
+	NextStep *StepT
+	// Data данные стейта (JSON) после выполнения шага
+	Data json.RawMessage
+}
+
+// ReplayStep сравнение записанного и повторного выполнения шага
+type ReplayStep[StepT ~string] struct {
+	// Index номер записи в истории выполнения
+	Index int
+	// Step выполненный шаг
+	Step StepT
+	// StartedAt время начала записанного выполнения, используется как время повторного выполнения
+	StartedAt time.Time
+	// Recorded результат записанного выполнения
+	Recorded ReplayResult[StepT]
+	// Replayed результат повторного выполнения
+	Replayed ReplayResult[StepT]
+	// OutcomeChanged исход выполнения отличается
+	OutcomeChanged bool
+	// NextStepChanged следующий шаг отличается
+	NextStepChanged bool
+	// DataChanged данные после выполнения отличаются
+	DataChanged bool
+}
+
+// HasDiff результат повторного выполнения отличается от записанного
+func (s ReplayStep[StepT]) HasDiff() bool {
+	return s.OutcomeChanged || s.NextStepChanged || s.DataChanged
+}
+
+// String описание расхождений повторного выполнения шага
+func (s ReplayStep[StepT]) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "history[%d] step %q:", s.Index, s.Step)
+	if s.OutcomeChanged {
+		fmt.Fprintf(&b, " outcome %s -> %s;", s.Recorded.Outcome, s.Replayed.Outcome)
+	}
+	if s.NextStepChanged {
+		fmt.Fprintf(&b, " next step %s -> %s;", formatStep(s.Recorded.NextStep), formatStep(s.Replayed.NextStep))
+	}
+	if s.DataChanged {
+		fmt.Fprintf(&b, " data %s -> %s;", s.Recorded.Data, s.Replayed.Data)
+	}
+	return b.String()
+}
+
+func formatStep[StepT ~string](step *StepT) string {
+	if step == nil {
+		return "<nil>"
+	}
+	return fmt.Sprintf("%q", *step)
+}
+
+// ReplayReport отчет о повторном выполнении шагов по истории выполнения стейта
+type ReplayReport[StepT ~string] struct {
+	// Steps повторно выполненные шаги в порядке истории выполнения
+	Steps []ReplayStep[StepT]
+	// Skipped количество выполнений шагов, пропущенных из-за отсутствия снимка данных
+	// или шага в StepRegistration.Steps
+	Skipped int
+}
+
+// Diffs шаги, результат повторного выполнения которых отличается от записанного
+func (r *ReplayReport[StepT]) Diffs() []ReplayStep[StepT] {
+	var res []ReplayStep[StepT]
+	for _, step := range r.Steps {
+		if step.HasDiff() {
+			res = append(res, step)
+		}
+	}
+	return res
+}
+
+// Replay повторно выполняет шаги стейта по его истории выполнения и сравнивает результат с записанным,
+// подробнее в ReplayHistory. Для стейта без снимков данных в истории возвращает ErrNoDataSnapshots
+func (i *StateMachine[DataT, FailDataT, MetaDataT, StepT, TypeT, CreateOptionsT]) Replay(
+	ctx context.Context,
+	stateID uuid.UUID,
+) (*ReplayReport[StepT], error) {
+	findState, err := i.GetStateByID(ctx, stateID)
+	if err != nil {
+		return nil, fmt.Errorf("GetStateByID: %w", err)
+	}
+	if findState == nil {
+		return nil, fmt.Errorf("state not found: %w", ErrNotFound)
+	}
+
+	history, err := i.History(ctx, stateID)
+	if err != nil {
+		return nil, fmt.Errorf("History: %w", err)
+	}
+
+	return i.ReplayHistory(ctx, *findState, history)
+}
+
+// ReplayHistory повторно выполняет зарегистрированные функции шагов по истории выполнения стейта state
+// и сравнивает исход, следующий шаг и данные с записанными в истории. Для воспроизведения нужны снимки данных
+// (StepRegistration.DataSnapshots). Шаги выполняются в изолированном StepContext со временем начала
+// записанного выполнения, результат не сохраняется. Побочные эффекты функций шагов (вызовы внешних систем)
+// не изолируются, для воспроизведения раннер должен использовать заглушки. Опции Complete не сохраняются
+// в истории, шаги выполняются без них. Действия оператора (отмена, повтор, переход на шаг) не воспроизводятся.
+// Если в истории нет ни одного снимка данных возвращает ErrNoDataSnapshots
+func (i *StateMachine[DataT, FailDataT, MetaDataT, StepT, TypeT, CreateOptionsT]) ReplayHistory(
+	ctx context.Context,
+	state State[DataT, FailDataT, MetaDataT, StepT, TypeT],
+	history []StepExecution[StepT],
+) (*ReplayReport[StepT], error) {
+	steps := i.runner.StepRegistration(StepRegistrationParams{}).Steps
+	report := &ReplayReport[StepT]{}
+
+	// Входной стейт восстанавливается по истории: статус, время перехода на шаг и счетчики попыток
+	input := state
+	input.Status = NewStatus
+	input.UpdatedAt = state.CreatedAt
+	input.Error = nil
+	input.NextRunAt = nil
+	input.FailedAttempts = 0
+	input.StepAttempts = 0
+	input.PanicAttempts = 0
+	input.FailedStep = ""
+	input.Paused = false
+
+	for index, item := range history {
+		if item.Reason == nil {
+			stepInfo, ok := steps[item.PreviousStep]
+			if item.DataBefore == nil || !ok {
+				report.Skipped++
+			} else {
+				input.Step = item.PreviousStep
+				step, err := replayStep(ctx, stepInfo, input, item)
+				if err != nil {
+					return nil, fmt.Errorf("history[%d]: %w", index, err)
+				}
+				step.Index = index
+				step.Recorded.Outcome = recordedOutcome(state, history, index)
+				step.OutcomeChanged = step.Recorded.Outcome != step.Replayed.Outcome
+				report.Steps = append(report.Steps, *step)
+			}
+		}
+
+		switch {
+		case item.NextStep != nil:
+			input.Status = InProgressStatus
+			input.UpdatedAt = item.CompletedAt
+			input.FailedAttempts = 0
+			input.StepAttempts = 0
+			input.PanicAttempts = 0
+		case item.Reason == nil:
+			input.StepAttempts++
+			if item.Error != nil {
+				input.FailedAttempts++
+			}
+			if item.Error != nil && strings.HasPrefix(*item.Error, stepPanicPrefix) {
+				input.PanicAttempts++
+			} else {
+				input.PanicAttempts = 0
+			}
+		}
+	}
+
+	if len(report.Steps) == 0 && report.Skipped > 0 {
+		return nil, ErrNoDataSnapshots
+	}
+
+	return report, nil
+}
+
+// replayStep выполняет шаг над данными из снимка записи истории item
+func replayStep[DataT any, FailDataT any, MetaDataT any, StepT ~string, TypeT ~string](
+	ctx context.Context,
+	stepInfo Step[DataT, FailDataT, MetaDataT, StepT, TypeT],
+	input State[DataT, FailDataT, MetaDataT, StepT, TypeT],
+	item StepExecution[StepT],
+) (*ReplayStep[StepT], error) {
+	var data DataT
+	if err := json.Unmarshal(item.DataBefore, &data); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal data snapshot: %w", err)
+	}
+	input.Data = data
+
+	stepResult := callStep(ctx, stepInfo.OnStep, StepContext[DataT, FailDataT, MetaDataT, StepT, TypeT]{
+		State:               input,
+		completeOptionsType: stepInfo.OptionsType,
+		startExecutedAt:     item.StartedAt,
+	})
+
+	if stepResult.newData != nil {
+		data = *stepResult.newData
+	}
+	replayedData, err := marshalDataSnapshot(data)
+	if err != nil {
+		return nil, err
+	}
+
+	res := &ReplayStep[StepT]{
+		Step:      item.PreviousStep,
+		StartedAt: item.StartedAt,
+		Recorded: ReplayResult[StepT]{
+			NextStep: item.NextStep,
+			Data:     item.DataAfter,
+		},
+		Replayed: ReplayResult[StepT]{
+			Outcome: stepOutcome(stepResult.state),
+			Data:    replayedData,
+		},
+	}
+	if stepResult.state == nextStepState {
+		res.Replayed.NextStep = stepResult.nextStatus
+	}
+	res.NextStepChanged = !reflect.DeepEqual(res.Recorded.NextStep, res.Replayed.NextStep)
+	res.DataChanged = !jsonEqual(res.Recorded.Data, res.Replayed.Data)
+
+	return res, nil
+}
+
+// recordedOutcome восстанавливает исход записанного выполнения шага по записи истории,
+// следующей за ним, и текущему статусу стейта
+func recordedOutcome[DataT any, FailDataT any, MetaDataT any, StepT ~string, TypeT ~string](
+	state State[DataT, FailDataT, MetaDataT, StepT, TypeT],
+	history []StepExecution[StepT],
+	index int,
+) StepOutcome {
+	item := history[index]
+	switch {
+	case item.NextStep != nil:
+		return StepOutcomeNext
+	case item.Error != nil:
+		return StepOutcomeError
+	}
+
+	if index+1 < len(history) {
+		next := history[index+1]
+		// После фейла стейт может быть перезапущен только оператором
+		if next.Reason != nil && next.PreviousStep == "" {
+			return StepOutcomeFail
+		}
+		return StepOutcomeEmpty
+	}
+
+	switch state.Status {
+	case CompletedStatus:
+		return StepOutcomeComplete
+	case FailedStatus:
+		return StepOutcomeFail
+	default:
+		return StepOutcomeEmpty
+	}
+}
+
+// jsonEqual сравнение JSON без учета форматирования
+func jsonEqual(a, b []byte) bool {
+	if len(a) == 0 || len(b) == 0 {
+		return len(a) == len(b)
+	}
+	var av, bv any
+	if json.Unmarshal(a, &av) != nil || json.Unmarshal(b, &bv) != nil {
+		return bytes.Equal(a, b)
+	}
+	return reflect.DeepEqual(av, bv)
+}
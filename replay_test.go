@@ -0,0 +1,117 @@
+package statemachine
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/samber/lo"
+	"github.com/stretchr/testify/require"
+
+	"github.com/kkiling/statemachine/memstore"
+)
+
+func TestStateMachine_Replay(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	// newRecordedState стейт с историей: first -> second, фейл на second, повтор оператором, завершение
+	newRecordedState := func(t *testing.T, runner testRunner) (*memstore.Storage, uuid.UUID) {
+		s := memstore.NewStorage()
+		sm := NewService[int, any, any, string, string, testOptions](Config{}, s, runner)
+
+		state, err := sm.Create(ctx, testOptions{})
+		require.NoError(t, err)
+		_, _, err = sm.Complete(ctx, state.ID)
+		require.NoError(t, err)
+		_, err = sm.Retry(ctx, state.ID, "", RetryOptions[int]{Data: lo.ToPtr(1), Reason: "data fixed"})
+		require.NoError(t, err)
+		_, _, err = sm.Complete(ctx, state.ID)
+		require.NoError(t, err)
+
+		return s, state.ID
+	}
+
+	t.Run("same code", func(t *testing.T) {
+		t.Parallel()
+
+		s, stateID := newRecordedState(t, newRetryFailedTestRunner().withDataSnapshots())
+		sm := NewService[int, any, any, string, string, testOptions](Config{}, s, newRetryFailedTestRunner().withDataSnapshots())
+
+		report, err := sm.Replay(ctx, stateID)
+		require.NoError(t, err)
+		require.Empty(t, report.Diffs())
+		require.Equal(t, 0, report.Skipped)
+		// Повтор оператором не воспроизводится
+		require.Equal(t, []StepOutcome{StepOutcomeNext, StepOutcomeFail, StepOutcomeComplete},
+			lo.Map(report.Steps, func(item ReplayStep[string], _ int) StepOutcome {
+				return item.Recorded.Outcome
+			}))
+		require.Equal(t, []int{0, 1, 3}, lo.Map(report.Steps, func(item ReplayStep[string], _ int) int {
+			return item.Index
+		}))
+
+		// Воспроизведение не изменяет стейт
+		history, err := sm.History(ctx, stateID)
+		require.NoError(t, err)
+		require.Len(t, history, 4)
+	})
+
+	t.Run("changed step", func(t *testing.T) {
+		t.Parallel()
+
+		s, stateID := newRecordedState(t, newRetryFailedTestRunner().withDataSnapshots())
+		// Изменена логика шага second
+		runner := newRetryFailedTestRunner().withDataSnapshots().withStep("second", testStepperStep{
+			OnStep: func(_ context.Context, stepContext testStepperContext) *testStepperResult {
+				return stepContext.Complete().WithData(stepContext.State.Data + 10)
+			},
+		})
+		sm := NewService[int, any, any, string, string, testOptions](Config{}, s, runner)
+
+		report, err := sm.Replay(ctx, stateID)
+		require.NoError(t, err)
+
+		diffs := report.Diffs()
+		require.Len(t, diffs, 2)
+
+		require.Equal(t, 1, diffs[0].Index)
+		require.Equal(t, "second", diffs[0].Step)
+		require.True(t, diffs[0].OutcomeChanged)
+		require.Equal(t, StepOutcomeFail, diffs[0].Recorded.Outcome)
+		require.Equal(t, StepOutcomeComplete, diffs[0].Replayed.Outcome)
+		require.False(t, diffs[0].NextStepChanged)
+		require.True(t, diffs[0].DataChanged)
+		require.JSONEq(t, "10", string(diffs[0].Replayed.Data))
+
+		require.Equal(t, 3, diffs[1].Index)
+		require.False(t, diffs[1].OutcomeChanged)
+		require.True(t, diffs[1].DataChanged)
+		require.JSONEq(t, "1", string(diffs[1].Recorded.Data))
+		require.JSONEq(t, "11", string(diffs[1].Replayed.Data))
+		require.Equal(t, `history[3] step "second": data 1 -> 11;`, diffs[1].String())
+	})
+
+	t.Run("no data snapshots", func(t *testing.T) {
+		t.Parallel()
+
+		s := memstore.NewStorage()
+		sm := NewService[int, any, any, string, string, testOptions](Config{}, s, newRetryFailedTestRunner())
+		state, err := sm.Create(ctx, testOptions{})
+		require.NoError(t, err)
+		_, _, err = sm.Complete(ctx, state.ID)
+		require.NoError(t, err)
+
+		_, err = sm.Replay(ctx, state.ID)
+		require.ErrorIs(t, err, ErrNoDataSnapshots)
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		t.Parallel()
+
+		sm := NewService[int, any, any, string, string, testOptions](Config{}, memstore.NewStorage(), newRetryFailedTestRunner().withDataSnapshots())
+		_, err := sm.Replay(ctx, uuid.New())
+		require.ErrorIs(t, err, ErrNotFound)
+	})
+}
@@ -0,0 +1,65 @@
+// Package replaytest помощники тестов, проверяющих изменения функций шагов на записанной истории выполнения.
+//
+// Пример использования:
+//
+//	func TestStepsReplay(t *testing.T) {
+//		// История выгружена из рабочего окружения через StateMachine.GetStateByID и StateMachine.History
+//		state, history := loadRecorded(t)
+//		sm := statemachine.NewService(statemachine.Config{}, memstore.NewStorage(), newRunner())
+//		replaytest.RequireHistory(t, sm, state, history)
+//	}
+package replaytest
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/samber/lo"
+	"github.com/stretchr/testify/require"
+
+	"github.com/kkiling/statemachine"
+)
+
+// Require повторно выполняет шаги стейта stateID функциями шагов sm и проваливает тест,
+// если результат отличается от записанного в истории выполнения
+func Require[DataT any, FailDataT any, MetaDataT any, StepT ~string, TypeT ~string, CreateOptionsT statemachine.CreateOptions](
+	t testing.TB,
+	sm *statemachine.StateMachine[DataT, FailDataT, MetaDataT, StepT, TypeT, CreateOptionsT],
+	stateID uuid.UUID,
+) {
+	t.Helper()
+
+	report, err := sm.Replay(context.Background(), stateID)
+	require.NoError(t, err)
+	requireNoDiff(t, report)
+}
+
+// RequireHistory повторно выполняет шаги стейта state по истории выполнения history функциями шагов sm
+// и проваливает тест, если результат отличается от записанного
+func RequireHistory[DataT any, FailDataT any, MetaDataT any, StepT ~string, TypeT ~string, CreateOptionsT statemachine.CreateOptions](
+	t testing.TB,
+	sm *statemachine.StateMachine[DataT, FailDataT, MetaDataT, StepT, TypeT, CreateOptionsT],
+	state statemachine.State[DataT, FailDataT, MetaDataT, StepT, TypeT],
+	history []statemachine.StepExecution[StepT],
+) {
+	t.Helper()
+
+	report, err := sm.ReplayHistory(context.Background(), state, history)
+	require.NoError(t, err)
+	requireNoDiff(t, report)
+}
+
+func requireNoDiff[StepT ~string](t testing.TB, report *statemachine.ReplayReport[StepT]) {
+	t.Helper()
+
+	diffs := report.Diffs()
+	if len(diffs) == 0 {
+		return
+	}
+	require.Fail(t, "replayed steps differ from recorded history", strings.Join(
+		lo.Map(diffs, func(item statemachine.ReplayStep[StepT], _ int) string {
+			return item.String()
+		}), "\n"))
+}
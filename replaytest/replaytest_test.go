@@ -0,0 +1,66 @@
+package replaytest_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/kkiling/statemachine"
+	"github.com/kkiling/statemachine/memstore"
+	"github.com/kkiling/statemachine/replaytest"
+)
+
+type testOptions struct{}
+
+func (testOptions) GetIdempotencyKey() string {
+	return ""
+}
+
+type testRunner struct{}
+
+func (testRunner) Create(context.Context, testOptions) (statemachine.CreateState[int, any, string], error) {
+	return statemachine.CreateState[int, any, string]{FirstStep: "first"}, nil
+}
+
+func (testRunner) Type() string {
+	return "replay_test"
+}
+
+func (testRunner) StepRegistration(statemachine.StepRegistrationParams) statemachine.StepRegistration[int, any, any, string, string] {
+	return statemachine.StepRegistration[int, any, any, string, string]{
+		Steps: map[string]statemachine.Step[int, any, any, string, string]{
+			"first": {
+				OnStep: func(_ context.Context, stepContext statemachine.StepContext[int, any, any, string, string]) *statemachine.StepResult[int, any, any, string] {
+					return stepContext.Next("second").WithData(stepContext.State.Data + 1)
+				},
+			},
+			"second": {
+				OnStep: func(_ context.Context, stepContext statemachine.StepContext[int, any, any, string, string]) *statemachine.StepResult[int, any, any, string] {
+					return stepContext.Complete().WithData(stepContext.State.Data * 2)
+				},
+			},
+		},
+		DataSnapshots: true,
+	}
+}
+
+func TestRequire(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	sm := statemachine.NewService[int, any, any, string, string, testOptions](statemachine.Config{}, memstore.NewStorage(), testRunner{})
+
+	state, err := sm.Create(ctx, testOptions{})
+	require.NoError(t, err)
+	completeState, _, err := sm.Complete(ctx, state.ID)
+	require.NoError(t, err)
+	require.Equal(t, statemachine.CompletedStatus, completeState.Status)
+	require.Equal(t, 2, completeState.Data)
+
+	replaytest.Require(t, sm, state.ID)
+
+	history, err := sm.History(ctx, state.ID)
+	require.NoError(t, err)
+	replaytest.RequireHistory(t, sm, *completeState, history)
+}
@@ -0,0 +1,106 @@
+package statemachine
+
+import (
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"time"
+)
+
+const (
+	defaultRetryMultiplier  = 2
+	defaultRetryMaxInterval = 24 * time.Hour
+)
+
+// RetryPolicy политика повторного выполнения шага, завершившегося ошибкой
+type RetryPolicy struct {
+	// MaxAttempts максимальное количество выполнений шага, завершившихся ошибкой,
+	// после которого стейт переводится в статус фейла (0 - без ограничений)
+	MaxAttempts int
+	// InitialInterval задержка перед первым повторным выполнением (0 - повтор без задержки)
+	InitialInterval time.Duration
+	// MaxInterval максимальная задержка между повторными выполнениями, с учетом Jitter (по умолчанию 24 часа)
+	MaxInterval time.Duration
+	// Multiplier множитель увеличения задержки после каждой ошибки (по умолчанию 2)
+	Multiplier float64
+	// Jitter доля случайного отклонения задержки, от 0 до 1
+	Jitter float64
+	// Retryable определяет, можно ли повторить шаг после ошибки (nil - все ошибки повторяемые)
+	Retryable func(err error) bool
+}
+
+// isRetryable можно ли повторить шаг после ошибки
+func (p *RetryPolicy) isRetryable(err error) bool {
+	return p.Retryable == nil || p.Retryable(err)
+}
+
+// isExhausted исчерпаны ли попытки выполнения шага
+func (p *RetryPolicy) isExhausted(failedAttempts int) bool {
+	return p.MaxAttempts > 0 && failedAttempts >= p.MaxAttempts
+}
+
+// backoff задержка перед повторным выполнением шага после failedAttempts ошибок подряд
+func (p *RetryPolicy) backoff(failedAttempts int) time.Duration {
+	if p.InitialInterval <= 0 || failedAttempts <= 0 {
+		return 0
+	}
+
+	multiplier := p.Multiplier
+	if multiplier <= 0 {
+		multiplier = defaultRetryMultiplier
+	}
+
+	maxInterval := p.MaxInterval
+	if maxInterval <= 0 {
+		maxInterval = defaultRetryMaxInterval
+	}
+
+	// При большом количестве ошибок задержка может выйти за пределы time.Duration,
+	// поэтому ограничение применяется к float64 до приведения типа
+	interval := float64(p.InitialInterval) * math.Pow(multiplier, float64(failedAttempts-1))
+	interval = math.Min(interval, float64(maxInterval))
+	if p.Jitter > 0 {
+		interval += interval * p.Jitter * (2*rand.Float64() - 1)
+	}
+	interval = math.Min(interval, float64(maxInterval))
+
+	return time.Duration(interval)
+}
+
+// StepFailedError причина перевода стейта в статус фейла после ошибки выполнения шага
+type StepFailedError struct {
+	// Step шаг, на котором произошла ошибка
+	Step string
+	// Attempts количество выполнений шага, завершившихся ошибкой
+	Attempts int
+	// Reason причина фейла (ErrRetryAttemptsExhausted, ErrNotRetryable или ErrPanicAttemptsExhausted)
+	Reason error
+	// Err последняя ошибка выполнения шага
+	Err error
+}
+
+func (e *StepFailedError) Error() string {
+	return fmt.Sprintf("step %s failed after %d attempts: %v: %v", e.Step, e.Attempts, e.Reason, e.Err)
+}
+
+func (e *StepFailedError) Unwrap() []error {
+	return []error{e.Reason, e.Err}
+}
+
+// StepTimeoutError превышено время выполнения шага
+type StepTimeoutError struct {
+	// Step шаг, выполнение которого превысило ограничение
+	Step string
+	// Timeout ограничение времени выполнения шага
+	Timeout time.Duration
+	// Err ошибка, которую вернул шаг после отмены контекста
+	Err error
+}
+
+func (e *StepTimeoutError) Error() string {
+	return fmt.Sprintf("step %s timed out after %s: %v", e.Step, e.Timeout, e.Err)
+}
+
+func (e *StepTimeoutError) Unwrap() []error {
+	return []error{ErrStepTimeout, e.Err}
+}
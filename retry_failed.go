@@ -0,0 +1,87 @@
+package statemachine
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/samber/lo"
+
+	"github.com/kkiling/statemachine/storage"
+)
+
+// RetryOptions параметры повторного запуска зафейленного стейта
+type RetryOptions[DataT any] struct {
+	// Data новые данные стейта (nil - данные не изменяются)
+	Data *DataT
+	// Reason причина повторного запуска, сохраняется в истории выполнения
+	Reason string
+}
+
+// Retry возвращает стейт из статуса фейла на шаг step (пустой step - на шаг, на котором стейт зафейлился).
+// Стейт переводится в InProgressStatus, ошибка и счетчики попыток сбрасываются, FailData сохраняется.
+// Для стейта не в статусе фейла возвращает ErrNotFailed, для незарегистрированного шага - ErrUnknownStep
+func (i *StateMachine[DataT, FailDataT, MetaDataT, StepT, TypeT, CreateOptionsT]) Retry(
+	ctx context.Context,
+	stateID uuid.UUID,
+	step StepT,
+	opts RetryOptions[DataT],
+) (*State[DataT, FailDataT, MetaDataT, StepT, TypeT], error) {
+	findState, err := i.GetStateByID(ctx, stateID)
+	if err != nil {
+		return nil, fmt.Errorf("GetStateByID: %w", err)
+	}
+	if findState == nil {
+		return nil, fmt.Errorf("state not found: %w", ErrNotFound)
+	}
+	if findState.Status != FailedStatus {
+		return nil, ErrNotFailed
+	}
+
+	if step == "" {
+		step = findState.FailedStep
+	}
+	registration := i.runner.StepRegistration(StepRegistrationParams{})
+	if _, ok := registration.Steps[step]; !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownStep, step)
+	}
+
+	now := i.clock.Now()
+	newState := *findState
+	newState.Status = InProgressStatus
+	newState.Step = step
+	newState.UpdatedAt = now
+	newState.Error = nil
+	newState.NextRunAt = nil
+	newState.FailedAttempts = 0
+	newState.StepAttempts = 0
+	newState.PanicAttempts = 0
+	newState.FailedStep = ""
+	if opts.Data != nil {
+		newState.Data = *opts.Data
+	}
+
+	// Повторный запуск сохраняется в истории выполнения как переход из фейла на шаг step
+	execute := storage.StepExecuteInfo{
+		StateID:            stateID,
+		StartExecutedAt:    now,
+		CompleteExecutedAt: now,
+		PreviewStep:        string(findState.Step),
+		NextStep:           lo.ToPtr(string(step)),
+		Reason:             lo.ToPtr(opts.Reason),
+	}
+	if registration.DataSnapshots {
+		// Сохраняем исправление данных оператором
+		if execute.DataBefore, err = marshalDataSnapshot(findState.Data); err != nil {
+			return nil, err
+		}
+		if execute.DataAfter, err = marshalDataSnapshot(newState.Data); err != nil {
+			return nil, err
+		}
+	}
+	if err = i.saveState(ctx, &newState, execute); err != nil {
+		return nil, err
+	}
+
+	return &newState, nil
+}
@@ -0,0 +1,94 @@
+package statemachine
+
+import (
+	"context"
+	"testing"
+
+	"github.com/samber/lo"
+	"github.com/stretchr/testify/require"
+
+	"github.com/kkiling/statemachine/memstore"
+)
+
+func TestStateMachine_Retry(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	newFailedState := func(t *testing.T) (*StateMachine[int, any, any, string, string, testOptions], *memstore.Storage, *State[int, any, any, string, string]) {
+		s := memstore.NewStorage()
+		sm := NewService[int, any, any, string, string, testOptions](Config{}, s, newRetryFailedTestRunner())
+
+		state, err := sm.Create(ctx, testOptions{})
+		require.NoError(t, err)
+		failState, _, err := sm.Complete(ctx, state.ID)
+		require.NoError(t, err)
+		require.Equal(t, FailedStatus, failState.Status)
+		require.Equal(t, "second", failState.FailedStep)
+
+		return sm, s, failState
+	}
+
+	t.Run("retry failed step with new data", func(t *testing.T) {
+		t.Parallel()
+
+		sm, s, failState := newFailedState(t)
+
+		retryState, err := sm.Retry(ctx, failState.ID, "", RetryOptions[int]{
+			Data:   lo.ToPtr(1),
+			Reason: "data fixed",
+		})
+		require.NoError(t, err)
+		require.Equal(t, InProgressStatus, retryState.Status)
+		require.Equal(t, "second", retryState.Step)
+		require.Equal(t, "", retryState.FailedStep)
+		require.Equal(t, 1, retryState.Data)
+
+		findState, err := sm.GetStateByID(ctx, failState.ID)
+		require.NoError(t, err)
+		require.Equal(t, retryState, findState)
+
+		completeState, _, err := sm.Complete(ctx, failState.ID)
+		require.NoError(t, err)
+		require.Equal(t, CompletedStatus, completeState.Status)
+
+		// Повторный запуск сохраняется в истории выполнения
+		infos, err := s.GetStepExecuteInfos(ctx, failState.ID)
+		require.NoError(t, err)
+		require.Len(t, infos, 4)
+		require.Equal(t, "", infos[2].PreviewStep)
+		require.Equal(t, lo.ToPtr("second"), infos[2].NextStep)
+		require.Equal(t, lo.ToPtr("data fixed"), infos[2].Reason)
+	})
+
+	t.Run("retry from chosen step", func(t *testing.T) {
+		t.Parallel()
+
+		sm, _, failState := newFailedState(t)
+
+		retryState, err := sm.Retry(ctx, failState.ID, "first", RetryOptions[int]{Reason: "rerun"})
+		require.NoError(t, err)
+		require.Equal(t, "first", retryState.Step)
+		require.Equal(t, 0, retryState.Data)
+	})
+
+	t.Run("unknown step", func(t *testing.T) {
+		t.Parallel()
+
+		sm, _, failState := newFailedState(t)
+
+		_, err := sm.Retry(ctx, failState.ID, "unknown", RetryOptions[int]{})
+		require.ErrorIs(t, err, ErrUnknownStep)
+	})
+
+	t.Run("state is not failed", func(t *testing.T) {
+		t.Parallel()
+
+		sm := NewService[int, any, any, string, string, testOptions](Config{}, memstore.NewStorage(), newRetryFailedTestRunner())
+		state, err := sm.Create(ctx, testOptions{})
+		require.NoError(t, err)
+
+		_, err = sm.Retry(ctx, state.ID, "", RetryOptions[int]{})
+		require.ErrorIs(t, err, ErrNotFailed)
+	})
+}
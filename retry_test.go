@@ -0,0 +1,80 @@
+package statemachine
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRetryPolicy_Backoff(t *testing.T) {
+	t.Parallel()
+
+	t.Run("exponential with max interval", func(t *testing.T) {
+		policy := RetryPolicy{
+			InitialInterval: time.Second,
+			MaxInterval:     5 * time.Second,
+		}
+
+		require.Equal(t, time.Second, policy.backoff(1))
+		require.Equal(t, 2*time.Second, policy.backoff(2))
+		require.Equal(t, 4*time.Second, policy.backoff(3))
+		require.Equal(t, 5*time.Second, policy.backoff(4))
+	})
+
+	t.Run("custom multiplier", func(t *testing.T) {
+		policy := RetryPolicy{
+			InitialInterval: time.Second,
+			Multiplier:      3,
+		}
+
+		require.Equal(t, 9*time.Second, policy.backoff(3))
+	})
+
+	t.Run("without initial interval", func(t *testing.T) {
+		policy := RetryPolicy{MaxAttempts: 3}
+
+		require.Zero(t, policy.backoff(2))
+	})
+
+	t.Run("jitter", func(t *testing.T) {
+		policy := RetryPolicy{
+			InitialInterval: 10 * time.Second,
+			Jitter:          0.5,
+		}
+
+		for range 100 {
+			backoff := policy.backoff(1)
+			require.GreaterOrEqual(t, backoff, 5*time.Second)
+			require.LessOrEqual(t, backoff, 15*time.Second)
+		}
+	})
+
+	t.Run("max interval with jitter", func(t *testing.T) {
+		policy := RetryPolicy{
+			InitialInterval: 10 * time.Second,
+			MaxInterval:     10 * time.Second,
+			Jitter:          0.5,
+		}
+
+		for range 100 {
+			backoff := policy.backoff(1)
+			require.GreaterOrEqual(t, backoff, 5*time.Second)
+			require.LessOrEqual(t, backoff, 10*time.Second)
+		}
+	})
+
+	t.Run("large attempt count", func(t *testing.T) {
+		// Без ограничений задержка переполнила бы time.Duration
+		policy := RetryPolicy{InitialInterval: time.Second}
+
+		for _, attempts := range []int{63, 100, 1000, 100000} {
+			require.Equal(t, defaultRetryMaxInterval, policy.backoff(attempts))
+		}
+
+		policy.Jitter = 0.5
+		backoff := policy.backoff(1000)
+		require.Positive(t, backoff)
+		require.LessOrEqual(t, backoff, defaultRetryMaxInterval)
+	})
+}
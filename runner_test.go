@@ -0,0 +1,84 @@
+package statemachine
+
+import (
+	"context"
+	"maps"
+)
+
+// testOptions опции создания стейта в тестах
+type testOptions struct {
+	key string
+}
+
+func (o testOptions) GetIdempotencyKey() string {
+	return o.key
+}
+
+// testRunner раннер стейтов для тестов, выполнение стейта начинается с шага firstStep
+type testRunner struct {
+	stateType     string
+	firstStep     string
+	steps         map[string]testStepperStep
+	dataSnapshots bool
+}
+
+// newTestRunner раннер стейтов типа stateType с шагами steps
+func newTestRunner(stateType, firstStep string, steps map[string]testStepperStep) testRunner {
+	return testRunner{
+		stateType: stateType,
+		firstStep: firstStep,
+		steps:     steps,
+	}
+}
+
+// newStepTestRunner раннер стейтов типа stateType с единственным шагом first
+func newStepTestRunner(stateType string, step testStepperStep) testRunner {
+	return newTestRunner(stateType, "first", map[string]testStepperStep{"first": step})
+}
+
+// newRetryFailedTestRunner раннер стейтов с шагами first -> second, шаг second фейлится, пока данные не исправлены
+func newRetryFailedTestRunner() testRunner {
+	return newTestRunner("retry_failed_test", "first", map[string]testStepperStep{
+		"first": {
+			OnStep: func(_ context.Context, stepContext testStepperContext) *testStepperResult {
+				return stepContext.Next("second")
+			},
+		},
+		"second": {
+			OnStep: func(_ context.Context, stepContext testStepperContext) *testStepperResult {
+				if stepContext.State.Data == 0 {
+					return stepContext.Fail()
+				}
+				return stepContext.Complete()
+			},
+		},
+	})
+}
+
+// withStep копия раннера с замененным или добавленным шагом name
+func (r testRunner) withStep(name string, step testStepperStep) testRunner {
+	r.steps = maps.Clone(r.steps)
+	r.steps[name] = step
+	return r
+}
+
+// withDataSnapshots копия раннера с сохранением снимков данных в истории выполнения
+func (r testRunner) withDataSnapshots() testRunner {
+	r.dataSnapshots = true
+	return r
+}
+
+func (r testRunner) Create(context.Context, testOptions) (CreateState[int, any, string], error) {
+	return CreateState[int, any, string]{FirstStep: r.firstStep}, nil
+}
+
+func (r testRunner) Type() string {
+	return r.stateType
+}
+
+func (r testRunner) StepRegistration(StepRegistrationParams) StepRegistration[int, any, any, string, string] {
+	return StepRegistration[int, any, any, string, string]{
+		Steps:         maps.Clone(r.steps),
+		DataSnapshots: r.dataSnapshots,
+	}
+}
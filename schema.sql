@@ -46,6 +46,41 @@ ALTER TABLE public.goose_db_version ALTER COLUMN id ADD GENERATED BY DEFAULT AS
 );
 
 
+--
+-- Name: pause_event; Type: TABLE; Schema: public; Owner: -
+--
+
+CREATE TABLE public.pause_event (
+    id integer NOT NULL,
+    type text NOT NULL,
+    state_id uuid,
+    paused boolean NOT NULL,
+    created_at timestamp with time zone NOT NULL,
+    created_by text NOT NULL,
+    reason text NOT NULL
+);
+
+
+--
+-- Name: pause_event_id_seq; Type: SEQUENCE; Schema: public; Owner: -
+--
+
+CREATE SEQUENCE public.pause_event_id_seq
+    AS integer
+    START WITH 1
+    INCREMENT BY 1
+    NO MINVALUE
+    NO MAXVALUE
+    CACHE 1;
+
+
+--
+-- Name: pause_event_id_seq; Type: SEQUENCE OWNED BY; Schema: public; Owner: -
+--
+
+ALTER SEQUENCE public.pause_event_id_seq OWNED BY public.pause_event.id;
+
+
 --
 -- Name: state; Type: TABLE; Schema: public; Owner: -
 --
@@ -61,7 +96,26 @@ CREATE TABLE public.state (
     error text,
     data jsonb,
     fail_data jsonb,
-    meta_data jsonb
+    meta_data jsonb,
+    lease_owner text,
+    lease_expires_at timestamp with time zone,
+    version bigint DEFAULT 0 NOT NULL,
+    next_run_at timestamp with time zone,
+    failed_attempts integer DEFAULT 0 NOT NULL,
+    step_attempts integer DEFAULT 0 NOT NULL,
+    paused boolean DEFAULT false NOT NULL,
+    failed_step text DEFAULT ''::text NOT NULL,
+    panic_attempts integer DEFAULT 0 NOT NULL
+);
+
+
+--
+-- Name: state_type_pause; Type: TABLE; Schema: public; Owner: -
+--
+
+CREATE TABLE public.state_type_pause (
+    type text NOT NULL,
+    paused_at timestamp with time zone NOT NULL
 );
 
 
@@ -76,7 +130,10 @@ CREATE TABLE public.step_execute_info (
     complete_executed_at timestamp with time zone NOT NULL,
     error text,
     preview_step text NOT NULL,
-    next_step text
+    next_step text,
+    reason text,
+    data_before jsonb,
+    data_after jsonb
 );
 
 
@@ -100,6 +157,13 @@ CREATE SEQUENCE public.step_execute_info_id_seq
 ALTER SEQUENCE public.step_execute_info_id_seq OWNED BY public.step_execute_info.id;
 
 
+--
+-- Name: pause_event id; Type: DEFAULT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.pause_event ALTER COLUMN id SET DEFAULT nextval('public.pause_event_id_seq'::regclass);
+
+
 --
 -- Name: step_execute_info id; Type: DEFAULT; Schema: public; Owner: -
 --
@@ -115,6 +179,14 @@ ALTER TABLE ONLY public.goose_db_version
     ADD CONSTRAINT goose_db_version_pkey PRIMARY KEY (id);
 
 
+--
+-- Name: pause_event pause_event_pkey; Type: CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.pause_event
+    ADD CONSTRAINT pause_event_pkey PRIMARY KEY (id);
+
+
 --
 -- Name: state state_pkey; Type: CONSTRAINT; Schema: public; Owner: -
 --
@@ -123,6 +195,14 @@ ALTER TABLE ONLY public.state
     ADD CONSTRAINT state_pkey PRIMARY KEY (id);
 
 
+--
+-- Name: state_type_pause state_type_pause_pkey; Type: CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.state_type_pause
+    ADD CONSTRAINT state_type_pause_pkey PRIMARY KEY (type);
+
+
 --
 -- Name: step_execute_info step_execute_info_pkey; Type: CONSTRAINT; Schema: public; Owner: -
 --
@@ -131,6 +211,20 @@ ALTER TABLE ONLY public.step_execute_info
     ADD CONSTRAINT step_execute_info_pkey PRIMARY KEY (id);
 
 
+--
+-- Name: idx_pause_event_type; Type: INDEX; Schema: public; Owner: -
+--
+
+CREATE INDEX idx_pause_event_type ON public.pause_event USING btree (type);
+
+
+--
+-- Name: idx_state_created_at_id; Type: INDEX; Schema: public; Owner: -
+--
+
+CREATE INDEX idx_state_created_at_id ON public.state USING btree (created_at, id);
+
+
 --
 -- Name: idx_state_idempotency_key; Type: INDEX; Schema: public; Owner: -
 --
@@ -138,6 +232,20 @@ ALTER TABLE ONLY public.step_execute_info
 CREATE UNIQUE INDEX idx_state_idempotency_key ON public.state USING btree (idempotency_key);
 
 
+--
+-- Name: idx_state_type_created_at_id; Type: INDEX; Schema: public; Owner: -
+--
+
+CREATE INDEX idx_state_type_created_at_id ON public.state USING btree (type, created_at, id);
+
+
+--
+-- Name: idx_state_type_status_updated_at; Type: INDEX; Schema: public; Owner: -
+--
+
+CREATE INDEX idx_state_type_status_updated_at ON public.state USING btree (type, status, updated_at);
+
+
 --
 -- Name: idx_step_execute_state_id; Type: INDEX; Schema: public; Owner: -
 --
@@ -145,6 +253,14 @@ CREATE UNIQUE INDEX idx_state_idempotency_key ON public.state USING btree (idemp
 CREATE INDEX idx_step_execute_state_id ON public.step_execute_info USING btree (state_id);
 
 
+--
+-- Name: pause_event pause_event_state_id_fkey; Type: FK CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.pause_event
+    ADD CONSTRAINT pause_event_state_id_fkey FOREIGN KEY (state_id) REFERENCES public.state(id) ON DELETE CASCADE;
+
+
 --
 -- Name: step_execute_info step_execute_info_state_id_fkey; Type: FK CONSTRAINT; Schema: public; Owner: -
 --
@@ -0,0 +1,18 @@
+// Package sqlitestore хранилище стейт машины в SQLite.
+//
+// Пакет использует драйвер github.com/mattn/go-sqlite3, которому для работы нужен cgo.
+// Он вынесен из пакета statemachine, что бы сервисы без SQLite собирались с CGO_ENABLED=0
+package sqlitestore
+
+import (
+	"github.com/kkiling/statemachine/internal/storage/sqlite"
+)
+
+// NewConn открывает базу SQLite, схема создается через Migrate
+var NewConn = sqlite.NewConn
+
+// NewStorage хранилище стейт машины в базе SQLite
+var NewStorage = sqlite.NewStorage
+
+// Migrate применяет к базе SQLite встроенные миграции схемы, которые еще не были применены
+var Migrate = sqlite.Migrate
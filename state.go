@@ -14,8 +14,15 @@ const (
 	InProgressStatus Status = iota
 	CompletedStatus  Status = iota
 	FailedStatus     Status = iota
+	// CancelledStatus стейт отменен вызовом StateMachine.Cancel
+	CancelledStatus Status = iota
 )
 
+// isTerminalStatus стейт в терминальном статусе больше не выполняется
+func isTerminalStatus(status Status) bool {
+	return status == CompletedStatus || status == FailedStatus || status == CancelledStatus
+}
+
 // State состояние стейт машины
 type State[DataT any, FailDataT any, MetaDataT any, StepT ~string, TypeT ~string] struct {
 	// ID идентификаторе текущего стейта
@@ -40,6 +47,20 @@ type State[DataT any, FailDataT any, MetaDataT any, StepT ~string, TypeT ~string
 	MetaData MetaDataT
 	// Ошибка выполнения
 	Error *string
+	// Version версия стейта, увеличивается при каждом сохранении изменений
+	Version int64
+	// NextRunAt время, раньше которого стейт не будет выполняться (nil - выполнение возможно сразу)
+	NextRunAt *time.Time
+	// FailedAttempts количество выполнений текущего шага, завершившихся ошибкой
+	FailedAttempts int
+	// StepAttempts количество завершенных выполнений текущего шага (сбрасывается при переходе на другой шаг)
+	StepAttempts int
+	// PanicAttempts количество выполнений текущего шага подряд, завершившихся паникой (сбрасывается любым другим результатом шага)
+	PanicAttempts int
+	// Paused выполнение стейта приостановлено вызовом StateMachine.Pause
+	Paused bool
+	// FailedStep шаг, на котором стейт перешел в статус фейла (пустой, если стейт не зафейлен)
+	FailedStep StepT
 }
 
 // CreateState структура инициализации стейта
@@ -60,6 +81,17 @@ type CreateOptions interface {
 type Step[DataT any, FailDataT any, MetaDataT any, StepT ~string, TypeT ~string] struct {
 	OptionsType reflect.Type
 	OnStep      StepFunc[DataT, FailDataT, MetaDataT, StepT, TypeT]
+	// RetryPolicy политика повторного выполнения шага после ошибки (nil - шаг повторяется без ограничений и задержки)
+	RetryPolicy *RetryPolicy
+	// PanicPolicy политика обработки паники в функции шага (nil - паника обрабатывается по RetryPolicy)
+	PanicPolicy *PanicPolicy
+	// Timeout ограничение времени выполнения шага, по истечении контекст шага отменяется
+	// и ошибка выполнения заменяется на *StepTimeoutError (0 - используется Config.StepTimeout).
+	// Пустой результат (Empty, RetryAfter, ScheduleAt), возвращенный после таймаута, также заменяется
+	// на *StepTimeoutError, а результат Next, Complete или Fail сохраняется.
+	// Таймаут считается обычным неудачным выполнением шага: учитывается в RetryPolicy.MaxAttempts
+	// и передается в RetryPolicy.Retryable, который может отклонить его по errors.Is(err, ErrStepTimeout)
+	Timeout time.Duration
 }
 
 type StepRegistrationParams struct {
@@ -67,4 +99,7 @@ type StepRegistrationParams struct {
 
 type StepRegistration[DataT any, FailDataT any, MetaDataT any, StepT ~string, TypeT ~string] struct {
 	Steps map[StepT]Step[DataT, FailDataT, MetaDataT, StepT, TypeT]
+	// DataSnapshots сохранять в истории выполнения данные стейта до и после каждого выполнения шага.
+	// Увеличивает объем истории, для типов с большим потоком стейтов лучше не включать
+	DataSnapshots bool
 }
@@ -4,12 +4,33 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"time"
 
 	"github.com/google/uuid"
-	"github.com/kkiling/goplatform/storagebase"
+
+	"github.com/kkiling/statemachine/storage"
+)
+
+const (
+	defaultLeaseDuration  = 5 * time.Minute
+	defaultMaxTransitions = 1000
+	maxConflictRetries    = 10
 )
 
 type Config struct {
+	// LeaseOwner идентификатор экземпляра стейт машины, захватывающего стейты на время выполнения
+	// (по умолчанию генерируется случайный)
+	LeaseOwner string
+	// LeaseDuration время аренды стейта, по истечении которого стейт может быть перехвачен
+	// другим обработчиком (например после падения процесса). Пока стейт выполняется,
+	// аренда продлевается каждую треть LeaseDuration (по умолчанию 5 минут)
+	LeaseDuration time.Duration
+	// StepTimeout ограничение времени выполнения шага, если для шага не задан Step.Timeout (0 - без ограничения)
+	StepTimeout time.Duration
+	// MaxTransitions максимальное количество переходов между шагами за один вызов Complete,
+	// при превышении стейт приостанавливается. Отключить ограничение нельзя:
+	// значение 0 и отрицательные значения заменяются на 1000
+	MaxTransitions int
 }
 
 type StateMachine[DataT any, FailDataT any, MetaDataT any, StepT ~string, TypeT ~string, CreateOptionsT CreateOptions] struct {
@@ -18,6 +39,7 @@ type StateMachine[DataT any, FailDataT any, MetaDataT any, StepT ~string, TypeT
 	storage       Storage
 	clock         Clock
 	uuidGenerator UUIDGenerator
+	running       *runningStates
 }
 
 func NewService[DataT any, FailDataT any, MetaDataT any, StepT ~string, TypeT ~string, CreateOptionsT CreateOptions](
@@ -26,12 +48,23 @@ func NewService[DataT any, FailDataT any, MetaDataT any, StepT ~string, TypeT ~s
 	runner Runner[DataT, FailDataT, MetaDataT, StepT, TypeT, CreateOptionsT],
 ) *StateMachine[DataT, FailDataT, MetaDataT, StepT, TypeT, CreateOptionsT] {
 
+	if cfg.LeaseOwner == "" {
+		cfg.LeaseOwner = uuid.NewString()
+	}
+	if cfg.LeaseDuration <= 0 {
+		cfg.LeaseDuration = defaultLeaseDuration
+	}
+	if cfg.MaxTransitions <= 0 {
+		cfg.MaxTransitions = defaultMaxTransitions
+	}
+
 	sm := StateMachine[DataT, FailDataT, MetaDataT, StepT, TypeT, CreateOptionsT]{
 		cfg:           cfg,
 		runner:        runner,
 		storage:       storage,
 		clock:         &realClock{},
 		uuidGenerator: &uuidGenerator{},
+		running:       newRunningStates(),
 	}
 
 	return &sm
@@ -44,7 +77,7 @@ func (i *StateMachine[DataT, FailDataT, MetaDataT, StepT, TypeT, CreateOptionsT]
 	findState, err := i.storage.GetStateByIdempotencyKey(ctx, idempotencyKey)
 	switch {
 	case err == nil:
-	case errors.Is(err, storagebase.ErrNotFound): // Стейт не найден
+	case errors.Is(err, storage.ErrNotFound): // Стейт не найден
 		return nil, nil
 	default:
 		return nil, fmt.Errorf("storage.GetStateByIdempotencyKey: %w", err)
@@ -60,7 +93,7 @@ func (i *StateMachine[DataT, FailDataT, MetaDataT, StepT, TypeT, CreateOptionsT]
 	findState, err := i.storage.GetStateByID(ctx, stateID)
 	switch {
 	case err == nil:
-	case errors.Is(err, storagebase.ErrNotFound): // Стейт не найден
+	case errors.Is(err, storage.ErrNotFound): // Стейт не найден
 		return nil, nil
 	default:
 		return nil, fmt.Errorf("storage.GetStateByID: %w", err)
@@ -118,31 +151,147 @@ func (i *StateMachine[DataT, FailDataT, MetaDataT, StepT, TypeT, CreateOptionsT]
 	for s, step := range stepsRegistration.Steps {
 		stepper.Add(s, step)
 	}
+	stepper.dataSnapshots = stepsRegistration.DataSnapshots
+	stepper.defaultTimeout = i.cfg.StepTimeout
+	stepper.maxTransitions = i.cfg.MaxTransitions
 	return stepper
 }
 
+// lease аренда стейта этим экземпляром стейт машины
+func (i *StateMachine[DataT, FailDataT, MetaDataT, StepT, TypeT, CreateOptionsT]) lease() storage.Lease {
+	now := i.clock.Now()
+	return storage.Lease{
+		Owner:      i.cfg.LeaseOwner,
+		AcquiredAt: now,
+		ExpiresAt:  now.Add(i.cfg.LeaseDuration),
+	}
+}
+
+// renewLease продлевает аренду стейта, пока выполняется стейт. Если аренда потеряна
+// (стейт захвачен другим обработчиком), контекст выполнения отменяется с причиной ErrLeaseLost.
+// Временные ошибки хранилища не прерывают выполнение, продление повторяется на следующем интервале
+func (i *StateMachine[DataT, FailDataT, MetaDataT, StepT, TypeT, CreateOptionsT]) renewLease(
+	ctx context.Context,
+	stateID uuid.UUID,
+) (renewCtx context.Context, stop func()) {
+	renewCtx, cancel := context.WithCancelCause(ctx)
+	stopped := make(chan struct{})
+	finished := make(chan struct{})
+
+	go func() {
+		defer close(finished)
+
+		ticker := time.NewTicker(i.cfg.LeaseDuration / 3)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-renewCtx.Done():
+				return
+			case <-stopped:
+				return
+			case <-ticker.C:
+			}
+
+			err := i.storage.RenewLease(renewCtx, stateID, i.lease())
+			if errors.Is(err, storage.ErrLocked) || errors.Is(err, storage.ErrNotFound) {
+				cancel(ErrLeaseLost)
+				return
+			}
+		}
+	}()
+
+	return renewCtx, func() {
+		close(stopped)
+		<-finished
+		cancel(nil)
+	}
+}
+
+// release освобождение аренды стейта, выполняется даже если контекст выполнения отменен
+func (i *StateMachine[DataT, FailDataT, MetaDataT, StepT, TypeT, CreateOptionsT]) release(ctx context.Context, stateID uuid.UUID) error {
+	if err := i.storage.ReleaseState(context.WithoutCancel(ctx), stateID, i.cfg.LeaseOwner); err != nil {
+		return fmt.Errorf("storage.ReleaseState: %w", err)
+	}
+	return nil
+}
+
 // Complete выполнение стейта
 func (i *StateMachine[DataT, FailDataT, MetaDataT, StepT, TypeT, CreateOptionsT]) Complete(
 	ctx context.Context,
 	stateID uuid.UUID,
 	options ...any,
 ) (st *State[DataT, FailDataT, MetaDataT, StepT, TypeT], executeErr error, err error) {
-	// Проверяем стейт на наличие ключа идемпотентности
-	findState, err := i.GetStateByID(ctx, stateID)
-	if err != nil {
-		return nil, nil, fmt.Errorf("getStateByID: %w", err)
+	// Захватываем стейт, что бы его одновременно не выполнял другой обработчик
+	lease := i.lease()
+	claimState, err := i.storage.ClaimState(ctx, stateID, lease)
+	switch {
+	case err == nil:
+	case errors.Is(err, storage.ErrNotFound):
+		return nil, nil, fmt.Errorf("state not found: %w", ErrNotFound)
+	case errors.Is(err, storage.ErrLocked):
+		return nil, nil, ErrStateLocked
+	default:
+		return nil, nil, fmt.Errorf("storage.ClaimState: %w", err)
 	}
 
-	if findState == nil {
-		return nil, nil, fmt.Errorf("state not found: %w", ErrNotFound)
+	defer func() {
+		if rErr := i.release(ctx, stateID); rErr != nil && err == nil {
+			st, executeErr, err = nil, nil, rErr
+		}
+	}()
+
+	findState, err := mapStorageToState[DataT, FailDataT, MetaDataT, StepT, TypeT](claimState)
+	if err != nil {
+		return nil, nil, fmt.Errorf("mapStorageToState: %w", err)
 	}
 
-	if findState.Status == FailedStatus || findState.Status == CompletedStatus {
+	return i.complete(ctx, *findState, lease.AcquiredAt, options...)
+}
+
+// complete выполнение захваченного стейта
+func (i *StateMachine[DataT, FailDataT, MetaDataT, StepT, TypeT, CreateOptionsT]) complete(
+	ctx context.Context,
+	findState State[DataT, FailDataT, MetaDataT, StepT, TypeT],
+	now time.Time,
+	options ...any,
+) (st *State[DataT, FailDataT, MetaDataT, StepT, TypeT], executeErr error, err error) {
+	if isTerminalStatus(findState.Status) {
 		return nil, nil, ErrInTerminalStatus
 	}
 
+	// Выполнение стейта или всех стейтов его типа приостановлено
+	if findState.Paused {
+		return nil, nil, ErrPaused
+	}
+	if paused, err := i.storage.IsTypePaused(ctx, string(findState.Type)); err != nil {
+		return nil, nil, fmt.Errorf("storage.IsTypePaused: %w", err)
+	} else if paused {
+		return nil, nil, ErrPaused
+	}
+
+	// Шаг отложен и время его выполнения еще не наступило
+	if findState.NextRunAt != nil && now.Before(*findState.NextRunAt) {
+		return nil, nil, ErrNotReady
+	}
+
+	// Контекст выполнения отменяется при вызове Cancel для этого стейта
+	runCtx, done := i.running.add(ctx, findState.ID)
+	defer done()
+	// и при потере аренды стейта
+	runCtx, stopRenew := i.renewLease(runCtx, findState.ID)
+	defer stopRenew()
+
 	stepper := i.initStepper()
-	res, eErr, err := stepper.Compete(ctx, *findState, options...)
+	res, eErr, err := stepper.Compete(runCtx, findState, options...)
+	if errors.Is(context.Cause(runCtx), ErrStateCancelled) || i.isCancelledConcurrently(ctx, findState.ID, err) {
+		return nil, nil, ErrStateCancelled
+	}
+	if errors.Is(context.Cause(runCtx), ErrStateMoved) {
+		return nil, nil, ErrStateMoved
+	}
+	if errors.Is(context.Cause(runCtx), ErrLeaseLost) {
+		return nil, nil, ErrLeaseLost
+	}
 	if err != nil {
 		return nil, nil, fmt.Errorf("stepper.Compete: %w", err)
 	}
@@ -150,6 +299,69 @@ func (i *StateMachine[DataT, FailDataT, MetaDataT, StepT, TypeT, CreateOptionsT]
 	return res, eErr, nil
 }
 
+// isCancelledConcurrently был ли стейт отменен, пока выполнялся его шаг. Отмена может быть сохранена
+// между завершением шага и сохранением его результата, до отмены контекста выполнения,
+// тогда сохранение результата завершается ошибкой ErrConcurrentModification
+func (i *StateMachine[DataT, FailDataT, MetaDataT, StepT, TypeT, CreateOptionsT]) isCancelledConcurrently(
+	ctx context.Context,
+	stateID uuid.UUID,
+	err error,
+) bool {
+	if !errors.Is(err, ErrConcurrentModification) {
+		return false
+	}
+	findState, gErr := i.GetStateByID(ctx, stateID)
+	return gErr == nil && findState != nil && findState.Status == CancelledStatus
+}
+
+// retryOnConflict повторяет изменение стейта fn, пока оно завершается ErrConcurrentModification
+// (стейт изменился между чтением и записью, например завершился шаг), не более maxConflictRetries раз
+func retryOnConflict(ctx context.Context, fn func() error) error {
+	var err error
+	for range maxConflictRetries {
+		if err = ctx.Err(); err != nil {
+			return err
+		}
+		if err = fn(); !errors.Is(err, ErrConcurrentModification) {
+			return err
+		}
+	}
+	return err
+}
+
+// saveState сохраняет изменения стейта, сделанные вне выполнения шагов, вместе с записью в истории выполнения.
+// Изменения сохраняются с проверкой версии newState.Version, при успехе версия увеличивается
+func (i *StateMachine[DataT, FailDataT, MetaDataT, StepT, TypeT, CreateOptionsT]) saveState(
+	ctx context.Context,
+	newState *State[DataT, FailDataT, MetaDataT, StepT, TypeT],
+	execute storage.StepExecuteInfo,
+) error {
+	update, err := mapStateToStorageUpdate[DataT, FailDataT, MetaDataT, StepT, TypeT](newState)
+	if err != nil {
+		return fmt.Errorf("mapStateToStorageUpdate: %w", err)
+	}
+
+	err = i.storage.RunTransaction(ctx, func(ctxTx context.Context) error {
+		if terr := i.storage.SaveStepExecuteInfo(ctxTx, execute); terr != nil {
+			return fmt.Errorf("storage.SaveStepExecuteInfo: %w", terr)
+		}
+		if terr := i.storage.UpdateState(ctxTx, newState.ID, update); terr != nil {
+			return fmt.Errorf("storage.UpdateState: %w", terr)
+		}
+		return nil
+	})
+	switch {
+	case err == nil:
+	case errors.Is(err, storage.ErrConcurrentModification):
+		return ErrConcurrentModification
+	default:
+		return fmt.Errorf("storage.RunTransaction: %w", err)
+	}
+
+	newState.Version++
+	return nil
+}
+
 // SetClock устанавливает кастомную реализацию часов
 func (i *StateMachine[DataT, FailDataT, MetaDataT, StepT, TypeT, CreateOptionsT]) SetClock(clock Clock) {
 	i.clock = clock
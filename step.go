@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"reflect"
+	"time"
 )
 
 // stepState управляющее состояние указывающее на результат работы шага
@@ -27,6 +28,23 @@ type StepContext[DataT any, FailDataT any, MetaDataT any, StepT ~string, TypeT ~
 	State               State[DataT, FailDataT, MetaDataT, StepT, TypeT]
 	completeOptionsType reflect.Type
 	completeOptions     any
+	// Время начала выполнения шага
+	startExecutedAt time.Time
+}
+
+// Attempt номер текущего выполнения шага, начиная с 1 (сбрасывается при переходе на другой шаг)
+func (s *StepContext[DataT, FailDataT, MetaDataT, StepT, TypeT]) Attempt() int {
+	return s.State.StepAttempts + 1
+}
+
+// StepEnteredAt время перехода стейта на текущий шаг
+func (s *StepContext[DataT, FailDataT, MetaDataT, StepT, TypeT]) StepEnteredAt() time.Time {
+	return s.State.UpdatedAt
+}
+
+// StateAge время, прошедшее с создания стейта до начала текущего выполнения шага
+func (s *StepContext[DataT, FailDataT, MetaDataT, StepT, TypeT]) StateAge() time.Duration {
+	return s.startExecutedAt.Sub(s.State.CreatedAt)
 }
 
 func (s *StepContext[DataT, FailDataT, MetaDataT, StepT, TypeT]) GetOptions(v any) (bool, error) {
@@ -71,59 +89,95 @@ func (s *StepContext[DataT, FailDataT, MetaDataT, StepT, TypeT]) GetOptions(v an
 }
 
 // Next указываем что нужно перейти на новый статус
-func (s *StepContext[DataT, FailDataT, MetaDataT, StepT, TypeT]) Next(status StepT) *StepResult[DataT, StepT] {
-	return &StepResult[DataT, StepT]{
+func (s *StepContext[DataT, FailDataT, MetaDataT, StepT, TypeT]) Next(status StepT) *StepResult[DataT, FailDataT, MetaDataT, StepT] {
+	return &StepResult[DataT, FailDataT, MetaDataT, StepT]{
 		nextStatus: &status,
 		state:      nextStepState,
 	}
 }
 
 // Empty возвращает пустой результат работы шага (стейт машина дальше не продвинется, и шаг будет выполнен еще раз)
-func (s *StepContext[DataT, FailDataT, MetaDataT, StepT, TypeT]) Empty() *StepResult[DataT, StepT] {
-	return &StepResult[DataT, StepT]{
+func (s *StepContext[DataT, FailDataT, MetaDataT, StepT, TypeT]) Empty() *StepResult[DataT, FailDataT, MetaDataT, StepT] {
+	return &StepResult[DataT, FailDataT, MetaDataT, StepT]{
 		state: emptyStepState,
 	}
 }
 
-func (s *StepContext[DataT, FailDataT, MetaDataT, StepT, TypeT]) Error(err error) *StepResult[DataT, StepT] {
-	return &StepResult[DataT, StepT]{
+// RetryAfter шаг не продвигается и будет выполнен еще раз не раньше чем через d после завершения текущего выполнения
+func (s *StepContext[DataT, FailDataT, MetaDataT, StepT, TypeT]) RetryAfter(d time.Duration) *StepResult[DataT, FailDataT, MetaDataT, StepT] {
+	return &StepResult[DataT, FailDataT, MetaDataT, StepT]{
+		state:      emptyStepState,
+		retryAfter: &d,
+	}
+}
+
+// ScheduleAt шаг не продвигается и будет выполнен еще раз не раньше момента t
+func (s *StepContext[DataT, FailDataT, MetaDataT, StepT, TypeT]) ScheduleAt(t time.Time) *StepResult[DataT, FailDataT, MetaDataT, StepT] {
+	return &StepResult[DataT, FailDataT, MetaDataT, StepT]{
+		state:     emptyStepState,
+		nextRunAt: &t,
+	}
+}
+
+func (s *StepContext[DataT, FailDataT, MetaDataT, StepT, TypeT]) Error(err error) *StepResult[DataT, FailDataT, MetaDataT, StepT] {
+	return &StepResult[DataT, FailDataT, MetaDataT, StepT]{
 		state: errorStepState,
 		err:   err,
 	}
 }
 
 // Fail переводит стейт в терминальное состояние фейла
-func (s *StepContext[DataT, FailDataT, MetaDataT, StepT, TypeT]) Fail() *StepResult[DataT, StepT] {
-	return &StepResult[DataT, StepT]{
+func (s *StepContext[DataT, FailDataT, MetaDataT, StepT, TypeT]) Fail() *StepResult[DataT, FailDataT, MetaDataT, StepT] {
+	return &StepResult[DataT, FailDataT, MetaDataT, StepT]{
 		state: failStepState,
 	}
 }
 
 // Complete переводит стейт в терминальное состояние успеха
-func (s *StepContext[DataT, FailDataT, MetaDataT, StepT, TypeT]) Complete() *StepResult[DataT, StepT] {
-	return &StepResult[DataT, StepT]{
+func (s *StepContext[DataT, FailDataT, MetaDataT, StepT, TypeT]) Complete() *StepResult[DataT, FailDataT, MetaDataT, StepT] {
+	return &StepResult[DataT, FailDataT, MetaDataT, StepT]{
 		state: completeStepState,
 	}
 }
 
 // StepResult результат работы стейта
-type StepResult[DataT any, StepT ~string] struct {
+type StepResult[DataT any, FailDataT any, MetaDataT any, StepT ~string] struct {
 	// Указание следующего шага на который должен перейти степпер
 	nextStatus *StepT
 	// Новое состояние
 	newData *DataT
+	// Данные фейла стейта
+	newFailData *FailDataT
+	// Новые метаданные стейта
+	newMetaData *MetaDataT
 	// Состояние шага, степер понимает что дальше с ним делать
 	state stepState
 	// Сохраняем ошибку которая произошла в результате выполнения шага
 	err error
+	// Задержка следующего выполнения шага относительно завершения текущего
+	retryAfter *time.Duration
+	// Время следующего выполнения шага
+	nextRunAt *time.Time
 }
 
-func (s *StepResult[DataT, StepT]) WithData(newData DataT) *StepResult[DataT, StepT] {
+func (s *StepResult[DataT, FailDataT, MetaDataT, StepT]) WithData(newData DataT) *StepResult[DataT, FailDataT, MetaDataT, StepT] {
 	s.newData = &newData
 	return s
 }
 
+// WithFailData данные причины фейла стейта, сохраняются только при переводе стейта в статус фейла (Fail)
+func (s *StepResult[DataT, FailDataT, MetaDataT, StepT]) WithFailData(failData FailDataT) *StepResult[DataT, FailDataT, MetaDataT, StepT] {
+	s.newFailData = &failData
+	return s
+}
+
+// WithMetaData заменяет метаданные стейта, сохраняются вместе с результатом шага при любом исходе
+func (s *StepResult[DataT, FailDataT, MetaDataT, StepT]) WithMetaData(metaData MetaDataT) *StepResult[DataT, FailDataT, MetaDataT, StepT] {
+	s.newMetaData = &metaData
+	return s
+}
+
 // StepFunc функция выполняющая логику шага
 type StepFunc[
 	DataT any, FailDataT any, MetaDataT any, StepT ~string, TypeT ~string,
-] func(context.Context, StepContext[DataT, FailDataT, MetaDataT, StepT, TypeT]) *StepResult[DataT, StepT]
+] func(context.Context, StepContext[DataT, FailDataT, MetaDataT, StepT, TypeT]) *StepResult[DataT, FailDataT, MetaDataT, StepT]
@@ -3,11 +3,13 @@ package statemachine
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"time"
 
 	"github.com/samber/lo"
 
-	"github.com/kkiling/statemachine/internal/storage"
+	"github.com/kkiling/statemachine/storage"
 )
 
 // Stepper выполняет шаги стейт машины
@@ -15,6 +17,12 @@ type Stepper[DataT any, FailDataT any, MetaDataT any, StepT ~string, TypeT ~stri
 	storage Storage
 	clock   Clock
 	steps   map[StepT]Step[DataT, FailDataT, MetaDataT, StepT, TypeT]
+	// dataSnapshots сохранять снимки данных стейта в истории выполнения
+	dataSnapshots bool
+	// defaultTimeout ограничение времени выполнения шагов без Step.Timeout
+	defaultTimeout time.Duration
+	// maxTransitions максимальное количество переходов между шагами за один вызов Compete, см. Config.MaxTransitions
+	maxTransitions int
 }
 
 func NewStepper[DataT any, FailDataT any, MetaDataT any, StepT ~string, TypeT ~string](
@@ -22,9 +30,10 @@ func NewStepper[DataT any, FailDataT any, MetaDataT any, StepT ~string, TypeT ~s
 	clock Clock,
 ) *Stepper[DataT, FailDataT, MetaDataT, StepT, TypeT] {
 	return &Stepper[DataT, FailDataT, MetaDataT, StepT, TypeT]{
-		storage: storage,
-		clock:   clock,
-		steps:   make(map[StepT]Step[DataT, FailDataT, MetaDataT, StepT, TypeT]),
+		storage:        storage,
+		clock:          clock,
+		steps:          make(map[StepT]Step[DataT, FailDataT, MetaDataT, StepT, TypeT]),
+		maxTransitions: defaultMaxTransitions,
 	}
 }
 
@@ -56,12 +65,24 @@ func (s *Stepper[DataT, FailDataT, MetaDataT, StepT, TypeT]) Compete(
 	}()
 
 	currentState := inputState
+	guard, err := newLoopGuard(s.maxTransitions, string(currentState.Step), currentState.Data, currentState.MetaData)
+	if err != nil {
+		return nil, nil, err
+	}
 
 	// Крутим стейт машину
-	for ctx.Err() == nil {
+	for executed := 0; ctx.Err() == nil; executed++ {
+		if executed > 0 {
+			// Во время выполнения предыдущего шага стейт мог быть изменен другим обработчиком (отмена,
+			// перевод на другой шаг), приостановлен или приостановлены все стейты его типа
+			if err = s.checkState(ctx, currentState); err != nil {
+				return nil, nil, err
+			}
+		}
+
 		stepInfo, ok := s.steps[currentState.Step]
 		if !ok {
-			return nil, nil, fmt.Errorf("unknown step %s", currentState.Step)
+			return nil, nil, fmt.Errorf("%w: %s", ErrUnknownStep, currentState.Step)
 		}
 
 		execute := storage.StepExecuteInfo{
@@ -76,14 +97,22 @@ func (s *Stepper[DataT, FailDataT, MetaDataT, StepT, TypeT]) Compete(
 			return nil, nil, ErrOptionsIsUndefined
 		}
 
+		if s.dataSnapshots {
+			// Снимок данных до выполнения шага
+			if execute.DataBefore, err = marshalDataSnapshot(currentState.Data); err != nil {
+				return nil, nil, err
+			}
+		}
+
 		// Выполнение шага
 		stepCtx := StepContext[DataT, FailDataT, MetaDataT, StepT, TypeT]{
 			State:               currentState,
 			completeOptionsType: stepInfo.OptionsType,
 			completeOptions:     completeOptions,
+			startExecutedAt:     execute.StartExecutedAt,
 		}
 
-		stepResult := stepInfo.OnStep(ctx, stepCtx)
+		stepResult := s.callStepWithTimeout(ctx, stepInfo, stepCtx)
 		newState := currentState
 
 		// Фиксация времени выполнения шага
@@ -92,20 +121,42 @@ func (s *Stepper[DataT, FailDataT, MetaDataT, StepT, TypeT]) Compete(
 			// Обновляем данные стейта
 			newState.Data = *stepResult.newData
 		}
+		if stepResult.newMetaData != nil {
+			// Обновляем метаданные стейта
+			newState.MetaData = *stepResult.newMetaData
+		}
 		newState.Error = nil
+		newState.NextRunAt = nil
+		newState.StepAttempts++
+		stepErr := stepResult.err
 
 		// Обработка
 		isBreak := false
 		switch stepResult.state {
 		case emptyStepState:
-			// Шаг не двигаем
+			// Шаг не двигаем, при необходимости откладываем следующее выполнение
+			switch {
+			case stepResult.retryAfter != nil:
+				newState.NextRunAt = lo.ToPtr(execute.CompleteExecutedAt.Add(*stepResult.retryAfter))
+			case stepResult.nextRunAt != nil:
+				newState.NextRunAt = stepResult.nextRunAt
+			}
+			newState.PanicAttempts = 0
 			isBreak = true
 		case errorStepState:
 			// Сохранение ошибки выполнения шага если была
 			execute.Error = lo.ToPtr(stepResult.err.Error())
 			newState.Error = execute.Error
+			newState.FailedAttempts++
+			// Паники шага считаются отдельно, ошибка без паники сбрасывает счетчик
+			newState.PanicAttempts = lo.Ternary(isStepPanic(stepErr), newState.PanicAttempts+1, 0)
 			// Шаг не двигаем
 			isBreak = true
+
+			// Проверяем, можно ли повторить шаг, или стейт нужно зафейлить
+			if failErr := s.applyRetryPolicy(stepInfo, &newState, stepErr, execute.CompleteExecutedAt); failErr != nil {
+				stepErr = failErr
+			}
 		case nextStepState:
 
 			if newState.Status == NewStatus {
@@ -116,17 +167,30 @@ func (s *Stepper[DataT, FailDataT, MetaDataT, StepT, TypeT]) Compete(
 			}
 			newState.Step = *stepResult.nextStatus
 			newState.UpdatedAt = execute.CompleteExecutedAt
+			newState.FailedAttempts = 0
+			newState.StepAttempts = 0
+			newState.PanicAttempts = 0
 			execute.NextStep = lo.Ternary(stepResult.nextStatus != nil,
 				lo.ToPtr(string(*stepResult.nextStatus)), nil)
 		case failStepState:
+			if stepResult.newFailData != nil {
+				newState.FailData = *stepResult.newFailData
+			}
 			newState.Status = FailedStatus
 			newState.UpdatedAt = execute.CompleteExecutedAt
+			newState.FailedStep = newState.Step
 			newState.Step = ""
+			newState.FailedAttempts = 0
+			newState.StepAttempts = 0
+			newState.PanicAttempts = 0
 			isBreak = true
 		case completeStepState:
 			newState.Status = CompletedStatus
 			newState.UpdatedAt = execute.CompleteExecutedAt
 			newState.Step = ""
+			newState.FailedAttempts = 0
+			newState.StepAttempts = 0
+			newState.PanicAttempts = 0
 			isBreak = true
 		}
 
@@ -139,6 +203,10 @@ func (s *Stepper[DataT, FailDataT, MetaDataT, StepT, TypeT]) Compete(
 		if string(data) == "null" {
 			data = []byte{}
 		}
+		if s.dataSnapshots {
+			// Снимок данных после выполнения шага
+			execute.DataAfter = data
+		}
 
 		var failData []byte
 		failData, err = json.Marshal(newState.FailData)
@@ -158,19 +226,25 @@ func (s *Stepper[DataT, FailDataT, MetaDataT, StepT, TypeT]) Compete(
 			metaData = []byte{}
 		}
 		err = s.storage.RunTransaction(ctx, func(ctxTx context.Context) error {
-			terr := s.storage.SaveStepExecuteInfo(ctx, execute)
+			terr := s.storage.SaveStepExecuteInfo(ctxTx, execute)
 			if terr != nil {
 				return fmt.Errorf("storage.SaveStepExecuteInfo: %w", terr)
 			}
 
-			terr = s.storage.UpdateState(ctx, newState.ID, storage.UpdateState{
-				UpdatedAt: newState.UpdatedAt,
-				Status:    newState.Status,
-				Step:      string(newState.Step),
-				Data:      data,
-				FailData:  failData,
-				MetaData:  metaData,
-				Error:     newState.Error,
+			terr = s.storage.UpdateState(ctxTx, newState.ID, storage.UpdateState{
+				UpdatedAt:      newState.UpdatedAt,
+				Status:         newState.Status,
+				Step:           string(newState.Step),
+				Data:           data,
+				FailData:       failData,
+				MetaData:       metaData,
+				Error:          newState.Error,
+				Version:        newState.Version,
+				NextRunAt:      newState.NextRunAt,
+				FailedAttempts: newState.FailedAttempts,
+				StepAttempts:   newState.StepAttempts,
+				PanicAttempts:  newState.PanicAttempts,
+				FailedStep:     string(newState.FailedStep),
 			})
 			if terr != nil {
 				return fmt.Errorf("storage.UpdateState: %w", terr)
@@ -178,13 +252,28 @@ func (s *Stepper[DataT, FailDataT, MetaDataT, StepT, TypeT]) Compete(
 			return nil
 		})
 
-		if err != nil {
+		switch {
+		case err == nil:
+			newState.Version++
+		case errors.Is(err, storage.ErrConcurrentModification):
+			// Стейт изменен другим обработчиком, результат шага не сохраняем и прекращаем выполнение
+			return nil, nil, ErrConcurrentModification
+		default:
 			return nil, nil, fmt.Errorf("storage.RunTransaction: %w", err)
 		}
 
 		if isBreak {
 			// Возвращаем ошибку которую получили во время выполнения шага
-			return &newState, stepResult.err, nil
+			return &newState, stepErr, nil
+		}
+
+		if loopErr := guard.visit(string(newState.Step), data, metaData); loopErr != nil {
+			// Стейт приостанавливается, управление возвращается вызывающему
+			parked, parkErr := s.park(ctx, newState, loopErr)
+			if parkErr != nil {
+				return nil, nil, parkErr
+			}
+			return parked, loopErr, nil
 		}
 
 		currentState = newState
@@ -194,3 +283,113 @@ func (s *Stepper[DataT, FailDataT, MetaDataT, StepT, TypeT]) Compete(
 
 	return &currentState, nil, nil
 }
+
+// checkState проверяет стейт перед выполнением следующего шага. Возвращает ErrConcurrentModification,
+// если стейт изменен с момента сохранения предыдущего шага, и ErrPaused, если выполнение стейта
+// или всех стейтов его типа приостановлено
+func (s *Stepper[DataT, FailDataT, MetaDataT, StepT, TypeT]) checkState(
+	ctx context.Context,
+	state State[DataT, FailDataT, MetaDataT, StepT, TypeT],
+) error {
+	findState, err := s.storage.GetStateByID(ctx, state.ID)
+	if err != nil {
+		return fmt.Errorf("storage.GetStateByID: %w", err)
+	}
+	if findState.Version != state.Version {
+		return ErrConcurrentModification
+	}
+	if findState.Paused {
+		return ErrPaused
+	}
+
+	paused, err := s.storage.IsTypePaused(ctx, string(state.Type))
+	if err != nil {
+		return fmt.Errorf("storage.IsTypePaused: %w", err)
+	}
+	if paused {
+		return ErrPaused
+	}
+	return nil
+}
+
+// callStepWithTimeout выполняет функцию шага с ограничением времени выполнения шага.
+// Если время истекло, ошибка выполнения шага заменяется на *StepTimeoutError
+func (s *Stepper[DataT, FailDataT, MetaDataT, StepT, TypeT]) callStepWithTimeout(
+	ctx context.Context,
+	stepInfo Step[DataT, FailDataT, MetaDataT, StepT, TypeT],
+	stepCtx StepContext[DataT, FailDataT, MetaDataT, StepT, TypeT],
+) *StepResult[DataT, FailDataT, MetaDataT, StepT] {
+	timeout := lo.Ternary(stepInfo.Timeout > 0, stepInfo.Timeout, s.defaultTimeout)
+	if timeout <= 0 {
+		return callStep(ctx, stepInfo.OnStep, stepCtx)
+	}
+
+	timeoutErr := &StepTimeoutError{Step: string(stepCtx.State.Step), Timeout: timeout}
+	runCtx, cancel := context.WithTimeoutCause(ctx, timeout, timeoutErr)
+	defer cancel()
+
+	stepResult := callStep(runCtx, stepInfo.OnStep, stepCtx)
+	if context.Cause(runCtx) != timeoutErr {
+		return stepResult
+	}
+	// После таймаута ошибка и пустой результат шага (например, шаг проглотил ctx.Err() и вернул Empty)
+	// заменяются на *StepTimeoutError. Результат Next, Complete или Fail, возвращенный шагом после таймаута,
+	// сохраняется: шаг успел выполнить свою работу
+	switch stepResult.state {
+	case errorStepState:
+		timeoutErr.Err = stepResult.err
+		stepResult.err = timeoutErr
+	case emptyStepState:
+		timeoutErr.Err = runCtx.Err()
+		stepResult.state = errorStepState
+		stepResult.err = timeoutErr
+		stepResult.retryAfter = nil
+		stepResult.nextRunAt = nil
+	}
+	return stepResult
+}
+
+// applyRetryPolicy откладывает повторное выполнение шага после ошибки согласно политикам шага,
+// либо переводит стейт в статус фейла, если шаг повторить нельзя. Возвращает причину фейла
+func (s *Stepper[DataT, FailDataT, MetaDataT, StepT, TypeT]) applyRetryPolicy(
+	step Step[DataT, FailDataT, MetaDataT, StepT, TypeT],
+	newState *State[DataT, FailDataT, MetaDataT, StepT, TypeT],
+	stepErr error,
+	completeExecutedAt time.Time,
+) error {
+	policy := step.RetryPolicy
+
+	var reason error
+	switch {
+	case step.PanicPolicy.isExhausted(stepErr, newState.PanicAttempts):
+		reason = ErrPanicAttemptsExhausted
+	case policy == nil:
+		return nil
+	case !policy.isRetryable(stepErr):
+		reason = ErrNotRetryable
+	case policy.isExhausted(newState.FailedAttempts):
+		reason = ErrRetryAttemptsExhausted
+	default:
+		if backoff := policy.backoff(newState.FailedAttempts); backoff > 0 {
+			newState.NextRunAt = lo.ToPtr(completeExecutedAt.Add(backoff))
+		}
+		return nil
+	}
+
+	failErr := &StepFailedError{
+		Step:     string(newState.Step),
+		Attempts: newState.FailedAttempts,
+		Reason:   reason,
+		Err:      stepErr,
+	}
+	newState.Status = FailedStatus
+	newState.UpdatedAt = completeExecutedAt
+	newState.FailedStep = newState.Step
+	newState.Step = ""
+	newState.FailedAttempts = 0
+	newState.StepAttempts = 0
+	newState.PanicAttempts = 0
+	newState.Error = lo.ToPtr(failErr.Error())
+
+	return failErr
+}
@@ -0,0 +1,406 @@
+package statemachine
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/google/uuid"
+	"github.com/samber/lo"
+	"github.com/stretchr/testify/require"
+
+	mock_statemachine "github.com/kkiling/statemachine/mocks"
+	"github.com/kkiling/statemachine/storage"
+)
+
+type (
+	testStepperState   = State[int, any, any, string, string]
+	testStepperStep    = Step[int, any, any, string, string]
+	testStepperContext = StepContext[int, any, any, string, string]
+	testStepperResult  = StepResult[int, any, any, string]
+)
+
+type stepperTestDeps struct {
+	ctx         context.Context
+	clock       *mock_statemachine.MockClock
+	storageMock *mock_statemachine.MockStorage
+	stepper     *Stepper[int, any, any, string, string]
+}
+
+func setupStepperTestDeps(t *testing.T, steps map[string]testStepperStep) *stepperTestDeps {
+	ctrl := gomock.NewController(t)
+	deps := &stepperTestDeps{
+		ctx:         context.Background(),
+		clock:       mock_statemachine.NewMockClock(ctrl),
+		storageMock: mock_statemachine.NewMockStorage(ctrl),
+	}
+
+	deps.stepper = NewStepper[int, any, any, string, string](deps.storageMock, deps.clock)
+	for step, info := range steps {
+		deps.stepper.Add(step, info)
+	}
+
+	return deps
+}
+
+// expectSaveStep ожидание сохранения результата выполнения шага, возвращает сохраненное обновление стейта
+func (d *stepperTestDeps) expectSaveStep(startExecutedAt, completeExecutedAt time.Time) *storage.UpdateState {
+	update := &storage.UpdateState{}
+
+	d.clock.EXPECT().Now().Return(startExecutedAt)
+	d.clock.EXPECT().Now().Return(completeExecutedAt)
+	d.storageMock.EXPECT().RunTransaction(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(ctx context.Context, txFunc func(context.Context) error) error {
+			return txFunc(ctx)
+		})
+	d.storageMock.EXPECT().SaveStepExecuteInfo(gomock.Any(), gomock.Any())
+	d.storageMock.EXPECT().UpdateState(gomock.Any(), gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, _ uuid.UUID, state storage.UpdateState) error {
+			*update = state
+			return nil
+		})
+
+	return update
+}
+
+// expectStateUnchanged ожидание проверки стейта перед выполнением следующего шага:
+// стейт не изменен другим обработчиком (версия равна version) и не приостановлен
+func (d *stepperTestDeps) expectStateUnchanged(state testStepperState, version int64) {
+	d.storageMock.EXPECT().GetStateByID(gomock.Any(), state.ID).Return(&storage.State{ID: state.ID, Version: version}, nil)
+	d.storageMock.EXPECT().IsTypePaused(gomock.Any(), state.Type).Return(false, nil)
+}
+
+func TestStepper_Compete_NextRunAt(t *testing.T) {
+	t.Parallel()
+
+	var (
+		now                = time.Now()
+		startExecutedAt    = now
+		completeExecutedAt = now.Add(time.Second)
+		scheduledAt        = now.Add(time.Hour)
+	)
+
+	inputState := testStepperState{
+		ID:     uuid.New(),
+		Status: InProgressStatus,
+		Step:   "poll",
+		// Стейт уже был отложен ранее
+		NextRunAt: lo.ToPtr(now.Add(-time.Minute)),
+	}
+
+	t.Run("retry after", func(t *testing.T) {
+		deps := setupStepperTestDeps(t, map[string]testStepperStep{
+			"poll": {
+				OnStep: func(_ context.Context, stepContext testStepperContext) *testStepperResult {
+					return stepContext.RetryAfter(time.Minute)
+				},
+			},
+		})
+		update := deps.expectSaveStep(startExecutedAt, completeExecutedAt)
+
+		res, executeErr, err := deps.stepper.Compete(deps.ctx, inputState)
+		require.NoError(t, err)
+		require.NoError(t, executeErr)
+		// Следующее выполнение отсчитывается от завершения текущего
+		require.Equal(t, lo.ToPtr(completeExecutedAt.Add(time.Minute)), res.NextRunAt)
+		require.Equal(t, res.NextRunAt, update.NextRunAt)
+		require.Equal(t, "poll", res.Step)
+	})
+
+	t.Run("schedule at", func(t *testing.T) {
+		deps := setupStepperTestDeps(t, map[string]testStepperStep{
+			"poll": {
+				OnStep: func(_ context.Context, stepContext testStepperContext) *testStepperResult {
+					return stepContext.ScheduleAt(scheduledAt)
+				},
+			},
+		})
+		update := deps.expectSaveStep(startExecutedAt, completeExecutedAt)
+
+		res, executeErr, err := deps.stepper.Compete(deps.ctx, inputState)
+		require.NoError(t, err)
+		require.NoError(t, executeErr)
+		require.Equal(t, lo.ToPtr(scheduledAt), res.NextRunAt)
+		require.Equal(t, res.NextRunAt, update.NextRunAt)
+	})
+
+	t.Run("reset on empty result", func(t *testing.T) {
+		deps := setupStepperTestDeps(t, map[string]testStepperStep{
+			"poll": {
+				OnStep: func(_ context.Context, stepContext testStepperContext) *testStepperResult {
+					return stepContext.Empty()
+				},
+			},
+		})
+		update := deps.expectSaveStep(startExecutedAt, completeExecutedAt)
+
+		res, executeErr, err := deps.stepper.Compete(deps.ctx, inputState)
+		require.NoError(t, err)
+		require.NoError(t, executeErr)
+		require.Nil(t, res.NextRunAt)
+		require.Nil(t, update.NextRunAt)
+	})
+}
+
+func TestStepper_Compete_RetryPolicy(t *testing.T) {
+	t.Parallel()
+
+	var (
+		now                = time.Now()
+		startExecutedAt    = now
+		completeExecutedAt = now.Add(time.Second)
+		stepErr            = errors.New("external service unavailable")
+		permanentErr       = errors.New("invalid request")
+	)
+
+	newDeps := func(t *testing.T) *stepperTestDeps {
+		return setupStepperTestDeps(t, map[string]testStepperStep{
+			"call": {
+				OnStep: func(_ context.Context, stepContext testStepperContext) *testStepperResult {
+					if stepContext.State.Data > 0 {
+						return stepContext.Error(permanentErr)
+					}
+					return stepContext.Error(stepErr)
+				},
+				RetryPolicy: &RetryPolicy{
+					MaxAttempts:     3,
+					InitialInterval: time.Minute,
+					Retryable: func(err error) bool {
+						return !errors.Is(err, permanentErr)
+					},
+				},
+			},
+		})
+	}
+
+	t.Run("retry with backoff", func(t *testing.T) {
+		deps := newDeps(t)
+		update := deps.expectSaveStep(startExecutedAt, completeExecutedAt)
+
+		res, executeErr, err := deps.stepper.Compete(deps.ctx, testStepperState{
+			Status:         InProgressStatus,
+			Step:           "call",
+			FailedAttempts: 1,
+		})
+		require.NoError(t, err)
+		require.ErrorIs(t, executeErr, stepErr)
+		require.Equal(t, InProgressStatus, res.Status)
+		require.Equal(t, "call", res.Step)
+		require.Equal(t, 2, res.FailedAttempts)
+		require.Equal(t, 2, update.FailedAttempts)
+		// Вторая ошибка подряд, задержка увеличилась вдвое
+		require.Equal(t, lo.ToPtr(completeExecutedAt.Add(2*time.Minute)), update.NextRunAt)
+	})
+
+	t.Run("fail after max attempts", func(t *testing.T) {
+		deps := newDeps(t)
+		update := deps.expectSaveStep(startExecutedAt, completeExecutedAt)
+
+		res, executeErr, err := deps.stepper.Compete(deps.ctx, testStepperState{
+			Status:         InProgressStatus,
+			Step:           "call",
+			FailedAttempts: 2,
+		})
+		require.NoError(t, err)
+		require.ErrorIs(t, executeErr, ErrRetryAttemptsExhausted)
+		require.ErrorIs(t, executeErr, stepErr)
+
+		var failErr *StepFailedError
+		require.ErrorAs(t, executeErr, &failErr)
+		require.Equal(t, "call", failErr.Step)
+		require.Equal(t, 3, failErr.Attempts)
+
+		require.Equal(t, FailedStatus, res.Status)
+		require.Equal(t, "", res.Step)
+		require.Equal(t, "call", res.FailedStep)
+		require.Equal(t, FailedStatus, update.Status)
+		require.Equal(t, "call", update.FailedStep)
+		require.Equal(t, lo.ToPtr(failErr.Error()), update.Error)
+		require.Nil(t, update.NextRunAt)
+	})
+
+	t.Run("fail on not retryable error", func(t *testing.T) {
+		deps := newDeps(t)
+		update := deps.expectSaveStep(startExecutedAt, completeExecutedAt)
+
+		res, executeErr, err := deps.stepper.Compete(deps.ctx, testStepperState{
+			Status: InProgressStatus,
+			Step:   "call",
+			Data:   1,
+		})
+		require.NoError(t, err)
+		require.ErrorIs(t, executeErr, ErrNotRetryable)
+		require.ErrorIs(t, executeErr, permanentErr)
+		require.Equal(t, FailedStatus, res.Status)
+		require.Equal(t, FailedStatus, update.Status)
+	})
+}
+
+func TestStepper_Compete_StepContextAttempt(t *testing.T) {
+	t.Parallel()
+
+	var (
+		createdAt          = time.Now().Add(-time.Hour)
+		enteredAt          = createdAt.Add(10 * time.Minute)
+		startExecutedAt    = time.Now()
+		completeExecutedAt = startExecutedAt.Add(time.Second)
+	)
+
+	inputState := testStepperState{
+		ID:           uuid.New(),
+		CreatedAt:    createdAt,
+		UpdatedAt:    enteredAt,
+		Status:       InProgressStatus,
+		Step:         "poll",
+		StepAttempts: 2,
+	}
+
+	t.Run("repeat step", func(t *testing.T) {
+		var stepContext testStepperContext
+		deps := setupStepperTestDeps(t, map[string]testStepperStep{
+			"poll": {
+				OnStep: func(_ context.Context, sc testStepperContext) *testStepperResult {
+					stepContext = sc
+					return sc.Empty()
+				},
+			},
+		})
+		update := deps.expectSaveStep(startExecutedAt, completeExecutedAt)
+
+		res, executeErr, err := deps.stepper.Compete(deps.ctx, inputState)
+		require.NoError(t, err)
+		require.NoError(t, executeErr)
+
+		require.Equal(t, 3, stepContext.Attempt())
+		require.Equal(t, enteredAt, stepContext.StepEnteredAt())
+		// Возраст стейта отсчитывается от начала выполнения шага
+		require.Equal(t, startExecutedAt.Sub(createdAt), stepContext.StateAge())
+
+		require.Equal(t, 3, res.StepAttempts)
+		require.Equal(t, 3, update.StepAttempts)
+	})
+
+	t.Run("reset on next step", func(t *testing.T) {
+		var attempts []int
+		deps := setupStepperTestDeps(t, map[string]testStepperStep{
+			"poll": {
+				OnStep: func(_ context.Context, sc testStepperContext) *testStepperResult {
+					attempts = append(attempts, sc.Attempt())
+					return sc.Next("done")
+				},
+			},
+			"done": {
+				OnStep: func(_ context.Context, sc testStepperContext) *testStepperResult {
+					attempts = append(attempts, sc.Attempt())
+					require.Equal(t, completeExecutedAt, sc.StepEnteredAt())
+					return sc.Complete()
+				},
+			},
+		})
+		deps.expectSaveStep(startExecutedAt, completeExecutedAt)
+		deps.expectStateUnchanged(inputState, inputState.Version+1)
+		update := deps.expectSaveStep(startExecutedAt, completeExecutedAt)
+
+		res, executeErr, err := deps.stepper.Compete(deps.ctx, inputState)
+		require.NoError(t, err)
+		require.NoError(t, executeErr)
+		require.Equal(t, []int{3, 1}, attempts)
+		require.Equal(t, 0, res.StepAttempts)
+		require.Equal(t, 0, update.StepAttempts)
+	})
+}
+
+func TestStepper_Compete_FailData(t *testing.T) {
+	t.Parallel()
+
+	var (
+		startExecutedAt    = time.Now()
+		completeExecutedAt = startExecutedAt.Add(time.Second)
+	)
+
+	inputState := testStepperState{
+		ID:     uuid.New(),
+		Status: InProgressStatus,
+		Step:   "check",
+	}
+
+	t.Run("fail with data", func(t *testing.T) {
+		deps := setupStepperTestDeps(t, map[string]testStepperStep{
+			"check": {
+				OnStep: func(_ context.Context, stepContext testStepperContext) *testStepperResult {
+					return stepContext.Fail().WithFailData("limit exceeded")
+				},
+			},
+		})
+		update := deps.expectSaveStep(startExecutedAt, completeExecutedAt)
+
+		res, executeErr, err := deps.stepper.Compete(deps.ctx, inputState)
+		require.NoError(t, err)
+		require.NoError(t, executeErr)
+		require.Equal(t, FailedStatus, res.Status)
+		require.Equal(t, "limit exceeded", res.FailData)
+		require.Equal(t, "check", res.FailedStep)
+		require.Equal(t, []byte(`"limit exceeded"`), update.FailData)
+		require.Equal(t, "check", update.FailedStep)
+	})
+
+	t.Run("ignore on not fail result", func(t *testing.T) {
+		deps := setupStepperTestDeps(t, map[string]testStepperStep{
+			"check": {
+				OnStep: func(_ context.Context, stepContext testStepperContext) *testStepperResult {
+					return stepContext.Complete().WithFailData("limit exceeded")
+				},
+			},
+		})
+		update := deps.expectSaveStep(startExecutedAt, completeExecutedAt)
+
+		res, executeErr, err := deps.stepper.Compete(deps.ctx, inputState)
+		require.NoError(t, err)
+		require.NoError(t, executeErr)
+		require.Equal(t, CompletedStatus, res.Status)
+		require.Nil(t, res.FailData)
+		require.Empty(t, update.FailData)
+	})
+}
+
+func TestStepper_Compete_MetaData(t *testing.T) {
+	t.Parallel()
+
+	var (
+		startExecutedAt    = time.Now()
+		completeExecutedAt = startExecutedAt.Add(time.Second)
+	)
+
+	deps := setupStepperTestDeps(t, map[string]testStepperStep{
+		"send": {
+			OnStep: func(_ context.Context, stepContext testStepperContext) *testStepperResult {
+				return stepContext.Next("wait").WithMetaData("correlation-id")
+			},
+		},
+		"wait": {
+			OnStep: func(_ context.Context, stepContext testStepperContext) *testStepperResult {
+				// Метаданные, сохраненные предыдущим шагом, доступны следующему
+				require.Equal(t, "correlation-id", stepContext.State.MetaData)
+				return stepContext.Empty()
+			},
+		},
+	})
+	inputState := testStepperState{
+		ID:       uuid.New(),
+		Status:   InProgressStatus,
+		Step:     "send",
+		MetaData: "initial",
+	}
+	first := deps.expectSaveStep(startExecutedAt, completeExecutedAt)
+	deps.expectStateUnchanged(inputState, inputState.Version+1)
+	second := deps.expectSaveStep(startExecutedAt, completeExecutedAt)
+
+	res, executeErr, err := deps.stepper.Compete(deps.ctx, inputState)
+	require.NoError(t, err)
+	require.NoError(t, executeErr)
+	require.Equal(t, "correlation-id", res.MetaData)
+	require.Equal(t, []byte(`"correlation-id"`), first.MetaData)
+	require.Equal(t, []byte(`"correlation-id"`), second.MetaData)
+}
@@ -0,0 +1,36 @@
+// Package storage модель хранения стейт машины и контракт для реализаций statemachine.Storage.
+//
+// Хранилище может быть реализовано вне модуля (in-memory, Redis, SQLite, декоратор над существующим),
+// для этого достаточно реализовать интерфейс statemachine.Storage, соблюдая контракт:
+//
+//   - GetStateByID, GetStateByIdempotencyKey возвращают ErrNotFound, если стейт не найден;
+//     CreateState возвращает ErrAlreadyExists при повторе ID или ключа идемпотентности.
+//   - RunTransaction выполняет txFunc атомарно: при ошибке txFunc все изменения, сделанные
+//     через переданный ctxTx, откатываются, а ошибка возвращается без изменений (через %w).
+//     Методы хранилища, вызванные с ctxTx, должны выполняться в этой транзакции.
+//   - GetStepExecuteInfos возвращает историю выполнения шагов стейта в порядке StartExecutedAt,
+//     записи с одинаковым временем начала - в порядке сохранения. Для неизвестного стейта - пустой список.
+//   - UpdateState обновляет стейт, только если его текущая версия равна UpdateState.Version,
+//     после чего версия увеличивается на 1. При несовпадении версии возвращается
+//     ErrConcurrentModification, если стейта нет - ErrNotFound.
+//   - ClaimState захватывает стейт в аренду (Lease), если у него нет аренды или она истекла
+//     к моменту Lease.AcquiredAt. Если стейт арендован другим обработчиком - ErrLocked.
+//   - ClaimReadyStates атомарно захватывает до ReadyStatesFilter.Limit стейтов указанного типа
+//     и статусов, без действующей аренды и с NextRunAt не позже Lease.AcquiredAt,
+//     в порядке UpdatedAt. Один стейт не может быть выдан двум обработчикам одновременно.
+//     Приостановленные стейты (State.Paused) и стейты приостановленного типа (PauseType) не выдаются.
+//   - ListStates возвращает до ListStatesFilter.Limit стейтов, подходящих под все заданные условия фильтра,
+//     в порядке возрастания (CreatedAt, ID), строго после курсора After, если он задан.
+//   - RenewLease продлевает аренду до Lease.ExpiresAt, только если ее владелец совпадает с Lease.Owner
+//     (в том числе если аренда истекла, но стейт никто не захватил). Если стейт арендован
+//     другим обработчиком или аренда снята - ErrLocked, если стейта нет - ErrNotFound.
+//   - ReleaseState снимает аренду, только если ее владелец совпадает с owner.
+//   - SetStatePaused изменяет только признак State.Paused, если текущая версия стейта равна version,
+//     не меняя версию. При несовпадении версии возвращается ErrConcurrentModification, если стейта нет - ErrNotFound.
+//     PauseType и ResumeType идемпотентны.
+//   - Поля Data, FailData, MetaData стейта и DataBefore, DataAfter истории выполнения хранятся
+//     как есть (JSON), пустое значение равнозначно отсутствию данных.
+//   - При отмене ctx методы возвращают ошибку контекста.
+//
+// Соответствие контракту проверяется набором тестов storagetest.Run.
+package storage
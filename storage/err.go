@@ -0,0 +1,20 @@
+package storage
+
+import (
+	"errors"
+
+	"github.com/kkiling/goplatform/storagebase"
+)
+
+var (
+	// ErrNotFound запись не найдена
+	ErrNotFound = storagebase.ErrNotFound
+	// ErrAlreadyExists запись уже существует
+	ErrAlreadyExists = storagebase.ErrAlreadyExists
+	// ErrForeignKeyViolation связанная запись не найдена
+	ErrForeignKeyViolation = storagebase.ErrForeignKeyViolation
+	// ErrLocked запись захвачена другим обработчиком
+	ErrLocked = errors.New("entity locked")
+	// ErrConcurrentModification запись была изменена с момента чтения
+	ErrConcurrentModification = errors.New("entity concurrently modified")
+)
@@ -0,0 +1,160 @@
+package storage
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// State состояние стейт машины
+type State struct {
+	// ID идентификаторе текущего стейта
+	ID uuid.UUID
+	// IdempotencyKey Ключ идемпотентности стейта
+	IdempotencyKey string
+	//  CreatedAt дата создания состояния
+	CreatedAt time.Time
+	// UpdatedAt дата обновления Status или Step
+	UpdatedAt time.Time
+	// Status статус
+	Status uint8
+	// Step текущий шаг
+	Step string
+	// Type тип состояния
+	Type string
+	// Data данные стейта
+	Data []byte
+	// FailData Данные фейла стейта
+	FailData []byte
+	// MetaDataT методаные стейта
+	MetaData []byte
+	// Ошибка выполнения
+	Error *string
+	// Version версия стейта, увеличивается при каждом обновлении
+	Version int64
+	// NextRunAt время, раньше которого стейт не должен выполняться
+	NextRunAt *time.Time
+	// FailedAttempts количество выполнений текущего шага, завершившихся ошибкой
+	FailedAttempts int
+	// StepAttempts количество завершенных выполнений текущего шага
+	StepAttempts int
+	// PanicAttempts количество паник текущего шага подряд
+	PanicAttempts int
+	// Paused стейт приостановлен, изменяется только через SetStatePaused
+	Paused bool
+	// FailedStep шаг, на котором стейт перешел в статус фейла
+	FailedStep string
+}
+
+// UpdateState структура для обновление состояния стейт машины
+type UpdateState struct {
+	// UpdatedAt дата обновления Status или Step
+	UpdatedAt time.Time
+	// Status статус
+	Status uint8
+	// Step текущий шаг
+	Step string
+	// Data данные стейта
+	Data []byte
+	// FailData Данные фейла стейта
+	FailData []byte
+	// MetaDataT методаные стейта
+	MetaData []byte
+	// Ошибка выполнения
+	Error *string
+	// Version ожидаемая текущая версия стейта, при несовпадении обновление не выполняется
+	Version int64
+	// NextRunAt время, раньше которого стейт не должен выполняться
+	NextRunAt *time.Time
+	// FailedAttempts количество выполнений текущего шага, завершившихся ошибкой
+	FailedAttempts int
+	// StepAttempts количество завершенных выполнений текущего шага
+	StepAttempts int
+	// PanicAttempts количество паник текущего шага подряд
+	PanicAttempts int
+	// FailedStep шаг, на котором стейт перешел в статус фейла
+	FailedStep string
+}
+
+// StepExecuteInfo Информация о выполнении шагов стейт машины
+type StepExecuteInfo struct {
+	StateID            uuid.UUID
+	StartExecutedAt    time.Time
+	CompleteExecutedAt time.Time
+	Error              *string
+	PreviewStep        string
+	NextStep           *string
+	// Reason причина ручного вмешательства оператора (отмена, повтор, переход на шаг)
+	Reason *string
+	// DataBefore данные стейта (JSON) до выполнения шага, если включено сохранение снимков данных
+	DataBefore []byte
+	// DataAfter данные стейта (JSON) после выполнения шага, если включено сохранение снимков данных
+	DataAfter []byte
+}
+
+// ListStatesFilter фильтр постраничной выборки стейтов в порядке (CreatedAt, ID).
+// Пустые значения полей не ограничивают выборку
+type ListStatesFilter struct {
+	// Type тип состояния
+	Type string
+	// Statuses допустимые статусы
+	Statuses []uint8
+	// Steps допустимые шаги
+	Steps []string
+	// CreatedFrom начало диапазона времени создания (включительно)
+	CreatedFrom *time.Time
+	// CreatedTo конец диапазона времени создания (не включительно)
+	CreatedTo *time.Time
+	// UpdatedFrom начало диапазона времени обновления (включительно)
+	UpdatedFrom *time.Time
+	// UpdatedTo конец диапазона времени обновления (не включительно)
+	UpdatedTo *time.Time
+	// HasError true - только стейты с ошибкой, false - только без ошибки
+	HasError *bool
+	// After курсор, выборка начинается со стейтов, следующих после него
+	After *ListCursor
+	// Limit максимальное количество стейтов в выборке
+	Limit int
+}
+
+// ListCursor позиция стейта в выборке ListStates
+type ListCursor struct {
+	CreatedAt time.Time
+	ID        uuid.UUID
+}
+
+// PauseEvent запись о приостановке или возобновлении выполнения стейта либо всех стейтов типа
+type PauseEvent struct {
+	// Type тип состояния
+	Type string
+	// StateID идентификатор стейта (nil - событие относится ко всем стейтам типа)
+	StateID *uuid.UUID
+	// Paused true - приостановка, false - возобновление
+	Paused bool
+	// CreatedAt время события
+	CreatedAt time.Time
+	// CreatedBy кто выполнил действие
+	CreatedBy string
+	// Reason причина действия
+	Reason string
+}
+
+// Lease аренда стейта обработчиком на время выполнения
+type Lease struct {
+	// Owner идентификатор владельца аренды
+	Owner string
+	// AcquiredAt время захвата, аренды истекшие к этому моменту могут быть перехвачены
+	AcquiredAt time.Time
+	// ExpiresAt время истечения аренды
+	ExpiresAt time.Time
+}
+
+// ReadyStatesFilter фильтр выборки стейтов, готовых к выполнению
+type ReadyStatesFilter struct {
+	// Type тип состояния
+	Type string
+	// Statuses статусы, в которых стейт считается готовым к выполнению
+	Statuses []uint8
+	// Limit максимальное количество стейтов в выборке
+	Limit int
+}
@@ -0,0 +1,145 @@
+package storagetest
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/samber/lo"
+	"github.com/stretchr/testify/require"
+
+	"github.com/kkiling/statemachine/storage"
+)
+
+func testListStates(t *testing.T, factory Factory) {
+	t.Parallel()
+	s := factory(t)
+	ctx := context.Background()
+	now := time.Now().UTC().Truncate(time.Second)
+
+	// Уникальный тип, чтобы не пересекаться с другими тестами
+	stateType := uuid.NewString()
+	createTestState := func(t *testing.T, createdAt time.Time, status uint8, step string, stateErr *string) *storage.State {
+		state := newTestState()
+		state.Type = stateType
+		state.CreatedAt = createdAt
+		state.UpdatedAt = createdAt.Add(time.Minute)
+		state.Status = status
+		state.Step = step
+		require.NoError(t, s.CreateState(ctx, state))
+		if stateErr != nil {
+			// Ошибка выполнения сохраняется только при обновлении стейта
+			require.NoError(t, s.UpdateState(ctx, state.ID, storage.UpdateState{
+				UpdatedAt: state.UpdatedAt,
+				Status:    state.Status,
+				Step:      state.Step,
+				Data:      state.Data,
+				Error:     stateErr,
+			}))
+		}
+		return state
+	}
+	stateIDs := func(states []storage.State) []uuid.UUID {
+		return lo.Map(states, func(item storage.State, _ int) uuid.UUID {
+			return item.ID
+		})
+	}
+
+	first := createTestState(t, now, 1, "initial", nil)
+	second := createTestState(t, now.Add(time.Second), 2, "next", lo.ToPtr("step error"))
+	third := createTestState(t, now.Add(2*time.Second), 2, "initial", nil)
+	// Стейт с тем же временем создания, порядок определяется по ID
+	fourth := createTestState(t, now.Add(2*time.Second), 3, "", nil)
+	// Стейт другого типа не попадает в выборку
+	other := newTestState()
+	other.Type = uuid.NewString()
+	require.NoError(t, s.CreateState(ctx, other))
+
+	ordered := []uuid.UUID{first.ID, second.ID, third.ID, fourth.ID}
+	if string(third.ID[:]) > string(fourth.ID[:]) {
+		ordered[2], ordered[3] = ordered[3], ordered[2]
+	}
+
+	t.Run("by type", func(t *testing.T) {
+		states, err := s.ListStates(ctx, storage.ListStatesFilter{Type: stateType, Limit: 10})
+		require.NoError(t, err)
+		require.Equal(t, ordered, stateIDs(states))
+		stateEqual(t, first, &states[0])
+	})
+
+	tests := []struct {
+		name     string
+		filter   storage.ListStatesFilter
+		expected []uuid.UUID
+	}{
+		{
+			name:     "statuses",
+			filter:   storage.ListStatesFilter{Statuses: []uint8{2, 3}},
+			expected: ordered[1:],
+		},
+		{
+			name:     "steps",
+			filter:   storage.ListStatesFilter{Steps: []string{"next", ""}},
+			expected: []uuid.UUID{second.ID, fourth.ID},
+		},
+		{
+			name: "created range",
+			filter: storage.ListStatesFilter{
+				Statuses:    []uint8{2, 3},
+				CreatedFrom: lo.ToPtr(now.Add(time.Second)),
+				CreatedTo:   lo.ToPtr(now.Add(2 * time.Second)),
+			},
+			expected: []uuid.UUID{second.ID},
+		},
+		{
+			name: "updated range",
+			filter: storage.ListStatesFilter{
+				Statuses:    []uint8{2, 3},
+				UpdatedFrom: lo.ToPtr(now.Add(time.Minute + 2*time.Second)),
+			},
+			expected: ordered[2:],
+		},
+		{
+			name:     "has error",
+			filter:   storage.ListStatesFilter{HasError: lo.ToPtr(true)},
+			expected: []uuid.UUID{second.ID},
+		},
+		{
+			name:     "without error",
+			filter:   storage.ListStatesFilter{Statuses: []uint8{2, 3}, HasError: lo.ToPtr(false)},
+			expected: ordered[2:],
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filter := tt.filter
+			filter.Type = stateType
+			filter.Limit = 10
+			states, err := s.ListStates(ctx, filter)
+			require.NoError(t, err)
+			require.Equal(t, tt.expected, stateIDs(states))
+		})
+	}
+
+	t.Run("pagination", func(t *testing.T) {
+		filter := storage.ListStatesFilter{
+			Type:     stateType,
+			Statuses: []uint8{1, 2, 3},
+			Limit:    2,
+		}
+		var res []uuid.UUID
+		for range 3 {
+			states, err := s.ListStates(ctx, filter)
+			require.NoError(t, err)
+			require.LessOrEqual(t, len(states), filter.Limit)
+			if len(states) == 0 {
+				break
+			}
+			res = append(res, stateIDs(states)...)
+			last := states[len(states)-1]
+			filter.After = &storage.ListCursor{CreatedAt: last.CreatedAt, ID: last.ID}
+		}
+		require.Equal(t, ordered, res)
+	})
+}
@@ -0,0 +1,219 @@
+package storagetest
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/samber/lo"
+	"github.com/stretchr/testify/require"
+
+	"github.com/kkiling/statemachine/storage"
+)
+
+func testSetStatePaused(t *testing.T, factory Factory) {
+	t.Parallel()
+	s := factory(t)
+	ctx := context.Background()
+
+	t.Run("pause and resume", func(t *testing.T) {
+		state := newTestState()
+		require.NoError(t, s.CreateState(ctx, state))
+
+		require.NoError(t, s.SetStatePaused(ctx, state.ID, true, state.Version))
+		findState, err := s.GetStateByID(ctx, state.ID)
+		require.NoError(t, err)
+		require.True(t, findState.Paused)
+		// Приостановка не изменяет версию стейта
+		require.Equal(t, int64(0), findState.Version)
+
+		// Обновление стейта не снимает приостановку
+		require.NoError(t, s.UpdateState(ctx, state.ID, storage.UpdateState{
+			UpdatedAt: state.UpdatedAt,
+			Status:    state.Status,
+			Step:      "next",
+			Version:   findState.Version,
+		}))
+		findState, err = s.GetStateByID(ctx, state.ID)
+		require.NoError(t, err)
+		require.True(t, findState.Paused)
+
+		require.NoError(t, s.SetStatePaused(ctx, state.ID, false, findState.Version))
+		findState, err = s.GetStateByID(ctx, state.ID)
+		require.NoError(t, err)
+		require.False(t, findState.Paused)
+	})
+
+	t.Run("version mismatch", func(t *testing.T) {
+		state := newTestState()
+		require.NoError(t, s.CreateState(ctx, state))
+
+		err := s.SetStatePaused(ctx, state.ID, true, state.Version+1)
+		require.ErrorIs(t, err, storage.ErrConcurrentModification)
+
+		findState, err := s.GetStateByID(ctx, state.ID)
+		require.NoError(t, err)
+		require.False(t, findState.Paused)
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		err := s.SetStatePaused(ctx, uuid.New(), true, 0)
+		require.ErrorIs(t, err, storage.ErrNotFound)
+	})
+}
+
+func testPauseType(t *testing.T, factory Factory) {
+	t.Parallel()
+	s := factory(t)
+	ctx := context.Background()
+	now := time.Now().UTC().Truncate(time.Second)
+	// Уникальный тип, чтобы не пересекаться с другими тестами
+	stateType := uuid.NewString()
+
+	paused, err := s.IsTypePaused(ctx, stateType)
+	require.NoError(t, err)
+	require.False(t, paused)
+
+	// Повторная приостановка допустима
+	require.NoError(t, s.PauseType(ctx, stateType, now))
+	require.NoError(t, s.PauseType(ctx, stateType, now.Add(time.Second)))
+
+	paused, err = s.IsTypePaused(ctx, stateType)
+	require.NoError(t, err)
+	require.True(t, paused)
+
+	// Приостановка не затрагивает другие типы
+	paused, err = s.IsTypePaused(ctx, uuid.NewString())
+	require.NoError(t, err)
+	require.False(t, paused)
+
+	require.NoError(t, s.ResumeType(ctx, stateType))
+	require.NoError(t, s.ResumeType(ctx, stateType))
+
+	paused, err = s.IsTypePaused(ctx, stateType)
+	require.NoError(t, err)
+	require.False(t, paused)
+}
+
+func testClaimReadyStatesPaused(t *testing.T, factory Factory) {
+	t.Parallel()
+	s := factory(t)
+	ctx := context.Background()
+	now := time.Now().UTC().Truncate(time.Second)
+	lease := storage.Lease{Owner: "worker_1", AcquiredAt: now, ExpiresAt: now.Add(time.Minute)}
+
+	createTestState := func(t *testing.T, stateType string) *storage.State {
+		state := newTestState()
+		state.Type = stateType
+		require.NoError(t, s.CreateState(ctx, state))
+		return state
+	}
+
+	t.Run("paused state", func(t *testing.T) {
+		stateType := uuid.NewString()
+		ready := createTestState(t, stateType)
+		paused := createTestState(t, stateType)
+		require.NoError(t, s.SetStatePaused(ctx, paused.ID, true, paused.Version))
+
+		states, err := s.ClaimReadyStates(ctx, storage.ReadyStatesFilter{
+			Type:     stateType,
+			Statuses: []uint8{ready.Status},
+			Limit:    10,
+		}, lease)
+		require.NoError(t, err)
+		require.Equal(t, []uuid.UUID{ready.ID}, lo.Map(states, func(item storage.State, _ int) uuid.UUID {
+			return item.ID
+		}))
+	})
+
+	t.Run("paused type", func(t *testing.T) {
+		stateType := uuid.NewString()
+		state := createTestState(t, stateType)
+		require.NoError(t, s.PauseType(ctx, stateType, now))
+
+		filter := storage.ReadyStatesFilter{
+			Type:     stateType,
+			Statuses: []uint8{state.Status},
+			Limit:    10,
+		}
+		states, err := s.ClaimReadyStates(ctx, filter, lease)
+		require.NoError(t, err)
+		require.Empty(t, states)
+
+		// После возобновления типа стейт снова выдается
+		require.NoError(t, s.ResumeType(ctx, stateType))
+		states, err = s.ClaimReadyStates(ctx, filter, lease)
+		require.NoError(t, err)
+		require.Len(t, states, 1)
+		require.Equal(t, state.ID, states[0].ID)
+	})
+}
+
+func testPauseEvents(t *testing.T, factory Factory) {
+	t.Parallel()
+	s := factory(t)
+	ctx := context.Background()
+	now := time.Now().UTC().Truncate(time.Second)
+
+	stateType := uuid.NewString()
+	state := newTestState()
+	state.Type = stateType
+	require.NoError(t, s.CreateState(ctx, state))
+
+	events := []storage.PauseEvent{
+		{
+			Type:      stateType,
+			StateID:   &state.ID,
+			Paused:    true,
+			CreatedAt: now,
+			CreatedBy: "operator",
+			Reason:    "incident",
+		},
+		{
+			Type:      stateType,
+			Paused:    true,
+			CreatedAt: now.Add(time.Second),
+			CreatedBy: "operator",
+			Reason:    "downstream unavailable",
+		},
+		{
+			Type:      stateType,
+			Paused:    false,
+			CreatedAt: now.Add(2 * time.Second),
+			CreatedBy: "admin",
+			Reason:    "resolved",
+		},
+	}
+	// Сохраняем в обратном порядке, чтобы проверить сортировку по времени
+	for _, event := range lo.Reverse(append([]storage.PauseEvent(nil), events...)) {
+		require.NoError(t, s.SavePauseEvent(ctx, event))
+	}
+	require.NoError(t, s.SavePauseEvent(ctx, storage.PauseEvent{
+		Type:      uuid.NewString(),
+		CreatedAt: now,
+		CreatedBy: "operator",
+		Reason:    "other type",
+	}))
+
+	res, err := s.GetPauseEvents(ctx, stateType)
+	require.NoError(t, err)
+	require.Len(t, res, len(events))
+	for i := range events {
+		require.Equal(t, events[i].Type, res[i].Type)
+		require.Equal(t, events[i].StateID, res[i].StateID)
+		require.Equal(t, events[i].Paused, res[i].Paused)
+		require.True(t, events[i].CreatedAt.Equal(res[i].CreatedAt))
+		require.Equal(t, events[i].CreatedBy, res[i].CreatedBy)
+		require.Equal(t, events[i].Reason, res[i].Reason)
+	}
+
+	t.Run("unknown state", func(t *testing.T) {
+		err := s.SavePauseEvent(ctx, storage.PauseEvent{
+			Type:      stateType,
+			StateID:   lo.ToPtr(uuid.New()),
+			CreatedAt: now,
+		})
+		require.ErrorIs(t, err, storage.ErrForeignKeyViolation)
+	})
+}
@@ -0,0 +1,996 @@
+package storagetest
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/samber/lo"
+	"github.com/stretchr/testify/require"
+
+	"github.com/kkiling/statemachine/storage"
+)
+
+func stateEqual(t *testing.T, a, b *storage.State) {
+	require.Equal(t, a.ID, b.ID)
+	require.Equal(t, a.IdempotencyKey, b.IdempotencyKey)
+	require.Equal(t, a.CreatedAt.Unix(), b.CreatedAt.Unix())
+	require.Equal(t, a.UpdatedAt.Unix(), b.UpdatedAt.Unix())
+	require.Equal(t, a.Status, b.Status)
+	require.Equal(t, a.Step, b.Step)
+	require.Equal(t, a.Type, b.Type)
+	require.Equal(t, a.Data, b.Data)
+	require.Equal(t, a.FailData, b.FailData)
+	require.Equal(t, a.MetaData, b.MetaData)
+	require.Equal(t, a.Error, b.Error)
+	require.Equal(t, a.Version, b.Version)
+	require.Equal(t, a.Paused, b.Paused)
+}
+
+func stepExecuteInfoEqual(t *testing.T, a, b storage.StepExecuteInfo) {
+	require.Equal(t, a.StateID, b.StateID)
+	require.Equal(t, a.StartExecutedAt.Unix(), b.StartExecutedAt.Unix())
+	require.Equal(t, a.CompleteExecutedAt.Unix(), b.CompleteExecutedAt.Unix())
+	require.Equal(t, a.Error, b.Error)
+	require.Equal(t, a.PreviewStep, b.PreviewStep)
+	require.Equal(t, a.NextStep, b.NextStep)
+	require.Equal(t, a.Reason, b.Reason)
+	require.Equal(t, a.DataBefore, b.DataBefore)
+	require.Equal(t, a.DataAfter, b.DataAfter)
+}
+
+func testCreateState(t *testing.T, factory Factory) {
+	t.Parallel()
+
+	s := factory(t)
+	ctx := context.Background()
+
+	t.Run("successful creation", func(t *testing.T) {
+		state := &storage.State{
+			ID:             uuid.New(),
+			IdempotencyKey: uuid.NewString(),
+			CreatedAt:      time.Now().UTC(),
+			UpdatedAt:      time.Now().UTC(),
+			Status:         1,
+			Step:           "initial",
+			Type:           "test",
+			Data:           []byte(`{"key": "value"}`),
+			FailData:       nil,
+			MetaData:       []byte(`{"meta": "data"}`),
+		}
+
+		err := s.CreateState(ctx, state)
+		require.NoError(t, err)
+
+		// Проверяем, что состояние действительно сохранено
+		savedState, err := s.GetStateByID(ctx, state.ID)
+		require.NoError(t, err)
+		stateEqual(t, state, savedState)
+	})
+
+	t.Run("duplicate id", func(t *testing.T) {
+		state := &storage.State{
+			ID:             uuid.New(),
+			IdempotencyKey: uuid.NewString(),
+			CreatedAt:      time.Now().UTC(),
+			UpdatedAt:      time.Now().UTC(),
+			Status:         1,
+			Step:           "initial",
+			Type:           "test",
+			Data:           []byte(`{"key":"value"}`),
+			FailData:       nil,
+			MetaData:       []byte(`{"meta":"data"}`),
+		}
+
+		// Первое сохранение должно быть успешным
+		err := s.CreateState(ctx, state)
+		require.NoError(t, err)
+
+		// Попытка сохранить с тем же ID должна вернуть ошибку
+		err = s.CreateState(ctx, state)
+		require.Error(t, err)
+		require.ErrorIs(t, err, storage.ErrAlreadyExists)
+	})
+
+	t.Run("duplicate idempotency_key", func(t *testing.T) {
+		idempotencyKey := uuid.NewString()
+
+		state1 := &storage.State{
+			ID:             uuid.New(),
+			IdempotencyKey: idempotencyKey,
+			CreatedAt:      time.Now().UTC(),
+			UpdatedAt:      time.Now().UTC(),
+			Status:         1,
+			Step:           "initial",
+			Type:           "test",
+			Data:           []byte(`{"key":"value1"}`),
+			FailData:       nil,
+			MetaData:       []byte(`{"meta":"data1"}`),
+		}
+
+		state2 := &storage.State{
+			ID:             uuid.New(),
+			IdempotencyKey: idempotencyKey,
+			CreatedAt:      time.Now().UTC(),
+			UpdatedAt:      time.Now().UTC(),
+			Status:         2,
+			Step:           "next",
+			Type:           "test",
+			Data:           []byte(`{"key":"value2"}`),
+			FailData:       nil,
+			MetaData:       []byte(`{"meta":"data2"}`),
+		}
+
+		// Первое сохранение должно быть успешным
+		err := s.CreateState(ctx, state1)
+		require.NoError(t, err)
+
+		// Попытка сохранить с тем же ключом идемпотентности должна вернуть ошибку
+		err = s.CreateState(ctx, state2)
+		require.Error(t, err)
+		require.ErrorIs(t, err, storage.ErrAlreadyExists)
+	})
+
+	t.Run("with empty data", func(t *testing.T) {
+		state := &storage.State{
+			ID:             uuid.New(),
+			IdempotencyKey: uuid.NewString(),
+			CreatedAt:      time.Now().UTC(),
+			UpdatedAt:      time.Now().UTC(),
+			Status:         1,
+			Step:           "empty",
+			Type:           "test",
+			Data:           nil,
+			FailData:       nil,
+			MetaData:       nil,
+		}
+
+		err := s.CreateState(ctx, state)
+		require.NoError(t, err)
+
+		// Проверяем, что состояние с пустыми данными сохранилось
+		savedState, err := s.GetStateByID(ctx, state.ID)
+		require.NoError(t, err)
+		require.Nil(t, savedState.Data)
+		require.Nil(t, savedState.FailData)
+		require.Nil(t, savedState.MetaData)
+	})
+}
+
+func testGetStateByIdempotencyKey(t *testing.T, factory Factory) {
+	t.Parallel()
+	s := factory(t)
+	ctx := context.Background()
+
+	t.Run("successful get", func(t *testing.T) {
+		t.Parallel()
+
+		// Подготовка тестовых данных
+		testState := &storage.State{
+			ID:             uuid.New(),
+			IdempotencyKey: uuid.NewString(),
+			CreatedAt:      time.Now().UTC().Truncate(time.Second), // Округляем до секунд для сравнения
+			UpdatedAt:      time.Now().UTC().Truncate(time.Second),
+			Status:         1,
+			Step:           "initial",
+			Type:           "test",
+			Data:           []byte(`{"key": "value"}`),
+			FailData:       nil,
+			MetaData:       []byte(`{"meta": "data"}`),
+		}
+
+		// Сначала создаем состояние
+		err := s.CreateState(ctx, testState)
+		require.NoError(t, err)
+
+		// Получаем состояние по ключу идемпотентности
+		state, err := s.GetStateByIdempotencyKey(ctx, testState.IdempotencyKey)
+		require.NoError(t, err)
+		require.NotNil(t, state)
+		stateEqual(t, testState, state)
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		t.Parallel()
+
+		// Пытаемся получить несуществующее состояние
+		nonExistentKey := uuid.NewString()
+		state, err := s.GetStateByIdempotencyKey(ctx, nonExistentKey)
+
+		// Проверяем, что получили ожидаемую ошибку
+		require.Nil(t, state)
+		require.ErrorIs(t, err, storage.ErrNotFound)
+	})
+
+	t.Run("empty idempotency key", func(t *testing.T) {
+		t.Parallel()
+
+		// Пытаемся получить состояние с пустым UUID
+		state, err := s.GetStateByIdempotencyKey(ctx, "")
+
+		require.Nil(t, state)
+		require.Error(t, err)
+		require.ErrorIs(t, err, storage.ErrNotFound)
+	})
+}
+
+func testGetStateByID(t *testing.T, factory Factory) {
+	t.Parallel()
+	s := factory(t)
+	ctx := context.Background()
+
+	// Подготовка тестовых данных
+	testState := &storage.State{
+		ID:             uuid.New(),
+		IdempotencyKey: uuid.NewString(),
+		CreatedAt:      time.Now().UTC().Truncate(time.Second), // Округляем для точного сравнения
+		UpdatedAt:      time.Now().UTC().Truncate(time.Second),
+		Status:         124,
+		Step:           "initial",
+		Type:           "test_transaction",
+		Data:           []byte(`{"amount": 100}`),
+		FailData:       nil,
+		MetaData:       []byte(`{"user_id": 123}`),
+	}
+
+	// Сначала создаем состояние
+	err := s.CreateState(ctx, testState)
+	require.NoError(t, err)
+
+	t.Run("successful get", func(t *testing.T) {
+		t.Parallel()
+		// Получаем состояние по ID
+		state, err := s.GetStateByID(ctx, testState.ID)
+		require.NoError(t, err)
+		require.NotNil(t, state)
+
+		// Проверяем все поля
+		stateEqual(t, testState, state)
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		t.Parallel()
+		// Пытаемся получить несуществующее состояние
+		nonExistentID := uuid.New()
+		state, err := s.GetStateByID(ctx, nonExistentID)
+
+		require.Nil(t, state)
+		require.ErrorIs(t, err, storage.ErrNotFound)
+	})
+
+	t.Run("empty UUID", func(t *testing.T) {
+		t.Parallel()
+		// Пытаемся получить состояние с пустым UUID
+		state, err := s.GetStateByID(ctx, uuid.Nil)
+
+		require.Nil(t, state)
+		require.Error(t, err)
+		require.ErrorIs(t, err, storage.ErrNotFound)
+	})
+}
+
+func testSaveStepExecuteInfo(t *testing.T, factory Factory) {
+	s := factory(t)
+	ctx := context.Background()
+
+	saveTestState := func(t *testing.T) *storage.State {
+		// Создаем тестовое состояние
+		testState := &storage.State{
+			ID:             uuid.New(),
+			IdempotencyKey: uuid.NewString(),
+			CreatedAt:      time.Now().UTC(),
+			UpdatedAt:      time.Now().UTC(),
+			Status:         1,
+			Step:           "initial",
+			Type:           "test",
+		}
+		require.NoError(t, s.CreateState(ctx, testState))
+
+		return testState
+	}
+
+	t.Run("successful save", func(t *testing.T) {
+		testState := saveTestState(t)
+
+		info := storage.StepExecuteInfo{
+			StateID:            testState.ID,
+			StartExecutedAt:    time.Now().UTC().Truncate(time.Second),
+			CompleteExecutedAt: time.Now().UTC().Add(5 * time.Second).Truncate(time.Second),
+			Error:              lo.ToPtr("test error"),
+			PreviewStep:        "prev_step",
+			NextStep:           lo.ToPtr("next_step"),
+		}
+
+		err := s.SaveStepExecuteInfo(ctx, info)
+		require.NoError(t, err)
+
+		// Проверяем через GetStepExecuteInfos
+		saved, err := s.GetStepExecuteInfos(ctx, testState.ID)
+		require.NoError(t, err)
+		require.Len(t, saved, 1)
+		stepExecuteInfoEqual(t, info, saved[0])
+	})
+
+	t.Run("fail on non-existent state", func(t *testing.T) {
+		info := storage.StepExecuteInfo{
+			StateID:         uuid.New(), // Несуществующий ID
+			StartExecutedAt: time.Now(),
+			PreviewStep:     "test",
+		}
+
+		err := s.SaveStepExecuteInfo(ctx, info)
+		require.Error(t, err)
+		require.ErrorIs(t, err, storage.ErrForeignKeyViolation)
+	})
+
+	t.Run("successful save multiple steps", func(t *testing.T) {
+		testState := saveTestState(t)
+
+		info := storage.StepExecuteInfo{
+			StateID:            testState.ID,
+			StartExecutedAt:    time.Now().UTC().Truncate(time.Second),
+			CompleteExecutedAt: time.Now().UTC().Add(5 * time.Second).Truncate(time.Second),
+			PreviewStep:        "1 step",
+			NextStep:           lo.ToPtr("2 step"),
+		}
+
+		err := s.SaveStepExecuteInfo(ctx, info)
+		require.NoError(t, err)
+
+		info.PreviewStep = "2 step"
+		info.NextStep = nil
+		err = s.SaveStepExecuteInfo(ctx, info)
+		require.NoError(t, err)
+
+		info.PreviewStep = "2 step"
+		info.NextStep = lo.ToPtr("3 step")
+		err = s.SaveStepExecuteInfo(ctx, info)
+		require.NoError(t, err)
+
+		// Проверяем через GetStepExecuteInfos
+		saved, err := s.GetStepExecuteInfos(ctx, testState.ID)
+		require.NoError(t, err)
+		require.Len(t, saved, 3)
+		require.Equal(t, saved[0].PreviewStep, "1 step")
+		require.Equal(t, saved[1].PreviewStep, "2 step")
+		require.Equal(t, saved[2].PreviewStep, "2 step")
+	})
+}
+
+func testGetStepExecuteInfos(t *testing.T, factory Factory) {
+	s := factory(t)
+	ctx := context.Background()
+
+	saveTestState := func(t *testing.T) *storage.State {
+		// Создаем тестовое состояние
+		testState := &storage.State{
+			ID:             uuid.New(),
+			IdempotencyKey: uuid.NewString(),
+			CreatedAt:      time.Now().UTC(),
+			UpdatedAt:      time.Now().UTC(),
+			Status:         1,
+			Step:           "initial",
+			Type:           "test",
+		}
+		require.NoError(t, s.CreateState(ctx, testState))
+
+		return testState
+	}
+
+	t.Run("get single step info", func(t *testing.T) {
+		state := saveTestState(t)
+		info := storage.StepExecuteInfo{
+			StateID:            state.ID,
+			StartExecutedAt:    time.Now().UTC().Truncate(time.Second),
+			CompleteExecutedAt: time.Now().UTC().Add(5 * time.Second).Truncate(time.Second),
+			Error:              lo.ToPtr("test error"),
+			PreviewStep:        "initial",
+			NextStep:           lo.ToPtr("next_step"),
+			Reason:             lo.ToPtr("moved by operator"),
+			DataBefore:         []byte(`{"counter": 1}`),
+			DataAfter:          []byte(`{"counter": 2}`),
+		}
+
+		err := s.SaveStepExecuteInfo(ctx, info)
+		require.NoError(t, err)
+
+		infos, err := s.GetStepExecuteInfos(ctx, state.ID)
+		require.NoError(t, err)
+		require.Len(t, infos, 1)
+
+		stepExecuteInfoEqual(t, info, infos[0])
+	})
+
+	t.Run("get multiple steps ordered by time", func(t *testing.T) {
+		state := saveTestState(t)
+
+		now := time.Now().UTC().Truncate(time.Second)
+		infos := []storage.StepExecuteInfo{
+			{
+				StateID:            state.ID,
+				StartExecutedAt:    now.Add(10 * time.Second),
+				CompleteExecutedAt: now.Add(15 * time.Second),
+				PreviewStep:        "step_2",
+				NextStep:           lo.ToPtr("step_3"),
+			},
+			{
+				StateID:            state.ID,
+				StartExecutedAt:    now,
+				CompleteExecutedAt: now.Add(5 * time.Second),
+				PreviewStep:        "step_1",
+				NextStep:           lo.ToPtr("step_2"),
+			},
+			{
+				StateID:            state.ID,
+				StartExecutedAt:    now.Add(20 * time.Second),
+				CompleteExecutedAt: now.Add(25 * time.Second),
+				PreviewStep:        "step_3",
+				NextStep:           nil,
+			},
+		}
+
+		for _, info := range infos {
+			err := s.SaveStepExecuteInfo(ctx, info)
+			require.NoError(t, err)
+		}
+
+		find, err := s.GetStepExecuteInfos(ctx, state.ID)
+		require.NoError(t, err)
+		require.Len(t, infos, 3)
+		stepExecuteInfoEqual(t, infos[1], find[0]) // step_1
+		stepExecuteInfoEqual(t, infos[0], find[1]) // step_2
+		stepExecuteInfoEqual(t, infos[2], find[2]) // step_3
+	})
+
+	t.Run("return empty slice for unknown state", func(t *testing.T) {
+		infos, err := s.GetStepExecuteInfos(ctx, uuid.New())
+		require.NoError(t, err)
+		require.Empty(t, infos)
+	})
+}
+
+func testUpdateState(t *testing.T, factory Factory) {
+	s := factory(t)
+	ctx := context.Background()
+
+	const (
+		StateStatusPending    = 12
+		StateStatusCompleted  = 3
+		StateStatusFailed     = 7
+		StateStatusProcessing = 4
+	)
+
+	// Вспомогательная функция для создания тестового состояния
+	createTestState := func(t *testing.T) *storage.State {
+		state := &storage.State{
+			ID:             uuid.New(),
+			IdempotencyKey: uuid.NewString(),
+			CreatedAt:      time.Now().UTC().Truncate(time.Second),
+			UpdatedAt:      time.Now().UTC().Truncate(time.Second),
+			Status:         StateStatusPending,
+			Step:           "initial",
+			Type:           "test",
+			Data:           []byte(`{"initial": true}`),
+		}
+		require.NoError(t, s.CreateState(ctx, state))
+		return state
+	}
+
+	t.Run("successful update all fields", func(t *testing.T) {
+		testState := createTestState(t)
+		newTime := time.Now().UTC().Truncate(time.Second)
+
+		update := storage.UpdateState{
+			UpdatedAt: newTime,
+			Status:    StateStatusCompleted,
+			Step:      "completed",
+			Data:      []byte(`{"completed": true}`),
+			FailData:  nil,
+			MetaData:  []byte(`{"meta": "data"}`),
+		}
+
+		err := s.UpdateState(ctx, testState.ID, update)
+		require.NoError(t, err)
+
+		// Проверяем обновленные данные
+		updatedState, err := s.GetStateByID(ctx, testState.ID)
+		require.NoError(t, err)
+
+		require.Equal(t, update.Status, updatedState.Status)
+		require.Equal(t, update.Step, updatedState.Step)
+		require.Equal(t, update.Data, updatedState.Data)
+		require.Equal(t, update.FailData, updatedState.FailData)
+		require.Equal(t, update.MetaData, updatedState.MetaData)
+		require.Equal(t, update.UpdatedAt.Unix(), updatedState.UpdatedAt.Unix())
+	})
+
+	t.Run("successful partial update", func(t *testing.T) {
+		testState := createTestState(t)
+		newTime := time.Now().UTC().Truncate(time.Second)
+
+		update := storage.UpdateState{
+			UpdatedAt: newTime,
+			Status:    StateStatusFailed,
+			Step:      "failed",
+			FailData:  []byte(`{"error": "something went wrong"}`),
+			// Data и MetaDataT не обновляем
+			Data:     testState.Data,
+			MetaData: testState.MetaData,
+		}
+
+		err := s.UpdateState(ctx, testState.ID, update)
+		require.NoError(t, err)
+
+		// Проверяем обновленные данные
+		updatedState, err := s.GetStateByID(ctx, testState.ID)
+		require.NoError(t, err)
+
+		require.Equal(t, update.UpdatedAt.Unix(), updatedState.UpdatedAt.Unix())
+		require.Equal(t, update.Status, updatedState.Status)
+		require.Equal(t, update.Step, updatedState.Step)
+		require.Equal(t, update.FailData, updatedState.FailData)
+		// Проверяем, что остальные поля не изменились
+		require.Equal(t, testState.Data, updatedState.Data)
+		require.Equal(t, testState.MetaData, updatedState.MetaData)
+	})
+
+	t.Run("fail on non-existent state", func(t *testing.T) {
+		update := storage.UpdateState{
+			UpdatedAt: time.Now(),
+			Status:    StateStatusPending,
+			Step:      "test",
+		}
+
+		err := s.UpdateState(ctx, uuid.New(), update)
+		require.Error(t, err)
+		require.ErrorIs(t, err, storage.ErrNotFound)
+	})
+
+	t.Run("update with null data fields", func(t *testing.T) {
+		testState := createTestState(t)
+		// Предварительно заполняем данные
+		require.NoError(t, s.UpdateState(ctx, testState.ID, storage.UpdateState{
+			UpdatedAt: time.Now(),
+			Status:    StateStatusPending,
+			Step:      "has_data",
+			Data:      []byte(`{"test":1}`),
+			FailData:  []byte(`{"error":"test"}`),
+			MetaData:  []byte(`{"meta":1}`),
+		}))
+
+		// Обновляем с null-значениями
+		update := storage.UpdateState{
+			UpdatedAt: time.Now().UTC().Truncate(time.Second),
+			Status:    StateStatusProcessing,
+			Step:      "null_data",
+			Data:      nil,
+			FailData:  nil,
+			MetaData:  nil,
+			Version:   1,
+		}
+
+		err := s.UpdateState(ctx, testState.ID, update)
+		require.NoError(t, err)
+
+		// Проверяем, что данные обнулились
+		updatedState, err := s.GetStateByID(ctx, testState.ID)
+		require.NoError(t, err)
+
+		require.Nil(t, updatedState.Data)
+		require.Nil(t, updatedState.FailData)
+		require.Nil(t, updatedState.MetaData)
+	})
+
+	t.Run("increment version", func(t *testing.T) {
+		testState := createTestState(t)
+		require.Equal(t, int64(0), testState.Version)
+
+		for version := int64(0); version < 3; version++ {
+			require.NoError(t, s.UpdateState(ctx, testState.ID, storage.UpdateState{
+				UpdatedAt: time.Now(),
+				Status:    StateStatusProcessing,
+				Step:      "processing",
+				Version:   version,
+			}))
+		}
+
+		updatedState, err := s.GetStateByID(ctx, testState.ID)
+		require.NoError(t, err)
+		require.Equal(t, int64(3), updatedState.Version)
+	})
+
+	t.Run("update next run at", func(t *testing.T) {
+		testState := createTestState(t)
+		nextRunAt := time.Now().UTC().Add(time.Minute).Truncate(time.Second)
+
+		require.NoError(t, s.UpdateState(ctx, testState.ID, storage.UpdateState{
+			UpdatedAt: time.Now(),
+			Status:    StateStatusProcessing,
+			Step:      "delayed",
+			NextRunAt: &nextRunAt,
+		}))
+
+		updatedState, err := s.GetStateByID(ctx, testState.ID)
+		require.NoError(t, err)
+		require.NotNil(t, updatedState.NextRunAt)
+		require.Equal(t, nextRunAt.Unix(), updatedState.NextRunAt.Unix())
+
+		// Сброс времени следующего выполнения
+		require.NoError(t, s.UpdateState(ctx, testState.ID, storage.UpdateState{
+			UpdatedAt: time.Now(),
+			Status:    StateStatusProcessing,
+			Step:      "delayed",
+			Version:   1,
+		}))
+
+		updatedState, err = s.GetStateByID(ctx, testState.ID)
+		require.NoError(t, err)
+		require.Nil(t, updatedState.NextRunAt)
+	})
+
+	t.Run("update failed attempts", func(t *testing.T) {
+		testState := createTestState(t)
+
+		require.NoError(t, s.UpdateState(ctx, testState.ID, storage.UpdateState{
+			UpdatedAt:      time.Now(),
+			Status:         StateStatusProcessing,
+			Step:           "retry",
+			FailedAttempts: 2,
+		}))
+
+		updatedState, err := s.GetStateByID(ctx, testState.ID)
+		require.NoError(t, err)
+		require.Equal(t, 2, updatedState.FailedAttempts)
+	})
+
+	t.Run("update step attempts", func(t *testing.T) {
+		testState := createTestState(t)
+
+		require.NoError(t, s.UpdateState(ctx, testState.ID, storage.UpdateState{
+			UpdatedAt:    time.Now(),
+			Status:       StateStatusProcessing,
+			Step:         "poll",
+			StepAttempts: 5,
+		}))
+
+		updatedState, err := s.GetStateByID(ctx, testState.ID)
+		require.NoError(t, err)
+		require.Equal(t, 5, updatedState.StepAttempts)
+	})
+
+	t.Run("update panic attempts", func(t *testing.T) {
+		testState := createTestState(t)
+
+		require.NoError(t, s.UpdateState(ctx, testState.ID, storage.UpdateState{
+			UpdatedAt:     time.Now(),
+			Status:        StateStatusProcessing,
+			Step:          "poll",
+			PanicAttempts: 2,
+		}))
+
+		updatedState, err := s.GetStateByID(ctx, testState.ID)
+		require.NoError(t, err)
+		require.Equal(t, 2, updatedState.PanicAttempts)
+	})
+
+	t.Run("update failed step", func(t *testing.T) {
+		testState := createTestState(t)
+
+		require.NoError(t, s.UpdateState(ctx, testState.ID, storage.UpdateState{
+			UpdatedAt:  time.Now(),
+			Status:     StateStatusFailed,
+			FailedStep: "charge",
+		}))
+
+		updatedState, err := s.GetStateByID(ctx, testState.ID)
+		require.NoError(t, err)
+		require.Equal(t, "", updatedState.Step)
+		require.Equal(t, "charge", updatedState.FailedStep)
+	})
+
+	t.Run("version mismatch", func(t *testing.T) {
+		testState := createTestState(t)
+
+		require.NoError(t, s.UpdateState(ctx, testState.ID, storage.UpdateState{
+			UpdatedAt: time.Now(),
+			Status:    StateStatusProcessing,
+			Step:      "first_writer",
+			Version:   0,
+		}))
+
+		// Второй обработчик прочитал стейт до первого обновления
+		err := s.UpdateState(ctx, testState.ID, storage.UpdateState{
+			UpdatedAt: time.Now(),
+			Status:    StateStatusFailed,
+			Step:      "stale_writer",
+			Version:   0,
+		})
+		require.ErrorIs(t, err, storage.ErrConcurrentModification)
+
+		// Изменения устаревшего обработчика не записались
+		updatedState, err := s.GetStateByID(ctx, testState.ID)
+		require.NoError(t, err)
+		require.Equal(t, "first_writer", updatedState.Step)
+		require.Equal(t, int64(1), updatedState.Version)
+	})
+
+	t.Run("context cancellation", func(t *testing.T) {
+		testState := createTestState(t)
+		ctx, cancel := context.WithCancel(ctx)
+		cancel() // сразу отменяем контекст
+
+		update := storage.UpdateState{
+			UpdatedAt: time.Now(),
+			Status:    StateStatusPending,
+			Step:      "canceled",
+		}
+
+		err := s.UpdateState(ctx, testState.ID, update)
+		require.Error(t, err)
+		require.True(t, errors.Is(err, context.Canceled))
+	})
+}
+
+func testClaimReadyStates(t *testing.T, factory Factory) {
+	s := factory(t)
+	ctx := context.Background()
+
+	const (
+		StateStatusNew        = 0
+		StateStatusProcessing = 1
+		StateStatusCompleted  = 2
+	)
+
+	now := time.Now().UTC().Truncate(time.Second)
+	readyStatuses := []uint8{StateStatusNew, StateStatusProcessing}
+
+	createTestState := func(t *testing.T, stateType string, status uint8, updatedAt time.Time) *storage.State {
+		state := &storage.State{
+			ID:             uuid.New(),
+			IdempotencyKey: uuid.NewString(),
+			CreatedAt:      now,
+			UpdatedAt:      updatedAt,
+			Status:         status,
+			Step:           "initial",
+			Type:           stateType,
+		}
+		require.NoError(t, s.CreateState(ctx, state))
+		return state
+	}
+
+	stateIDs := func(states []storage.State) []uuid.UUID {
+		return lo.Map(states, func(item storage.State, _ int) uuid.UUID {
+			return item.ID
+		})
+	}
+
+	t.Run("filter by type and status ordered by updated_at", func(t *testing.T) {
+		// Уникальный тип, чтобы не пересекаться с другими тестами
+		stateType := uuid.NewString()
+		processing := createTestState(t, stateType, StateStatusProcessing, now.Add(2*time.Second))
+		newState := createTestState(t, stateType, StateStatusNew, now.Add(time.Second))
+		createTestState(t, stateType, StateStatusCompleted, now)
+		createTestState(t, uuid.NewString(), StateStatusNew, now)
+
+		states, err := s.ClaimReadyStates(ctx, storage.ReadyStatesFilter{
+			Type:     stateType,
+			Statuses: readyStatuses,
+			Limit:    10,
+		}, storage.Lease{Owner: "worker_1", AcquiredAt: now, ExpiresAt: now.Add(time.Minute)})
+		require.NoError(t, err)
+		require.ElementsMatch(t, []uuid.UUID{newState.ID, processing.ID}, stateIDs(states))
+	})
+
+	t.Run("limit", func(t *testing.T) {
+		stateType := uuid.NewString()
+		first := createTestState(t, stateType, StateStatusNew, now)
+		createTestState(t, stateType, StateStatusNew, now.Add(time.Second))
+
+		states, err := s.ClaimReadyStates(ctx, storage.ReadyStatesFilter{
+			Type:     stateType,
+			Statuses: readyStatuses,
+			Limit:    1,
+		}, storage.Lease{Owner: "worker_1", AcquiredAt: now, ExpiresAt: now.Add(time.Minute)})
+		require.NoError(t, err)
+		require.Equal(t, []uuid.UUID{first.ID}, stateIDs(states))
+	})
+
+	t.Run("skip claimed and reclaim expired", func(t *testing.T) {
+		stateType := uuid.NewString()
+		state := createTestState(t, stateType, StateStatusNew, now)
+		filter := storage.ReadyStatesFilter{
+			Type:     stateType,
+			Statuses: readyStatuses,
+			Limit:    10,
+		}
+
+		states, err := s.ClaimReadyStates(ctx, filter,
+			storage.Lease{Owner: "worker_1", AcquiredAt: now, ExpiresAt: now.Add(time.Minute)})
+		require.NoError(t, err)
+		require.Equal(t, []uuid.UUID{state.ID}, stateIDs(states))
+
+		// Аренда еще не истекла
+		states, err = s.ClaimReadyStates(ctx, filter,
+			storage.Lease{Owner: "worker_2", AcquiredAt: now.Add(time.Second), ExpiresAt: now.Add(time.Minute)})
+		require.NoError(t, err)
+		require.Empty(t, states)
+
+		// Аренда истекла (например первый обработчик упал)
+		states, err = s.ClaimReadyStates(ctx, filter,
+			storage.Lease{Owner: "worker_2", AcquiredAt: now.Add(time.Minute), ExpiresAt: now.Add(2 * time.Minute)})
+		require.NoError(t, err)
+		require.Equal(t, []uuid.UUID{state.ID}, stateIDs(states))
+	})
+
+	t.Run("skip states scheduled in future", func(t *testing.T) {
+		stateType := uuid.NewString()
+		state := createTestState(t, stateType, StateStatusProcessing, now)
+		require.NoError(t, s.UpdateState(ctx, state.ID, storage.UpdateState{
+			UpdatedAt: state.UpdatedAt,
+			Status:    state.Status,
+			Step:      state.Step,
+			NextRunAt: lo.ToPtr(now.Add(time.Minute)),
+		}))
+		filter := storage.ReadyStatesFilter{
+			Type:     stateType,
+			Statuses: readyStatuses,
+			Limit:    10,
+		}
+
+		states, err := s.ClaimReadyStates(ctx, filter,
+			storage.Lease{Owner: "worker_1", AcquiredAt: now, ExpiresAt: now.Add(time.Minute)})
+		require.NoError(t, err)
+		require.Empty(t, states)
+
+		states, err = s.ClaimReadyStates(ctx, filter,
+			storage.Lease{Owner: "worker_1", AcquiredAt: now.Add(time.Minute), ExpiresAt: now.Add(2 * time.Minute)})
+		require.NoError(t, err)
+		require.Equal(t, []uuid.UUID{state.ID}, stateIDs(states))
+		require.Equal(t, now.Add(time.Minute).Unix(), states[0].NextRunAt.Unix())
+	})
+}
+
+func testClaimState(t *testing.T, factory Factory) {
+	s := factory(t)
+	ctx := context.Background()
+	now := time.Now().UTC().Truncate(time.Second)
+
+	createTestState := func(t *testing.T) *storage.State {
+		state := &storage.State{
+			ID:             uuid.New(),
+			IdempotencyKey: uuid.NewString(),
+			CreatedAt:      now,
+			UpdatedAt:      now,
+			Status:         1,
+			Step:           "initial",
+			Type:           "test",
+			Data:           []byte(`{"key": "value"}`),
+		}
+		require.NoError(t, s.CreateState(ctx, state))
+		return state
+	}
+
+	t.Run("successful claim", func(t *testing.T) {
+		testState := createTestState(t)
+
+		state, err := s.ClaimState(ctx, testState.ID,
+			storage.Lease{Owner: "worker_1", AcquiredAt: now, ExpiresAt: now.Add(time.Minute)})
+		require.NoError(t, err)
+		stateEqual(t, testState, state)
+	})
+
+	t.Run("locked by another owner", func(t *testing.T) {
+		testState := createTestState(t)
+
+		_, err := s.ClaimState(ctx, testState.ID,
+			storage.Lease{Owner: "worker_1", AcquiredAt: now, ExpiresAt: now.Add(time.Minute)})
+		require.NoError(t, err)
+
+		state, err := s.ClaimState(ctx, testState.ID,
+			storage.Lease{Owner: "worker_2", AcquiredAt: now.Add(time.Second), ExpiresAt: now.Add(time.Minute)})
+		require.Nil(t, state)
+		require.ErrorIs(t, err, storage.ErrLocked)
+	})
+
+	t.Run("reclaim expired lease", func(t *testing.T) {
+		testState := createTestState(t)
+
+		_, err := s.ClaimState(ctx, testState.ID,
+			storage.Lease{Owner: "worker_1", AcquiredAt: now, ExpiresAt: now.Add(time.Minute)})
+		require.NoError(t, err)
+
+		_, err = s.ClaimState(ctx, testState.ID,
+			storage.Lease{Owner: "worker_2", AcquiredAt: now.Add(time.Minute), ExpiresAt: now.Add(2 * time.Minute)})
+		require.NoError(t, err)
+	})
+
+	t.Run("claim after release", func(t *testing.T) {
+		testState := createTestState(t)
+
+		_, err := s.ClaimState(ctx, testState.ID,
+			storage.Lease{Owner: "worker_1", AcquiredAt: now, ExpiresAt: now.Add(time.Minute)})
+		require.NoError(t, err)
+
+		// Освобождение чужой аренды ни на что не влияет
+		require.NoError(t, s.ReleaseState(ctx, testState.ID, "worker_2"))
+		_, err = s.ClaimState(ctx, testState.ID,
+			storage.Lease{Owner: "worker_2", AcquiredAt: now, ExpiresAt: now.Add(time.Minute)})
+		require.ErrorIs(t, err, storage.ErrLocked)
+
+		require.NoError(t, s.ReleaseState(ctx, testState.ID, "worker_1"))
+		_, err = s.ClaimState(ctx, testState.ID,
+			storage.Lease{Owner: "worker_2", AcquiredAt: now, ExpiresAt: now.Add(time.Minute)})
+		require.NoError(t, err)
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		state, err := s.ClaimState(ctx, uuid.New(),
+			storage.Lease{Owner: "worker_1", AcquiredAt: now, ExpiresAt: now.Add(time.Minute)})
+		require.Nil(t, state)
+		require.ErrorIs(t, err, storage.ErrNotFound)
+	})
+}
+
+func testRenewLease(t *testing.T, factory Factory) {
+	s := factory(t)
+	ctx := context.Background()
+	now := time.Now().UTC().Truncate(time.Second)
+
+	claimTestState := func(t *testing.T) *storage.State {
+		state := &storage.State{
+			ID:             uuid.New(),
+			IdempotencyKey: uuid.NewString(),
+			CreatedAt:      now,
+			UpdatedAt:      now,
+			Status:         1,
+			Step:           "initial",
+			Type:           "test",
+		}
+		require.NoError(t, s.CreateState(ctx, state))
+		_, err := s.ClaimState(ctx, state.ID,
+			storage.Lease{Owner: "worker_1", AcquiredAt: now, ExpiresAt: now.Add(time.Minute)})
+		require.NoError(t, err)
+		return state
+	}
+
+	t.Run("renew own lease", func(t *testing.T) {
+		testState := claimTestState(t)
+
+		require.NoError(t, s.RenewLease(ctx, testState.ID,
+			storage.Lease{Owner: "worker_1", AcquiredAt: now.Add(30 * time.Second), ExpiresAt: now.Add(2 * time.Minute)}))
+
+		// Продленная аренда не истекла к моменту окончания первоначальной
+		_, err := s.ClaimState(ctx, testState.ID,
+			storage.Lease{Owner: "worker_2", AcquiredAt: now.Add(time.Minute), ExpiresAt: now.Add(3 * time.Minute)})
+		require.ErrorIs(t, err, storage.ErrLocked)
+	})
+
+	t.Run("lease claimed by another owner", func(t *testing.T) {
+		testState := claimTestState(t)
+
+		_, err := s.ClaimState(ctx, testState.ID,
+			storage.Lease{Owner: "worker_2", AcquiredAt: now.Add(time.Minute), ExpiresAt: now.Add(2 * time.Minute)})
+		require.NoError(t, err)
+
+		err = s.RenewLease(ctx, testState.ID,
+			storage.Lease{Owner: "worker_1", AcquiredAt: now.Add(time.Minute), ExpiresAt: now.Add(2 * time.Minute)})
+		require.ErrorIs(t, err, storage.ErrLocked)
+	})
+
+	t.Run("released lease", func(t *testing.T) {
+		testState := claimTestState(t)
+		require.NoError(t, s.ReleaseState(ctx, testState.ID, "worker_1"))
+
+		err := s.RenewLease(ctx, testState.ID,
+			storage.Lease{Owner: "worker_1", AcquiredAt: now, ExpiresAt: now.Add(time.Minute)})
+		require.ErrorIs(t, err, storage.ErrLocked)
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		err := s.RenewLease(ctx, uuid.New(),
+			storage.Lease{Owner: "worker_1", AcquiredAt: now, ExpiresAt: now.Add(time.Minute)})
+		require.ErrorIs(t, err, storage.ErrNotFound)
+	})
+}
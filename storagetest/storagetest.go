@@ -0,0 +1,55 @@
+// Package storagetest набор тестов, проверяющих соответствие реализации statemachine.Storage
+// контракту, описанному в пакете storage.
+//
+// Пример использования в тестах реализации:
+//
+//	func TestStorage(t *testing.T) {
+//		storagetest.Run(t, func(t *testing.T) storagetest.Storage {
+//			return mystorage.New(...)
+//		})
+//	}
+package storagetest
+
+import (
+	"testing"
+
+	"github.com/kkiling/statemachine"
+)
+
+// Storage проверяемое хранилище
+type Storage = statemachine.Storage
+
+// Factory создает хранилище для теста. Хранилище может быть общим для нескольких тестов,
+// тесты не зависят от данных, созданных другими тестами
+type Factory func(t *testing.T) Storage
+
+// Run запускает все тесты набора для хранилища, создаваемого factory
+func Run(t *testing.T, factory Factory) {
+	tests := []struct {
+		name string
+		test func(t *testing.T, factory Factory)
+	}{
+		{name: "CreateState", test: testCreateState},
+		{name: "GetStateByIdempotencyKey", test: testGetStateByIdempotencyKey},
+		{name: "GetStateByID", test: testGetStateByID},
+		{name: "SaveStepExecuteInfo", test: testSaveStepExecuteInfo},
+		{name: "GetStepExecuteInfos", test: testGetStepExecuteInfos},
+		{name: "UpdateState", test: testUpdateState},
+		{name: "ConcurrentUpdateState", test: testConcurrentUpdateState},
+		{name: "RunTransaction", test: testRunTransaction},
+		{name: "ClaimReadyStates", test: testClaimReadyStates},
+		{name: "ClaimState", test: testClaimState},
+		{name: "RenewLease", test: testRenewLease},
+		{name: "SetStatePaused", test: testSetStatePaused},
+		{name: "PauseType", test: testPauseType},
+		{name: "ClaimReadyStatesPaused", test: testClaimReadyStatesPaused},
+		{name: "PauseEvents", test: testPauseEvents},
+		{name: "ListStates", test: testListStates},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.test(t, factory)
+		})
+	}
+}
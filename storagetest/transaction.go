@@ -0,0 +1,200 @@
+package storagetest
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+
+	"github.com/kkiling/statemachine/storage"
+)
+
+func newTestState() *storage.State {
+	now := time.Now().UTC().Truncate(time.Second)
+	return &storage.State{
+		ID:             uuid.New(),
+		IdempotencyKey: uuid.NewString(),
+		CreatedAt:      now,
+		UpdatedAt:      now,
+		Status:         1,
+		Step:           "initial",
+		Type:           "test",
+		Data:           []byte(`{"counter": 0}`),
+	}
+}
+
+func testRunTransaction(t *testing.T, factory Factory) {
+	t.Parallel()
+	s := factory(t)
+	ctx := context.Background()
+
+	t.Run("commit", func(t *testing.T) {
+		state := newTestState()
+		info := storage.StepExecuteInfo{
+			StateID:            state.ID,
+			StartExecutedAt:    state.CreatedAt,
+			CompleteExecutedAt: state.CreatedAt.Add(time.Second),
+			PreviewStep:        state.Step,
+		}
+
+		err := s.RunTransaction(ctx, func(ctxTx context.Context) error {
+			if err := s.CreateState(ctxTx, state); err != nil {
+				return err
+			}
+			return s.SaveStepExecuteInfo(ctxTx, info)
+		})
+		require.NoError(t, err)
+
+		savedState, err := s.GetStateByID(ctx, state.ID)
+		require.NoError(t, err)
+		stateEqual(t, state, savedState)
+
+		infos, err := s.GetStepExecuteInfos(ctx, state.ID)
+		require.NoError(t, err)
+		require.Len(t, infos, 1)
+		stepExecuteInfoEqual(t, info, infos[0])
+	})
+
+	t.Run("rollback on error", func(t *testing.T) {
+		state := newTestState()
+		txErr := errors.New("tx error")
+
+		err := s.RunTransaction(ctx, func(ctxTx context.Context) error {
+			if err := s.CreateState(ctxTx, state); err != nil {
+				return err
+			}
+			if err := s.SaveStepExecuteInfo(ctxTx, storage.StepExecuteInfo{
+				StateID:            state.ID,
+				StartExecutedAt:    state.CreatedAt,
+				CompleteExecutedAt: state.CreatedAt,
+				PreviewStep:        state.Step,
+			}); err != nil {
+				return err
+			}
+			// Внутри транзакции изменения видны
+			if _, err := s.GetStateByID(ctxTx, state.ID); err != nil {
+				return err
+			}
+			return txErr
+		})
+		require.ErrorIs(t, err, txErr)
+
+		_, err = s.GetStateByID(ctx, state.ID)
+		require.ErrorIs(t, err, storage.ErrNotFound)
+
+		infos, err := s.GetStepExecuteInfos(ctx, state.ID)
+		require.NoError(t, err)
+		require.Empty(t, infos)
+	})
+
+	t.Run("rollback update", func(t *testing.T) {
+		state := newTestState()
+		require.NoError(t, s.CreateState(ctx, state))
+		txErr := errors.New("tx error")
+
+		err := s.RunTransaction(ctx, func(ctxTx context.Context) error {
+			if err := s.UpdateState(ctxTx, state.ID, storage.UpdateState{
+				UpdatedAt: time.Now(),
+				Status:    2,
+				Step:      "updated",
+			}); err != nil {
+				return err
+			}
+			return txErr
+		})
+		require.ErrorIs(t, err, txErr)
+
+		savedState, err := s.GetStateByID(ctx, state.ID)
+		require.NoError(t, err)
+		stateEqual(t, state, savedState)
+	})
+
+	t.Run("nested transaction joins outer", func(t *testing.T) {
+		state := newTestState()
+		txErr := errors.New("tx error")
+
+		err := s.RunTransaction(ctx, func(ctxTx context.Context) error {
+			if err := s.RunTransaction(ctxTx, func(ctxTx context.Context) error {
+				return s.CreateState(ctxTx, state)
+			}); err != nil {
+				return err
+			}
+			return txErr
+		})
+		require.ErrorIs(t, err, txErr)
+
+		// Ошибка внешней транзакции откатывает и изменения вложенной
+		_, err = s.GetStateByID(ctx, state.ID)
+		require.ErrorIs(t, err, storage.ErrNotFound)
+	})
+
+	t.Run("unique violation in transaction", func(t *testing.T) {
+		state := newTestState()
+		require.NoError(t, s.CreateState(ctx, state))
+
+		err := s.RunTransaction(ctx, func(ctxTx context.Context) error {
+			duplicate := newTestState()
+			duplicate.IdempotencyKey = state.IdempotencyKey
+			return s.CreateState(ctxTx, duplicate)
+		})
+		require.ErrorIs(t, err, storage.ErrAlreadyExists)
+	})
+}
+
+func testConcurrentUpdateState(t *testing.T, factory Factory) {
+	t.Parallel()
+	s := factory(t)
+	ctx := context.Background()
+
+	state := newTestState()
+	require.NoError(t, s.CreateState(ctx, state))
+
+	const workers = 10
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		success  int
+		conflict int
+		errs     []error
+	)
+	for i := range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			// Все обработчики прочитали стейт с версией 0
+			err := s.RunTransaction(ctx, func(ctxTx context.Context) error {
+				return s.UpdateState(ctxTx, state.ID, storage.UpdateState{
+					UpdatedAt: time.Now(),
+					Status:    2,
+					Step:      "step_" + string(rune('a'+i)),
+					Version:   0,
+				})
+			})
+
+			mu.Lock()
+			defer mu.Unlock()
+			switch {
+			case err == nil:
+				success++
+			case errors.Is(err, storage.ErrConcurrentModification):
+				conflict++
+			default:
+				errs = append(errs, err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	require.Empty(t, errs)
+	// Изменение применяется только один раз, остальные получают ошибку конкурентного изменения
+	require.Equal(t, 1, success)
+	require.Equal(t, workers-1, conflict)
+
+	savedState, err := s.GetStateByID(ctx, state.ID)
+	require.NoError(t, err)
+	require.Equal(t, int64(1), savedState.Version)
+}
@@ -0,0 +1,212 @@
+package statemachine
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/kkiling/statemachine/memstore"
+)
+
+func TestStateMachine_StepTimeout(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	// hangStep шаг, ожидающий отмены контекста
+	hangStep := func(ctx context.Context, stepContext StepContext[int, any, any, string, string]) *StepResult[int, any, any, string] {
+		<-ctx.Done()
+		return stepContext.Error(ctx.Err())
+	}
+
+	t.Run("step timeout", func(t *testing.T) {
+		t.Parallel()
+
+		s := memstore.NewStorage()
+		sm := NewService[int, any, any, string, string, testOptions](Config{}, s, newStepTestRunner("timeout_test", testStepperStep{
+			OnStep:  hangStep,
+			Timeout: 50 * time.Millisecond,
+		}))
+		state, err := sm.Create(ctx, testOptions{})
+		require.NoError(t, err)
+
+		res, executeErr, err := sm.Complete(ctx, state.ID)
+		require.NoError(t, err)
+		require.ErrorIs(t, executeErr, ErrStepTimeout)
+		require.ErrorIs(t, executeErr, context.DeadlineExceeded)
+		var timeoutErr *StepTimeoutError
+		require.ErrorAs(t, executeErr, &timeoutErr)
+		require.Equal(t, "first", timeoutErr.Step)
+		require.Equal(t, 50*time.Millisecond, timeoutErr.Timeout)
+
+		// Стейт остается на шаге, таймаут считается ошибкой выполнения шага
+		require.Equal(t, "first", res.Step)
+		require.Equal(t, 1, res.FailedAttempts)
+
+		infos, err := s.GetStepExecuteInfos(ctx, state.ID)
+		require.NoError(t, err)
+		require.Len(t, infos, 1)
+		require.NotNil(t, infos[0].Error)
+		require.Equal(t, executeErr.Error(), *infos[0].Error)
+		require.Contains(t, *infos[0].Error, "timed out after 50ms")
+	})
+
+	t.Run("step swallows timeout", func(t *testing.T) {
+		t.Parallel()
+
+		s := memstore.NewStorage()
+		sm := NewService[int, any, any, string, string, testOptions](Config{}, s, newStepTestRunner("timeout_test", testStepperStep{
+			OnStep: func(ctx context.Context, stepContext StepContext[int, any, any, string, string]) *StepResult[int, any, any, string] {
+				// Шаг не возвращает ошибку отмены контекста, а откладывает повторное выполнение
+				<-ctx.Done()
+				return stepContext.RetryAfter(time.Hour)
+			},
+			Timeout: 50 * time.Millisecond,
+		}))
+		state, err := sm.Create(ctx, testOptions{})
+		require.NoError(t, err)
+
+		res, executeErr, err := sm.Complete(ctx, state.ID)
+		require.NoError(t, err)
+		require.ErrorIs(t, executeErr, ErrStepTimeout)
+		require.ErrorIs(t, executeErr, context.DeadlineExceeded)
+		require.Equal(t, "first", res.Step)
+		require.Equal(t, 1, res.FailedAttempts)
+		require.Nil(t, res.NextRunAt)
+
+		infos, err := s.GetStepExecuteInfos(ctx, state.ID)
+		require.NoError(t, err)
+		require.Len(t, infos, 1)
+		require.NotNil(t, infos[0].Error)
+		require.Equal(t, executeErr.Error(), *infos[0].Error)
+	})
+
+	t.Run("step completes after timeout", func(t *testing.T) {
+		t.Parallel()
+
+		sm := NewService[int, any, any, string, string, testOptions](Config{}, memstore.NewStorage(), newStepTestRunner("timeout_test", testStepperStep{
+			OnStep: func(ctx context.Context, stepContext StepContext[int, any, any, string, string]) *StepResult[int, any, any, string] {
+				<-ctx.Done()
+				return stepContext.Complete()
+			},
+			Timeout: 10 * time.Millisecond,
+		}))
+		state, err := sm.Create(ctx, testOptions{})
+		require.NoError(t, err)
+
+		// Результат Complete после таймаута сохраняется
+		res, executeErr, err := sm.Complete(ctx, state.ID)
+		require.NoError(t, err)
+		require.NoError(t, executeErr)
+		require.Equal(t, CompletedStatus, res.Status)
+	})
+
+	t.Run("default timeout from config", func(t *testing.T) {
+		t.Parallel()
+
+		sm := NewService[int, any, any, string, string, testOptions](Config{
+			StepTimeout: 50 * time.Millisecond,
+		}, memstore.NewStorage(), newStepTestRunner("timeout_test", testStepperStep{OnStep: hangStep}))
+		state, err := sm.Create(ctx, testOptions{})
+		require.NoError(t, err)
+
+		_, executeErr, err := sm.Complete(ctx, state.ID)
+		require.NoError(t, err)
+		require.ErrorIs(t, executeErr, ErrStepTimeout)
+	})
+
+	t.Run("step timeout overrides config", func(t *testing.T) {
+		t.Parallel()
+
+		sm := NewService[int, any, any, string, string, testOptions](Config{
+			StepTimeout: time.Millisecond,
+		}, memstore.NewStorage(), newStepTestRunner("timeout_test", testStepperStep{
+			OnStep: func(ctx context.Context, stepContext StepContext[int, any, any, string, string]) *StepResult[int, any, any, string] {
+				select {
+				case <-ctx.Done():
+					return stepContext.Error(ctx.Err())
+				case <-time.After(20 * time.Millisecond):
+					return stepContext.Complete()
+				}
+			},
+			Timeout: time.Minute,
+		}))
+		state, err := sm.Create(ctx, testOptions{})
+		require.NoError(t, err)
+
+		res, executeErr, err := sm.Complete(ctx, state.ID)
+		require.NoError(t, err)
+		require.NoError(t, executeErr)
+		require.Equal(t, CompletedStatus, res.Status)
+	})
+
+	t.Run("step error without timeout", func(t *testing.T) {
+		t.Parallel()
+
+		stepErr := errors.New("external service unavailable")
+		sm := NewService[int, any, any, string, string, testOptions](Config{}, memstore.NewStorage(), newStepTestRunner("timeout_test", testStepperStep{
+			OnStep: func(_ context.Context, stepContext StepContext[int, any, any, string, string]) *StepResult[int, any, any, string] {
+				return stepContext.Error(stepErr)
+			},
+			Timeout: time.Minute,
+		}))
+		state, err := sm.Create(ctx, testOptions{})
+		require.NoError(t, err)
+
+		_, executeErr, err := sm.Complete(ctx, state.ID)
+		require.NoError(t, err)
+		require.ErrorIs(t, executeErr, stepErr)
+		require.NotErrorIs(t, executeErr, ErrStepTimeout)
+	})
+
+	t.Run("timeout with retry policy", func(t *testing.T) {
+		t.Parallel()
+
+		sm := NewService[int, any, any, string, string, testOptions](Config{}, memstore.NewStorage(), newStepTestRunner("timeout_test", testStepperStep{
+			OnStep:  hangStep,
+			Timeout: 10 * time.Millisecond,
+			RetryPolicy: &RetryPolicy{
+				MaxAttempts: 2,
+				// Таймауты не повторяются
+				Retryable: func(err error) bool {
+					return !errors.Is(err, ErrStepTimeout)
+				},
+			},
+		}))
+		state, err := sm.Create(ctx, testOptions{})
+		require.NoError(t, err)
+
+		res, executeErr, err := sm.Complete(ctx, state.ID)
+		require.NoError(t, err)
+		require.ErrorIs(t, executeErr, ErrNotRetryable)
+		require.ErrorIs(t, executeErr, ErrStepTimeout)
+		require.Equal(t, FailedStatus, res.Status)
+		require.Equal(t, "first", res.FailedStep)
+	})
+
+	t.Run("timeouts count as failed attempts", func(t *testing.T) {
+		t.Parallel()
+
+		sm := NewService[int, any, any, string, string, testOptions](Config{}, memstore.NewStorage(), newStepTestRunner("timeout_test", testStepperStep{
+			OnStep:      hangStep,
+			Timeout:     10 * time.Millisecond,
+			RetryPolicy: &RetryPolicy{MaxAttempts: 2},
+		}))
+		state, err := sm.Create(ctx, testOptions{})
+		require.NoError(t, err)
+
+		res, executeErr, err := sm.Complete(ctx, state.ID)
+		require.NoError(t, err)
+		require.ErrorIs(t, executeErr, ErrStepTimeout)
+		require.NotErrorIs(t, executeErr, ErrRetryAttemptsExhausted)
+		require.Equal(t, 1, res.FailedAttempts)
+
+		res, executeErr, err = sm.Complete(ctx, state.ID)
+		require.NoError(t, err)
+		require.ErrorIs(t, executeErr, ErrStepTimeout)
+		require.ErrorIs(t, executeErr, ErrRetryAttemptsExhausted)
+		require.Equal(t, FailedStatus, res.Status)
+	})
+}
@@ -2,6 +2,7 @@ package statemachine
 
 import (
 	"github.com/kkiling/goplatform/storagebase/postgrebase"
+
 	"github.com/kkiling/statemachine/internal/storage/postgresql"
 )
 
@@ -10,3 +11,15 @@ type StorageConfig = postgrebase.Config
 var NewPgConn = postgrebase.NewPgConn
 
 var NewStorage = postgresql.NewStorage
+
+// Migrate применяет к базе PostgreSQL встроенные миграции схемы, которые еще не были применены
+var Migrate = postgresql.Migrate
+
+// PgTablesConfig схема и префикс таблиц хранилища PostgreSQL
+type PgTablesConfig = postgresql.Config
+
+// NewStorageWithConfig хранилище PostgreSQL с таблицами в заданной схеме и/или с префиксом
+var NewStorageWithConfig = postgresql.NewStorageWithConfig
+
+// MigrateWithConfig применяет встроенные миграции к таблицам в заданной схеме и/или с префиксом
+var MigrateWithConfig = postgresql.MigrateWithConfig
@@ -0,0 +1,185 @@
+package statemachine
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/kkiling/statemachine/storage"
+)
+
+const (
+	defaultWorkerConcurrency     = 1
+	defaultWorkerPollInterval    = time.Second
+	defaultWorkerShutdownTimeout = 30 * time.Second
+)
+
+// WorkerConfig настройки фонового обработчика стейтов
+type WorkerConfig struct {
+	// Concurrency количество стейтов, выполняемых одновременно
+	Concurrency int
+	// PollInterval интервал опроса хранилища на наличие стейтов, готовых к выполнению
+	PollInterval time.Duration
+	// BatchSize максимальное количество стейтов, выбираемых за один опрос (по умолчанию равен Concurrency)
+	BatchSize int
+	// ShutdownTimeout время ожидания завершения выполняющихся стейтов при остановке
+	ShutdownTimeout time.Duration
+	// OnError вызывается при ошибке выполнения стейта или опроса хранилища
+	OnError func(stateID uuid.UUID, err error)
+}
+
+// Worker фоновый обработчик, продвигающий нетерминальные стейты без внешнего вызова Complete
+type Worker[DataT any, FailDataT any, MetaDataT any, StepT ~string, TypeT ~string, CreateOptionsT CreateOptions] struct {
+	cfg WorkerConfig
+	sm  *StateMachine[DataT, FailDataT, MetaDataT, StepT, TypeT, CreateOptionsT]
+}
+
+func NewWorker[DataT any, FailDataT any, MetaDataT any, StepT ~string, TypeT ~string, CreateOptionsT CreateOptions](
+	cfg WorkerConfig,
+	sm *StateMachine[DataT, FailDataT, MetaDataT, StepT, TypeT, CreateOptionsT],
+) *Worker[DataT, FailDataT, MetaDataT, StepT, TypeT, CreateOptionsT] {
+	if cfg.Concurrency <= 0 {
+		cfg.Concurrency = defaultWorkerConcurrency
+	}
+	if cfg.PollInterval <= 0 {
+		cfg.PollInterval = defaultWorkerPollInterval
+	}
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = cfg.Concurrency
+	}
+	if cfg.ShutdownTimeout <= 0 {
+		cfg.ShutdownTimeout = defaultWorkerShutdownTimeout
+	}
+
+	return &Worker[DataT, FailDataT, MetaDataT, StepT, TypeT, CreateOptionsT]{
+		cfg: cfg,
+		sm:  sm,
+	}
+}
+
+// Run запускает обработку стейтов и блокируется до отмены ctx.
+// После отмены новые стейты не выбираются, а выполняющиеся дожидаются завершения в течение ShutdownTimeout
+func (w *Worker[DataT, FailDataT, MetaDataT, StepT, TypeT, CreateOptionsT]) Run(ctx context.Context) error {
+	// Выполнение стейтов не прерывается сразу при отмене ctx, чтобы шаги успели корректно завершиться
+	execCtx, cancelExec := context.WithCancel(context.WithoutCancel(ctx))
+	defer cancelExec()
+
+	sem := make(chan struct{}, w.cfg.Concurrency)
+	wg := sync.WaitGroup{}
+
+	ticker := time.NewTicker(w.cfg.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		w.poll(ctx, execCtx, sem, &wg)
+
+		select {
+		case <-ctx.Done():
+			return w.shutdown(&wg, cancelExec)
+		case <-ticker.C:
+		}
+	}
+}
+
+// poll захватывает готовые к выполнению стейты и запускает их в свободных слотах
+func (w *Worker[DataT, FailDataT, MetaDataT, StepT, TypeT, CreateOptionsT]) poll(
+	ctx context.Context,
+	execCtx context.Context,
+	sem chan struct{},
+	wg *sync.WaitGroup,
+) {
+	free := cap(sem) - len(sem)
+	if free <= 0 || ctx.Err() != nil {
+		return
+	}
+
+	lease := w.sm.lease()
+	states, err := w.sm.storage.ClaimReadyStates(ctx, storage.ReadyStatesFilter{
+		Type:     string(w.sm.runner.Type()),
+		Statuses: []uint8{NewStatus, InProgressStatus},
+		Limit:    min(free, w.cfg.BatchSize),
+	}, lease)
+	if err != nil {
+		if ctx.Err() == nil {
+			w.onError(uuid.Nil, fmt.Errorf("storage.ClaimReadyStates: %w", err))
+		}
+		return
+	}
+
+	for _, state := range states {
+		sem <- struct{}{}
+		wg.Add(1)
+		go func() {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			w.execute(execCtx, &state, lease.AcquiredAt)
+		}()
+	}
+}
+
+// execute выполняет один захваченный стейт и освобождает его аренду
+func (w *Worker[DataT, FailDataT, MetaDataT, StepT, TypeT, CreateOptionsT]) execute(
+	ctx context.Context,
+	claimState *storage.State,
+	now time.Time,
+) {
+	defer func() {
+		if err := w.sm.release(ctx, claimState.ID); err != nil {
+			w.onError(claimState.ID, err)
+		}
+	}()
+
+	findState, err := mapStorageToState[DataT, FailDataT, MetaDataT, StepT, TypeT](claimState)
+	if err != nil {
+		w.onError(claimState.ID, fmt.Errorf("mapStorageToState: %w", err))
+		return
+	}
+
+	// Ошибка выполнения шага сохраняется в истории выполнения, поэтому здесь не обрабатывается
+	_, _, err = w.sm.complete(ctx, *findState, now)
+	switch {
+	case err == nil:
+	case errors.Is(err, ErrInTerminalStatus), errors.Is(err, ErrNotReady), errors.Is(err, ErrStateCancelled),
+		errors.Is(err, ErrStateMoved), errors.Is(err, ErrPaused):
+		// Стейт успел завершиться, был отложен, отменен, переведен на другой шаг или приостановлен
+		// между выборкой и выполнением
+	default:
+		w.onError(claimState.ID, err)
+	}
+}
+
+// shutdown дожидается завершения выполняющихся стейтов, по истечении ShutdownTimeout отменяет их контекст
+func (w *Worker[DataT, FailDataT, MetaDataT, StepT, TypeT, CreateOptionsT]) shutdown(
+	wg *sync.WaitGroup,
+	cancelExec context.CancelFunc,
+) error {
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+
+	timer := time.NewTimer(w.cfg.ShutdownTimeout)
+	defer timer.Stop()
+
+	select {
+	case <-done:
+		return nil
+	case <-timer.C:
+		cancelExec()
+		<-done
+		return fmt.Errorf("worker shutdown timeout exceeded")
+	}
+}
+
+func (w *Worker[DataT, FailDataT, MetaDataT, StepT, TypeT, CreateOptionsT]) onError(stateID uuid.UUID, err error) {
+	if w.cfg.OnError != nil {
+		w.cfg.OnError(stateID, err)
+	}
+}
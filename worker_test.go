@@ -0,0 +1,122 @@
+package statemachine
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+
+	mock_statemachine "github.com/kkiling/statemachine/mocks"
+	"github.com/kkiling/statemachine/storage"
+)
+
+func TestWorker_Run(t *testing.T) {
+	t.Parallel()
+
+	// Стейт завершается за один шаг с увеличением данных
+	runner := newStepTestRunner("worker_test", testStepperStep{
+		OnStep: func(_ context.Context, stepContext testStepperContext) *testStepperResult {
+			return stepContext.Complete().WithData(stepContext.State.Data + 1)
+		},
+	})
+
+	t.Run("complete ready state", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		storageMock := mock_statemachine.NewMockStorage(ctrl)
+		stateID := uuid.New()
+		completed := make(chan struct{})
+
+		storageMock.EXPECT().ClaimReadyStates(gomock.Any(), storage.ReadyStatesFilter{
+			Type:     "worker_test",
+			Statuses: []uint8{NewStatus, InProgressStatus},
+			Limit:    2,
+		}, gomock.Any()).
+			DoAndReturn(func(_ context.Context, _ storage.ReadyStatesFilter, lease storage.Lease) ([]storage.State, error) {
+				require.Equal(t, "worker", lease.Owner)
+				require.Equal(t, lease.AcquiredAt.Add(time.Minute), lease.ExpiresAt)
+				return []storage.State{{
+					ID:     stateID,
+					Status: NewStatus,
+					Step:   "first",
+					Type:   "worker_test",
+					Data:   []byte("41"),
+				}}, nil
+			})
+		storageMock.EXPECT().ClaimReadyStates(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, nil).AnyTimes()
+		storageMock.EXPECT().IsTypePaused(gomock.Any(), "worker_test").Return(false, nil)
+		storageMock.EXPECT().RunTransaction(gomock.Any(), gomock.Any()).
+			DoAndReturn(func(ctx context.Context, txFunc func(context.Context) error) error {
+				return txFunc(ctx)
+			})
+		storageMock.EXPECT().SaveStepExecuteInfo(gomock.Any(), gomock.Any())
+		storageMock.EXPECT().UpdateState(gomock.Any(), stateID, gomock.Any()).
+			DoAndReturn(func(_ context.Context, _ uuid.UUID, state storage.UpdateState) error {
+				require.Equal(t, CompletedStatus, state.Status)
+				require.Equal(t, []byte("42"), state.Data)
+				return nil
+			})
+		// После выполнения аренда стейта освобождается
+		storageMock.EXPECT().ReleaseState(gomock.Any(), stateID, "worker").
+			DoAndReturn(func(context.Context, uuid.UUID, string) error {
+				close(completed)
+				return nil
+			})
+
+		sm := NewService[int, any, any, string, string, testOptions](Config{
+			LeaseOwner:    "worker",
+			LeaseDuration: time.Minute,
+		}, storageMock, runner)
+		worker := NewWorker(WorkerConfig{
+			Concurrency:  2,
+			PollInterval: 10 * time.Millisecond,
+			OnError: func(stateID uuid.UUID, err error) {
+				t.Errorf("unexpected error for state %s: %v", stateID, err)
+			},
+		}, sm)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		runErr := make(chan error)
+		go func() {
+			runErr <- worker.Run(ctx)
+		}()
+
+		select {
+		case <-completed:
+		case <-time.After(5 * time.Second):
+			t.Fatal("state was not completed by worker")
+		}
+
+		cancel()
+		require.NoError(t, <-runErr)
+	})
+
+	t.Run("report storage error", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		storageMock := mock_statemachine.NewMockStorage(ctrl)
+		storageErr := errors.New("storage unavailable")
+
+		storageMock.EXPECT().ClaimReadyStates(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, storageErr).MinTimes(1)
+
+		var reported atomic.Bool
+		sm := NewService[int, any, any, string, string, testOptions](Config{}, storageMock, runner)
+		worker := NewWorker(WorkerConfig{
+			PollInterval: 10 * time.Millisecond,
+			OnError: func(stateID uuid.UUID, err error) {
+				require.Equal(t, uuid.Nil, stateID)
+				require.ErrorIs(t, err, storageErr)
+				reported.Store(true)
+			},
+		}, sm)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+
+		require.NoError(t, worker.Run(ctx))
+		require.True(t, reported.Load())
+	})
+}